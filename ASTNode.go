@@ -0,0 +1,218 @@
+package govaluate
+
+/*
+	A node in the abstract syntax tree of an expression, as returned by `EvaluableExpression.AST()`.
+	Each node is one of the *Node types in this file; use a type switch to tell them apart.
+
+	The tree is built anew each time it's asked for, so changing it has no effect on the expression it came from.
+*/
+type ASTNode interface {
+
+	// the operator this node represents, such as PLUS for a BinaryNode, or LITERAL for a LiteralNode.
+	Symbol() OperatorSymbol
+
+	// where this node is in the original expression.
+	Span() Span
+
+	// the nodes directly below this one, in the order they appear in the expression.
+	Children() []ASTNode
+
+	setSpan(span Span)
+}
+
+/*
+	The part of the original expression which a node was parsed from, from [Start] up to (but not including) [End].
+	Nodes of expressions built from tokens, and nodes which weren't parsed at all, have a zero Span.
+*/
+type Span struct {
+	Start Position
+	End   Position
+}
+
+/*
+	Returns the smallest span which covers both [this] and [other].
+*/
+func (this Span) extend(other Span) Span {
+
+	if other == (Span{}) {
+		return this
+	}
+	if this == (Span{}) {
+		return other
+	}
+
+	if other.Start.Offset < this.Start.Offset {
+		this.Start = other.Start
+	}
+	if other.End.Offset > this.End.Offset {
+		this.End = other.End
+	}
+	return this
+}
+
+type nodeSpan struct {
+	span Span
+}
+
+func (this *nodeSpan) Span() Span {
+	return this.span
+}
+
+func (this *nodeSpan) setSpan(span Span) {
+	this.span = span
+}
+
+/*
+	An operator with a value on each side, such as `a + b`, `a in (1, 2)`, or `a ?? b`.
+*/
+type BinaryNode struct {
+	nodeSpan
+
+	Operator    OperatorSymbol
+	Left, Right ASTNode
+}
+
+func (this *BinaryNode) Symbol() OperatorSymbol {
+	return this.Operator
+}
+
+func (this *BinaryNode) Children() []ASTNode {
+	return []ASTNode{this.Left, this.Right}
+}
+
+/*
+	A prefix operator, such as `-a`, `!a` or `~a`. [Operator] is one of NEGATE, INVERT, or BITWISE_NOT.
+*/
+type UnaryNode struct {
+	nodeSpan
+
+	Operator OperatorSymbol
+	Operand  ASTNode
+}
+
+func (this *UnaryNode) Symbol() OperatorSymbol {
+	return this.Operator
+}
+
+func (this *UnaryNode) Children() []ASTNode {
+	return []ASTNode{this.Operand}
+}
+
+/*
+	A ternary conditional, `a ? b : c`. [Else] is nil if the expression has no `:` part (in which case it evaluates to nil when [Condition] is false).
+	The symbol of a TernaryNode is TERNARY_TRUE.
+*/
+type TernaryNode struct {
+	nodeSpan
+
+	Condition ASTNode
+	Then      ASTNode
+	Else      ASTNode
+}
+
+func (this *TernaryNode) Symbol() OperatorSymbol {
+	return TERNARY_TRUE
+}
+
+func (this *TernaryNode) Children() []ASTNode {
+
+	if this.Else == nil {
+		return []ASTNode{this.Condition, this.Then}
+	}
+	return []ASTNode{this.Condition, this.Then, this.Else}
+}
+
+/*
+	A constant value. [Value] is a number (float64, int64, or Decimal, depending on the NumericMode), string, bool,
+	time.Time, time.Duration, or *regexp.Regexp (for constant patterns on the right of `=~` and `!~`).
+*/
+type LiteralNode struct {
+	nodeSpan
+
+	Value interface{}
+}
+
+func (this *LiteralNode) Symbol() OperatorSymbol {
+	return LITERAL
+}
+
+func (this *LiteralNode) Children() []ASTNode {
+	return nil
+}
+
+/*
+	A parameter, such as `foo` or `[foo bar]`.
+*/
+type VariableNode struct {
+	nodeSpan
+
+	Name string
+}
+
+func (this *VariableNode) Symbol() OperatorSymbol {
+	return VALUE
+}
+
+func (this *VariableNode) Children() []ASTNode {
+	return nil
+}
+
+/*
+	A call to a user-defined function, such as `foo(1, 2)`.
+	[Function] is the ExpressionFunction or ContextExpressionFunction which will be called.
+	[Name] is the name it was called by; it's empty for FUNCTION tokens given to `NewEvaluableExpressionFromTokens`,
+	since an ExpressionToken only holds the function itself.
+*/
+type FunctionNode struct {
+	nodeSpan
+
+	Name      string
+	Function  interface{}
+	Arguments []ASTNode
+}
+
+func (this *FunctionNode) Symbol() OperatorSymbol {
+	return FUNCTIONAL
+}
+
+func (this *FunctionNode) Children() []ASTNode {
+	return this.Arguments
+}
+
+/*
+	Access to a field or method of a parameter, such as `foo.Bar` or `foo.Bar(1, 2)`.
+	[Path] is the parameter name followed by each field or method name. [Called] is true if the accessor has parenthesis,
+	in which case [Arguments] are given to the method.
+*/
+type AccessorNode struct {
+	nodeSpan
+
+	Path      []string
+	Called    bool
+	Arguments []ASTNode
+}
+
+func (this *AccessorNode) Symbol() OperatorSymbol {
+	return ACCESS
+}
+
+func (this *AccessorNode) Children() []ASTNode {
+	return this.Arguments
+}
+
+/*
+	A list of values, such as `(1, 2, 3)`.
+*/
+type ArrayNode struct {
+	nodeSpan
+
+	Elements []ASTNode
+}
+
+func (this *ArrayNode) Symbol() OperatorSymbol {
+	return SEPARATE
+}
+
+func (this *ArrayNode) Children() []ASTNode {
+	return this.Elements
+}
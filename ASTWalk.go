@@ -0,0 +1,105 @@
+package govaluate
+
+/*
+	Visits the nodes of an AST, see `Walk`.
+*/
+type ASTVisitor interface {
+
+	// called for each node. If the returned visitor isn't nil, it's used to visit each child of [node], and then called with nil.
+	Visit(node ASTNode) (visitor ASTVisitor)
+}
+
+/*
+	Traverses the AST under (and including) [node], depth-first, in the order the nodes appear in the expression.
+	Calls `visitor.Visit(node)` first; if the visitor it returns isn't nil, each child of [node] is walked with that visitor,
+	followed by a call to `Visit(nil)`.
+*/
+func Walk(visitor ASTVisitor, node ASTNode) {
+
+	if node == nil {
+		return
+	}
+
+	visitor = visitor.Visit(node)
+	if visitor == nil {
+		return
+	}
+
+	for _, child := range node.Children() {
+		Walk(visitor, child)
+	}
+	visitor.Visit(nil)
+}
+
+type inspector func(node ASTNode) bool
+
+func (this inspector) Visit(node ASTNode) ASTVisitor {
+
+	if this(node) {
+		return this
+	}
+	return nil
+}
+
+/*
+	Traverses the AST under (and including) [node], in the same order as `Walk`, calling [inspect] for each node.
+	If [inspect] returns false, the children of that node are skipped.
+	Once all the children of a node have been inspected, [inspect] is called with nil.
+*/
+func Inspect(node ASTNode, inspect func(node ASTNode) bool) {
+	Walk(inspector(inspect), node)
+}
+
+/*
+	Transforms the AST under (and including) [node], from the bottom up.
+	The children of each node are rewritten first, then [rewriter] is called with the node, and whatever it returns takes the node's place.
+	To leave a node as it is, return it unchanged.
+
+	Nodes are changed in place, so [node] shouldn't be used afterwards. Returns the root of the rewritten tree.
+	See `EvaluableExpression.Rewrite` to make a new expression from the result.
+*/
+func Rewrite(node ASTNode, rewriter func(node ASTNode) ASTNode) ASTNode {
+
+	if node == nil {
+		return nil
+	}
+
+	switch node.(type) {
+
+	case *BinaryNode:
+		binary := node.(*BinaryNode)
+		binary.Left = Rewrite(binary.Left, rewriter)
+		binary.Right = Rewrite(binary.Right, rewriter)
+
+	case *UnaryNode:
+		unary := node.(*UnaryNode)
+		unary.Operand = Rewrite(unary.Operand, rewriter)
+
+	case *TernaryNode:
+		ternary := node.(*TernaryNode)
+		ternary.Condition = Rewrite(ternary.Condition, rewriter)
+		ternary.Then = Rewrite(ternary.Then, rewriter)
+		ternary.Else = Rewrite(ternary.Else, rewriter)
+
+	case *FunctionNode:
+		function := node.(*FunctionNode)
+		rewriteNodes(function.Arguments, rewriter)
+
+	case *AccessorNode:
+		accessor := node.(*AccessorNode)
+		rewriteNodes(accessor.Arguments, rewriter)
+
+	case *ArrayNode:
+		array := node.(*ArrayNode)
+		rewriteNodes(array.Elements, rewriter)
+	}
+
+	return rewriter(node)
+}
+
+func rewriteNodes(nodes []ASTNode, rewriter func(node ASTNode) ASTNode) {
+
+	for i, node := range nodes {
+		nodes[i] = Rewrite(node, rewriter)
+	}
+}
@@ -0,0 +1,498 @@
+package govaluate
+
+import (
+	"context"
+	"math"
+)
+
+/*
+	CompiledExpression is an EvaluableExpression whose stages have been compiled into a flat list of instructions,
+	which are run on a stack instead of by walking the stages recursively.
+	It gives the same results and errors as the expression it was compiled from, but is faster to evaluate,
+	especially for long expressions which are evaluated many times.
+
+	A CompiledExpression is safe to evaluate from many goroutines at once.
+*/
+type CompiledExpression struct {
+	expression   EvaluableExpression
+	instructions []instruction
+	stackSize    int
+
+	// the names of the variables (and roots of accessors) in the expression, in the order of their slots.
+	variables []string
+	slots     map[string]int
+
+	// whether any accessors need to get their parameters by name.
+	accesses bool
+}
+
+type instructionKind int
+
+const (
+	// pushes a constant.
+	literalInstruction instructionKind = iota
+
+	// pushes the value of a parameter.
+	parameterInstruction
+
+	// pops the sides of a stage (if it has them) and pushes the result of its operator.
+	operateInstruction
+
+	// jumps past a stage when the value on top of the stack is its result, leaving that value in place.
+	// Used by `&&`, `||`, and `??`, which don't need to evaluate their right side in those cases.
+	jumpIfFalseInstruction
+	jumpIfTrueInstruction
+	jumpIfNotNilInstruction
+
+	// pushes a placeholder for the right side of a ternary and jumps to its operator, when that side doesn't need to be evaluated.
+	skipIfFalseInstruction
+	skipIfNotNilInstruction
+)
+
+type instruction struct {
+	kind instructionKind
+
+	// how many stages are started by this instruction, which are counted towards the MaxSteps of the expression.
+	steps int
+
+	// the constant of a literal, or the name of a parameter.
+	value interface{}
+
+	// the slot of a parameter.
+	slot int
+
+	// the stage this instruction was compiled from, used by operators and to say where errors happened.
+	stage *evaluationStage
+
+	// the instruction to jump to.
+	target int
+
+	hasLeft, hasRight bool
+
+	// what the operator does when both of its sides are float64, if it's one which can be done without boxing its result (see unboxedFloat).
+	arithmetic func(float64, float64) float64
+	comparison func(float64, float64) bool
+}
+
+/*
+	Marks a place on the stack whose value is a float64 kept in the matching place of a separate stack of numbers.
+	Putting a float64 into an interface{} allocates, so the results of arithmetic are only boxed when they're given to something other than more arithmetic.
+*/
+type unboxedValue struct{}
+
+var unboxedFloat interface{} = unboxedValue{}
+
+/*
+	The same as the operators of these symbols, when both sides are float64.
+*/
+var floatArithmetic = map[OperatorSymbol]func(float64, float64) float64{
+	PLUS:     func(left float64, right float64) float64 { return left + right },
+	MINUS:    func(left float64, right float64) float64 { return left - right },
+	MULTIPLY: func(left float64, right float64) float64 { return left * right },
+	DIVIDE:   func(left float64, right float64) float64 { return left / right },
+	MODULUS:  math.Mod,
+	EXPONENT: math.Pow,
+}
+
+var floatComparisons = map[OperatorSymbol]func(float64, float64) bool{
+	GT:  func(left float64, right float64) bool { return left > right },
+	GTE: func(left float64, right float64) bool { return left >= right },
+	LT:  func(left float64, right float64) bool { return left < right },
+	LTE: func(left float64, right float64) bool { return left <= right },
+	EQ:  func(left float64, right float64) bool { return left == right },
+	NEQ: func(left float64, right float64) bool { return left != right },
+}
+
+/*
+	Compiles this expression into instructions which are faster to evaluate than the expression itself, see `CompiledExpression`.
+	The compiled expression keeps the ChecksTypes and Options this expression has when it's compiled; later changes to them don't affect it.
+*/
+func (this EvaluableExpression) Compile() *CompiledExpression {
+
+	compiler := &stageCompiler{
+		slots: make(map[string]int),
+	}
+
+	if this.evaluationStages != nil {
+		compiler.compileStage(this.evaluationStages)
+	}
+
+	return &CompiledExpression{
+		expression:   this,
+		instructions: compiler.instructions,
+		stackSize:    compiler.maxDepth,
+		variables:    compiler.variables,
+		slots:        compiler.slots,
+		accesses:     compiler.accesses,
+	}
+}
+
+/*
+	Same as `Eval`, but automatically wraps a map of parameters into a `govalute.Parameters` structure.
+*/
+func (this CompiledExpression) Evaluate(parameters map[string]interface{}) (interface{}, error) {
+
+	if parameters == nil {
+		return this.Eval(nil)
+	}
+
+	return this.Eval(MapParameters(parameters))
+}
+
+/*
+	Runs the compiled expression using the given [parameters], the same as `EvaluableExpression.Eval`.
+*/
+func (this CompiledExpression) Eval(parameters Parameters) (interface{}, error) {
+
+	return this.EvalContext(context.Background(), parameters)
+}
+
+/*
+	Runs the compiled expression, stopping as soon as the given [ctx] is done, the same as `EvaluableExpression.EvalContext`.
+*/
+func (this CompiledExpression) EvalContext(ctx context.Context, parameters Parameters) (interface{}, error) {
+
+	if parameters != nil {
+		parameters = &sanitizedParameters{parameters, this.expression.parsingOptions.NumericMode}
+	} else {
+		parameters = DUMMY_PARAMETERS
+	}
+
+	return this.run(ctx, parameters, nil)
+}
+
+/*
+	Runs the instructions of this expression. Parameters are taken from [slots] if it's given, otherwise from [parameters] by name.
+	Accessors always use [parameters].
+*/
+func (this CompiledExpression) run(ctx context.Context, parameters Parameters, slots []interface{}) (interface{}, error) {
+
+	var buffer [16]interface{}
+	var numberBuffer [16]float64
+	var stack []interface{}
+	var numbers []float64
+	var value interface{}
+	var err error
+
+	if len(this.instructions) == 0 {
+		return nil, nil
+	}
+
+	// most expressions are shallow enough to keep their stack off the heap.
+	if this.stackSize <= len(buffer) {
+		stack = buffer[:]
+		numbers = numberBuffer[:]
+	} else {
+		stack = make([]interface{}, this.stackSize)
+		numbers = make([]float64, this.stackSize)
+	}
+
+	state := &evaluationState{
+		ctx: ctx,
+	}
+	done := ctx.Done()
+	maxSteps := this.expression.Options.MaxSteps
+	top := 0
+
+	for index := 0; index < len(this.instructions); index++ {
+
+		instruction := &this.instructions[index]
+
+		if instruction.steps > 0 {
+
+			if done != nil {
+				select {
+				case <-done:
+					return nil, ctx.Err()
+				default:
+				}
+			}
+
+			state.steps += instruction.steps
+			if maxSteps > 0 && state.steps > maxSteps {
+				return nil, LimitExceededError{STEP_LIMIT, maxSteps}
+			}
+		}
+
+		switch instruction.kind {
+
+		case literalInstruction:
+			stack[top] = instruction.value
+			top++
+
+		case parameterInstruction:
+
+			if slots != nil {
+
+				value = slots[instruction.slot]
+				if value == unboundSlot {
+					err = MissingParameterError{Name: instruction.value.(string)}
+					return nil, this.expression.locateError(state, instruction.stage, err)
+				}
+				value = sanitizeNumeric(value, this.expression.parsingOptions.NumericMode)
+
+			} else {
+
+				value, err = parameters.Get(instruction.value.(string))
+				if err != nil {
+					return nil, this.expression.locateError(state, instruction.stage, err)
+				}
+			}
+
+			stack[top] = value
+			top++
+
+		case operateInstruction:
+
+			var left, right interface{}
+
+			if instruction.arithmetic != nil || instruction.comparison != nil {
+
+				leftNumber, leftIsFloat := floatAt(stack, numbers, top-2)
+				rightNumber, rightIsFloat := floatAt(stack, numbers, top-1)
+
+				if leftIsFloat && rightIsFloat {
+
+					top -= 2
+
+					if instruction.arithmetic != nil {
+						numbers[top] = instruction.arithmetic(leftNumber, rightNumber)
+						stack[top] = unboxedFloat
+					} else {
+						stack[top] = boolIface(instruction.comparison(leftNumber, rightNumber))
+					}
+
+					top++
+					continue
+				}
+			}
+
+			if instruction.hasRight {
+				top--
+				right = boxedAt(stack, numbers, top)
+			}
+			if instruction.hasLeft {
+				top--
+				left = boxedAt(stack, numbers, top)
+			}
+
+			value, err = this.expression.operateStage(state, instruction.stage, left, right, parameters)
+			if err != nil {
+				return nil, err
+			}
+
+			stack[top] = value
+			top++
+
+		case jumpIfFalseInstruction:
+			if stack[top-1] == false {
+				index = instruction.target - 1
+			}
+
+		case jumpIfTrueInstruction:
+			if stack[top-1] == true {
+				index = instruction.target - 1
+			}
+
+		case jumpIfNotNilInstruction:
+			if stack[top-1] != nil {
+				index = instruction.target - 1
+			}
+
+		case skipIfFalseInstruction:
+			if stack[top-1] == false {
+				stack[top] = shortCircuitHolder
+				top++
+				index = instruction.target - 1
+			}
+
+		case skipIfNotNilInstruction:
+			if stack[top-1] != nil {
+				stack[top] = shortCircuitHolder
+				top++
+				index = instruction.target - 1
+			}
+		}
+	}
+
+	return boxedAt(stack, numbers, top-1), nil
+}
+
+/*
+	Returns the float64 at the given [index] of the stack, and whether there is one.
+*/
+func floatAt(stack []interface{}, numbers []float64, index int) (float64, bool) {
+
+	if stack[index] == unboxedFloat {
+		return numbers[index], true
+	}
+
+	number, isFloat := stack[index].(float64)
+	return number, isFloat
+}
+
+/*
+	Returns the value at the given [index] of the stack, boxing it if it's an unboxed float64.
+*/
+func boxedAt(stack []interface{}, numbers []float64, index int) interface{} {
+
+	if stack[index] == unboxedFloat {
+		return numbers[index]
+	}
+	return stack[index]
+}
+
+/*
+	Returns the text of the expression this was compiled from.
+*/
+func (this CompiledExpression) String() string {
+	return this.expression.String()
+}
+
+/*
+	Compiles stages into instructions in the order they're evaluated; each stage's left side, then right side, then its operator.
+*/
+type stageCompiler struct {
+	instructions []instruction
+
+	// the number of stages started since the last instruction, which are counted by the next one.
+	steps int
+
+	depth, maxDepth int
+
+	variables []string
+	slots     map[string]int
+	accesses  bool
+}
+
+func (this *stageCompiler) compileStage(stage *evaluationStage) {
+
+	var jump int
+
+	this.steps++
+
+	if stage.leftStage == nil && stage.rightStage == nil && stage.typeCheck == nil &&
+		stage.leftTypeCheck == nil && stage.rightTypeCheck == nil {
+
+		switch stage.symbol {
+		case LITERAL:
+			value, _ := stage.operator(nil, nil, nil)
+			this.push(instruction{kind: literalInstruction, value: value})
+			return
+
+		case VALUE:
+
+			name, isName := stage.token.Value.(string)
+			if isName && stage.token.Kind == VARIABLE {
+				this.push(instruction{kind: parameterInstruction, value: name, slot: this.findSlot(name), stage: stage})
+				return
+			}
+		}
+	}
+
+	if stage.symbol == ACCESS {
+		this.findSlot(stage.token.Value.([]string)[0])
+		this.accesses = true
+	}
+
+	// parenthesis only pass along their contents.
+	if stage.symbol == NOOP && stage.leftStage == nil && stage.rightStage != nil {
+		this.compileStage(stage.rightStage)
+		return
+	}
+
+	if stage.leftStage != nil {
+		this.compileStage(stage.leftStage)
+	}
+
+	jump = -1
+	if stage.leftStage != nil && stage.rightStage != nil {
+
+		switch stage.symbol {
+		case AND:
+			jump = this.emit(instruction{kind: jumpIfFalseInstruction})
+		case OR:
+			jump = this.emit(instruction{kind: jumpIfTrueInstruction})
+		case COALESCE:
+			jump = this.emit(instruction{kind: jumpIfNotNilInstruction})
+		case TERNARY_TRUE:
+			jump = this.emit(instruction{kind: skipIfFalseInstruction})
+		case TERNARY_FALSE:
+			jump = this.emit(instruction{kind: skipIfNotNilInstruction})
+		}
+	}
+
+	if stage.rightStage != nil {
+		this.compileStage(stage.rightStage)
+	}
+
+	operator := len(this.instructions)
+
+	if stage.leftStage != nil {
+		this.depth--
+	}
+	if stage.rightStage != nil {
+		this.depth--
+	}
+
+	this.push(instruction{
+		kind:       operateInstruction,
+		stage:      stage,
+		hasLeft:    stage.leftStage != nil,
+		hasRight:   stage.rightStage != nil,
+		arithmetic: floatArithmetic[stage.symbol],
+		comparison: floatComparisons[stage.symbol],
+	})
+
+	// skips land on the operator, which is given a placeholder for the right side. Jumps land after it.
+	if jump >= 0 {
+
+		switch this.instructions[jump].kind {
+		case skipIfFalseInstruction:
+			fallthrough
+		case skipIfNotNilInstruction:
+			this.instructions[jump].target = operator
+		default:
+			this.instructions[jump].target = len(this.instructions)
+		}
+	}
+}
+
+/*
+	Adds an instruction which pushes a value onto the stack.
+*/
+func (this *stageCompiler) push(instruction instruction) {
+
+	this.emit(instruction)
+
+	this.depth++
+	if this.depth > this.maxDepth {
+		this.maxDepth = this.depth
+	}
+}
+
+/*
+	Adds an instruction, which starts any stages which were compiled since the last one. Returns the index of the instruction.
+*/
+func (this *stageCompiler) emit(instruction instruction) int {
+
+	instruction.steps = this.steps
+	this.steps = 0
+
+	this.instructions = append(this.instructions, instruction)
+	return len(this.instructions) - 1
+}
+
+/*
+	Returns the slot of the variable of the given [name], giving it the next one if it doesn't have one yet.
+*/
+func (this *stageCompiler) findSlot(name string) int {
+
+	slot, found := this.slots[name]
+	if !found {
+		slot = len(this.variables)
+		this.slots[name] = slot
+		this.variables = append(this.variables, name)
+	}
+	return slot
+}
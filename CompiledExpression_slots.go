@@ -0,0 +1,173 @@
+package govaluate
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+	The value of a slot in a Binding which hasn't been set.
+*/
+type unboundValue struct{}
+
+var unboundSlot interface{} = unboundValue{}
+
+/*
+	Returns the names of the variables this expression uses, in the order of their slots.
+	Each name is given once, no matter how many times it's used. Accessors (like `foo.Bar`) use the slot of their first name (`foo`).
+*/
+func (this CompiledExpression) Variables() []string {
+
+	ret := make([]string, len(this.variables))
+	copy(ret, this.variables)
+	return ret
+}
+
+/*
+	Returns the slot of the variable of the given [name], and false if this expression doesn't use it.
+*/
+func (this CompiledExpression) Slot(name string) (int, bool) {
+
+	slot, found := this.slots[name]
+	return slot, found
+}
+
+/*
+	Runs the compiled expression using the given [values] for its variables, in the order given by `Variables()`,
+	instead of looking up each variable by name. This is the fastest way to evaluate an expression against one set of values at a time.
+
+	Arithmetic and comparisons of float64 values don't allocate, but other operators and functions may allocate the values they return,
+	and so does a float64 result of the whole expression.
+
+	Returns an error if there are fewer values than variables.
+*/
+func (this CompiledExpression) EvalSlots(values []interface{}) (interface{}, error) {
+
+	return this.EvalSlotsContext(context.Background(), values)
+}
+
+/*
+	Same as `EvalSlots`, but stops evaluation as soon as the given [ctx] is done. See `EvaluableExpression.EvalContext`.
+*/
+func (this CompiledExpression) EvalSlotsContext(ctx context.Context, values []interface{}) (interface{}, error) {
+
+	var parameters Parameters = DUMMY_PARAMETERS
+
+	if len(values) < len(this.variables) {
+		return nil, fmt.Errorf("Expression has %d variables, but only %d values were given", len(this.variables), len(values))
+	}
+
+	// slots are never nil when they're given, even if the expression has no variables.
+	if values == nil {
+		values = []interface{}{}
+	}
+
+	if this.accesses {
+		parameters = slotParameters{this.variables, values}
+	}
+	return this.run(ctx, parameters, values)
+}
+
+/*
+	Binding holds the values of the variables of a CompiledExpression, which can be set by slot and then evaluated over and over.
+	Variables which haven't been set give a MissingParameterError when they're used.
+
+	A Binding is meant to be reused, such as for every row of a table, but only by one goroutine at a time.
+*/
+type Binding struct {
+	expression CompiledExpression
+	values     []interface{}
+}
+
+/*
+	Returns a new Binding for this expression, with none of its variables set.
+*/
+func (this CompiledExpression) NewBinding() *Binding {
+
+	ret := &Binding{
+		expression: this,
+		values:     make([]interface{}, len(this.variables)),
+	}
+	ret.Reset()
+	return ret
+}
+
+/*
+	Sets the variable of the given [name] to [value]. Returns false (and sets nothing) if the expression doesn't use the variable.
+	For speed, use `SetSlot` with a slot found once with `Slot()`.
+*/
+func (this *Binding) Set(name string, value interface{}) bool {
+
+	slot, found := this.expression.slots[name]
+	if !found {
+		return false
+	}
+
+	this.values[slot] = value
+	return true
+}
+
+/*
+	Sets the variable in the given [slot] to [value].
+*/
+func (this *Binding) SetSlot(slot int, value interface{}) {
+	this.values[slot] = value
+}
+
+/*
+	Unsets every variable.
+*/
+func (this *Binding) Reset() {
+
+	for i := range this.values {
+		this.values[i] = unboundSlot
+	}
+}
+
+/*
+	Returns the value of the variable of the given [name], so that a Binding can be used as Parameters.
+*/
+func (this *Binding) Get(name string) (interface{}, error) {
+	return slotParameters{this.expression.variables, this.values}.Get(name)
+}
+
+/*
+	Runs the expression using the values which have been set.
+*/
+func (this *Binding) Eval() (interface{}, error) {
+
+	return this.EvalContext(context.Background())
+}
+
+/*
+	Same as `Eval`, but stops evaluation as soon as the given [ctx] is done. See `EvaluableExpression.EvalContext`.
+*/
+func (this *Binding) EvalContext(ctx context.Context) (interface{}, error) {
+
+	var parameters Parameters = DUMMY_PARAMETERS
+
+	if this.expression.accesses {
+		parameters = this
+	}
+	return this.expression.run(ctx, parameters, this.values)
+}
+
+/*
+	Gets parameters by name from slots, for accessors.
+*/
+type slotParameters struct {
+	variables []string
+	values    []interface{}
+}
+
+func (this slotParameters) Get(name string) (interface{}, error) {
+
+	// expressions have few enough variables that this is faster than a map.
+	for slot, variable := range this.variables {
+
+		if variable == name && this.values[slot] != unboundSlot {
+			return this.values[slot], nil
+		}
+	}
+	return nil, MissingParameterError{Name: name}
+}
@@ -0,0 +1,355 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+/*
+	Represents an exact decimal number, used for all numbers when an expression is parsed with DECIMAL_NUMERICS.
+	A Decimal is an arbitrary-precision integer (the "unscaled" value), and a number of digits after the decimal point (the "scale").
+	For instance, "1.50" has an unscaled value of 150, and a scale of 2.
+
+	Decimals are immutable, every operation returns a new Decimal.
+	The zero value is the number 0.
+*/
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+var bigTen = big.NewInt(10)
+
+/*
+	The largest number of bits that the unscaled value of a power may have, about 20,000 decimal digits.
+*/
+const maxDecimalPowerBits = 1 << 16
+
+/*
+	Parses the given [text] as a Decimal. Accepts an optional sign, digits with an optional decimal point,
+	and an optional exponent (such as "1.5e3").
+*/
+func ParseDecimal(text string) (Decimal, error) {
+
+	var unscaled *big.Int
+	var exponent int64
+	var scale int64
+	var err error
+	var ok bool
+
+	mantissa := text
+
+	exponentIndex := strings.IndexAny(text, "eE")
+	if exponentIndex >= 0 {
+
+		mantissa = text[:exponentIndex]
+		exponent, err = strconv.ParseInt(text[exponentIndex+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("Unable to parse decimal '%s': invalid exponent", text)
+		}
+	}
+
+	pointIndex := strings.Index(mantissa, ".")
+	if pointIndex >= 0 {
+		scale = int64(len(mantissa) - pointIndex - 1)
+		mantissa = mantissa[:pointIndex] + mantissa[pointIndex+1:]
+	}
+
+	if len(strings.TrimLeft(mantissa, "+-")) == 0 {
+		return Decimal{}, fmt.Errorf("Unable to parse decimal '%s': no digits", text)
+	}
+
+	unscaled, ok = new(big.Int).SetString(mantissa, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("Unable to parse decimal '%s'", text)
+	}
+
+	scale -= exponent
+	if scale < math.MinInt32 || scale > math.MaxInt32 {
+		return Decimal{}, fmt.Errorf("Unable to parse decimal '%s': exponent out of range", text)
+	}
+
+	return newDecimal(unscaled, int32(scale)), nil
+}
+
+/*
+	Returns a Decimal with the same value as the given integer.
+*/
+func NewDecimalFromInt(value int64) Decimal {
+	return Decimal{big.NewInt(value), 0}
+}
+
+/*
+	Returns a Decimal with the same value as the shortest decimal representation of the given float.
+	(e.g., 0.1 becomes exactly 0.1, not the nearest binary fraction)
+	Returns an error if the float is NaN or infinite.
+*/
+func NewDecimalFromFloat(value float64) (Decimal, error) {
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Decimal{}, fmt.Errorf("Unable to represent '%v' as a decimal", value)
+	}
+
+	return ParseDecimal(strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+/*
+	Creates a decimal, never allowing a negative scale; those are instead multiplied into the unscaled value.
+*/
+func newDecimal(unscaled *big.Int, scale int32) Decimal {
+
+	if scale < 0 {
+		unscaled = new(big.Int).Mul(unscaled, pow10(int64(-scale)))
+		scale = 0
+	}
+	return Decimal{unscaled, scale}
+}
+
+func pow10(exponent int64) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(exponent), nil)
+}
+
+func (this Decimal) integer() *big.Int {
+
+	if this.unscaled == nil {
+		return new(big.Int)
+	}
+	return this.unscaled
+}
+
+/*
+	Returns the unscaled values of [this] and [other], multiplied so that they both have the larger of the two scales.
+*/
+func (this Decimal) align(other Decimal) (*big.Int, *big.Int, int32) {
+
+	left := this.integer()
+	right := other.integer()
+
+	if this.scale > other.scale {
+		return left, new(big.Int).Mul(right, pow10(int64(this.scale-other.scale))), this.scale
+	}
+	if other.scale > this.scale {
+		return new(big.Int).Mul(left, pow10(int64(other.scale-this.scale))), right, other.scale
+	}
+	return left, right, this.scale
+}
+
+func (this Decimal) Add(other Decimal) Decimal {
+
+	left, right, scale := this.align(other)
+	return Decimal{new(big.Int).Add(left, right), scale}
+}
+
+func (this Decimal) Sub(other Decimal) Decimal {
+
+	left, right, scale := this.align(other)
+	return Decimal{new(big.Int).Sub(left, right), scale}
+}
+
+func (this Decimal) Mul(other Decimal) Decimal {
+	return Decimal{new(big.Int).Mul(this.integer(), other.integer()), this.scale + other.scale}
+}
+
+func (this Decimal) Neg() Decimal {
+	return Decimal{new(big.Int).Neg(this.integer()), this.scale}
+}
+
+/*
+	Divides [this] by [other], keeping [precision] digits after the decimal point.
+	Any further digits are rounded according to [rounding].
+	Returns an error if [other] is zero.
+*/
+func (this Decimal) Quo(other Decimal, precision int, rounding big.RoundingMode) (Decimal, error) {
+
+	if precision < 0 || precision > math.MaxInt32 {
+		return Decimal{}, fmt.Errorf("Invalid decimal precision %d", precision)
+	}
+
+	if other.Sign() == 0 {
+		return Decimal{}, errors.New(decimalDivisionByZero)
+	}
+
+	// this / other == (unscaled * 10^(precision + other.scale)) / (otherUnscaled * 10^this.scale), at [precision] digits.
+	numerator := new(big.Int).Mul(this.integer(), pow10(int64(precision)+int64(other.scale)))
+	denominator := new(big.Int).Mul(other.integer(), pow10(int64(this.scale)))
+
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	return Decimal{roundQuotient(quotient, remainder, denominator, rounding), int32(precision)}, nil
+}
+
+/*
+	Returns the remainder of dividing [this] by [other]. The result has the same sign as [this], as with Go's `%` and `math.Mod`.
+	Returns an error if [other] is zero.
+*/
+func (this Decimal) Rem(other Decimal) (Decimal, error) {
+
+	if other.Sign() == 0 {
+		return Decimal{}, errors.New(decimalDivisionByZero)
+	}
+
+	left, right, scale := this.align(other)
+	return Decimal{new(big.Int).Rem(left, right), scale}, nil
+}
+
+/*
+	Raises [this] to the given integer power.
+	Negative powers require a division, which is done with the given [precision] and [rounding].
+*/
+func (this Decimal) Pow(exponent int64, precision int, rounding big.RoundingMode) (Decimal, error) {
+
+	if exponent == math.MinInt64 {
+		return Decimal{}, fmt.Errorf("Decimal power %v ** %d is too large", this, exponent)
+	}
+
+	if exponent < 0 {
+
+		denominator, err := this.Pow(-exponent, precision, rounding)
+		if err != nil {
+			return Decimal{}, err
+		}
+		return NewDecimalFromInt(1).Quo(denominator, precision, rounding)
+	}
+
+	// the result has at least this many bits, so this refuses powers which would take too long, or too much memory, to compute.
+	// Powers of 0, 1 and -1 are cheap no matter the exponent.
+	bits := int64(this.integer().BitLen() - 1)
+	if bits > 0 && exponent > maxDecimalPowerBits/bits {
+		return Decimal{}, fmt.Errorf("Decimal power %v ** %d is too large", this, exponent)
+	}
+
+	if int64(this.scale)*exponent > math.MaxInt32 {
+		return Decimal{}, fmt.Errorf("Decimal power %v ** %d is too large", this, exponent)
+	}
+
+	unscaled := new(big.Int).Exp(this.integer(), big.NewInt(exponent), nil)
+	return Decimal{unscaled, this.scale * int32(exponent)}, nil
+}
+
+/*
+	Returns this number rounded to [places] digits after the decimal point.
+	Numbers which already have [places] or fewer digits are returned unmodified.
+*/
+func (this Decimal) Round(places int, rounding big.RoundingMode) Decimal {
+
+	if places >= int(this.scale) {
+		return this
+	}
+
+	divisor := pow10(int64(this.scale) - int64(places))
+	quotient, remainder := new(big.Int).QuoRem(this.integer(), divisor, new(big.Int))
+	return newDecimal(roundQuotient(quotient, remainder, divisor, rounding), int32(places))
+}
+
+/*
+	Given the truncated [quotient] and [remainder] of a division by [divisor],
+	adjusts the quotient by one (away from zero, or not) according to [rounding].
+*/
+func roundQuotient(quotient *big.Int, remainder *big.Int, divisor *big.Int, rounding big.RoundingMode) *big.Int {
+
+	if remainder.Sign() == 0 {
+		return quotient
+	}
+
+	// the sign of the exact result. The quotient may be zero, so it can't be used for this.
+	sign := remainder.Sign() * divisor.Sign()
+
+	// compares the discarded fraction to one half.
+	half := new(big.Int).Abs(remainder)
+	half.Lsh(half, 1)
+	half.Sub(half, new(big.Int).Abs(divisor))
+	comparison := half.Sign()
+
+	var increment bool
+
+	switch rounding {
+	case big.ToNearestEven:
+		increment = comparison > 0 || (comparison == 0 && quotient.Bit(0) == 1)
+	case big.ToNearestAway:
+		increment = comparison >= 0
+	case big.ToZero:
+		increment = false
+	case big.AwayFromZero:
+		increment = true
+	case big.ToNegativeInf:
+		increment = sign < 0
+	case big.ToPositiveInf:
+		increment = sign > 0
+	}
+
+	if !increment {
+		return quotient
+	}
+	return quotient.Add(quotient, big.NewInt(int64(sign)))
+}
+
+/*
+	Returns -1, 0, or +1, depending on whether [this] is less than, equal to, or greater than [other].
+*/
+func (this Decimal) Cmp(other Decimal) int {
+
+	left, right, _ := this.align(other)
+	return left.Cmp(right)
+}
+
+/*
+	Returns -1, 0, or +1, depending on whether this is negative, zero, or positive.
+*/
+func (this Decimal) Sign() int {
+	return this.integer().Sign()
+}
+
+/*
+	Returns true if this number has no fractional part.
+*/
+func (this Decimal) IsInteger() bool {
+
+	if this.scale == 0 {
+		return true
+	}
+	return new(big.Int).Rem(this.integer(), pow10(int64(this.scale))).Sign() == 0
+}
+
+/*
+	Returns the integer part of this number, and whether or not it fit into an int64.
+*/
+func (this Decimal) Int64() (int64, bool) {
+
+	integer := new(big.Int).Quo(this.integer(), pow10(int64(this.scale)))
+	return integer.Int64(), integer.IsInt64()
+}
+
+/*
+	Returns the float64 nearest to this number.
+*/
+func (this Decimal) Float64() float64 {
+
+	ret, _ := new(big.Rat).SetFrac(this.integer(), pow10(int64(this.scale))).Float64()
+	return ret
+}
+
+/*
+	Returns the exact decimal text of this number, such as "-12.50". The scale is preserved, so trailing zeroes are kept.
+*/
+func (this Decimal) String() string {
+
+	text := new(big.Int).Abs(this.integer()).String()
+
+	if this.scale > 0 {
+
+		if len(text) <= int(this.scale) {
+			text = strings.Repeat("0", int(this.scale)-len(text)+1) + text
+		}
+
+		point := len(text) - int(this.scale)
+		text = text[:point] + "." + text[point:]
+	}
+
+	if this.Sign() < 0 {
+		return "-" + text
+	}
+	return text
+}
@@ -1,8 +1,9 @@
 package govaluate
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"strings"
 )
 
 const isoDateFormat string = "2006-01-02T15:04:05.999999999Z0700"
@@ -13,11 +14,15 @@ var DUMMY_PARAMETERS = MapParameters(map[string]interface{}{})
 /*
 	EvaluableExpression represents a set of ExpressionTokens which, taken together,
 	are an expression that can be evaluated down into a single value.
+
+	Evaluation never changes an expression, so one expression can be evaluated by many goroutines at once
+	(see `EvalParallel`), so long as its fields aren't changed meanwhile, and the parameters and functions it's given are safe to use concurrently.
 */
 type EvaluableExpression struct {
 
 	/*
-		Represents the query format used to output dates. Typically only used when creating SQL or Mongo queries from an expression.
+		Represents the query format used to output dates. Typically only used when creating SQL queries from an expression,
+		since Mongo queries keep times as `time.Time`.
 		Defaults to the complete ISO8601 format, including nanoseconds.
 	*/
 	QueryDateFormat string
@@ -32,9 +37,17 @@ type EvaluableExpression struct {
 	*/
 	ChecksTypes bool
 
+	/*
+		Limits placed upon every evaluation of this expression. See EvaluationOptions for details.
+		By default, no limits are enforced.
+	*/
+	Options EvaluationOptions
+
 	tokens           []ExpressionToken
+	sources          []tokenSource
 	evaluationStages *evaluationStage
 	inputExpression  string
+	parsingOptions   ParsingOptions
 }
 
 /*
@@ -53,28 +66,52 @@ func NewEvaluableExpression(expression string) (*EvaluableExpression, error) {
 */
 func NewEvaluableExpressionFromTokens(tokens []ExpressionToken) (*EvaluableExpression, error) {
 
+	return newEvaluableExpressionFromTokens(tokens, make([]tokenSource, len(tokens)), ParsingOptions{})
+}
+
+/*
+	Similar to [NewEvaluableExpressionFromTokens], except that the expression is made from the given abstract syntax tree,
+	such as one returned by `AST()` and then changed. See `Rewrite` for an easier way to change an existing expression.
+
+	Any number in the tree is converted to the NumericMode of the given [options].
+	Any FunctionNode without a Function calls the function of the same Name from the given [options].
+	Returns an error if the tree can't be made into a valid expression.
+*/
+func NewEvaluableExpressionFromAST(node ASTNode, options ParsingOptions) (*EvaluableExpression, error) {
+
+	tokens, sources, err := makeASTTokens(node, options)
+	if err != nil {
+		return nil, err
+	}
+	return newEvaluableExpressionFromTokens(tokens, sources, options)
+}
+
+func newEvaluableExpressionFromTokens(tokens []ExpressionToken, sources []tokenSource, options ParsingOptions) (*EvaluableExpression, error) {
+
 	var ret *EvaluableExpression
 	var err error
 
 	ret = new(EvaluableExpression)
 	ret.QueryDateFormat = isoDateFormat
+	ret.parsingOptions = options
+	ret.sources = sources
 
-	err = checkBalance(tokens)
+	err = checkBalance(tokens, sources)
 	if err != nil {
 		return nil, err
 	}
 
-	err = checkExpressionSyntax(tokens)
+	err = checkExpressionSyntax(tokens, sources)
 	if err != nil {
 		return nil, err
 	}
 
-	ret.tokens, err = optimizeTokens(tokens)
+	ret.tokens, err = optimizeTokens(tokens, sources)
 	if err != nil {
 		return nil, err
 	}
 
-	ret.evaluationStages, err = planStages(ret.tokens)
+	ret.evaluationStages, err = planStages(ret.tokens, ret.sources, options)
 	if err != nil {
 		return nil, err
 	}
@@ -89,34 +126,59 @@ func NewEvaluableExpressionFromTokens(tokens []ExpressionToken) (*EvaluableExpre
 */
 func NewEvaluableExpressionWithFunctions(expression string, functions map[string]ExpressionFunction) (*EvaluableExpression, error) {
 
+	options := ParsingOptions{
+		Functions: functions,
+	}
+	return NewEvaluableExpressionWithOptions(expression, options)
+}
+
+/*
+	Similar to [NewEvaluableExpressionWithFunctions], except that the given functions receive the context
+	passed to `EvalContext` whenever they are called.
+*/
+func NewEvaluableExpressionWithContextFunctions(expression string, functions map[string]ContextExpressionFunction) (*EvaluableExpression, error) {
+
+	options := ParsingOptions{
+		ContextFunctions: functions,
+	}
+	return NewEvaluableExpressionWithOptions(expression, options)
+}
+
+/*
+	Similar to [NewEvaluableExpression], except that the given [options] control how the expression is parsed,
+	such as which functions are available and how numbers are represented.
+*/
+func NewEvaluableExpressionWithOptions(expression string, options ParsingOptions) (*EvaluableExpression, error) {
+
 	var ret *EvaluableExpression
 	var err error
 
 	ret = new(EvaluableExpression)
 	ret.QueryDateFormat = isoDateFormat
 	ret.inputExpression = expression
+	ret.parsingOptions = options
 
-	ret.tokens, err = parseTokens(expression, functions)
+	ret.tokens, ret.sources, err = parseTokens(expression, options)
 	if err != nil {
 		return nil, err
 	}
 
-	err = checkBalance(ret.tokens)
+	err = checkBalance(ret.tokens, ret.sources)
 	if err != nil {
 		return nil, err
 	}
 
-	err = checkExpressionSyntax(ret.tokens)
+	err = checkExpressionSyntax(ret.tokens, ret.sources)
 	if err != nil {
 		return nil, err
 	}
 
-	ret.tokens, err = optimizeTokens(ret.tokens)
+	ret.tokens, err = optimizeTokens(ret.tokens, ret.sources)
 	if err != nil {
 		return nil, err
 	}
 
-	ret.evaluationStages, err = planStages(ret.tokens)
+	ret.evaluationStages, err = planStages(ret.tokens, ret.sources, options)
 	if err != nil {
 		return nil, err
 	}
@@ -150,26 +212,60 @@ func (this EvaluableExpression) Evaluate(parameters map[string]interface{}) (int
 */
 func (this EvaluableExpression) Eval(parameters Parameters) (interface{}, error) {
 
+	return this.EvalContext(context.Background(), parameters)
+}
+
+/*
+	Same as `Eval`, but stops evaluation as soon as the given [ctx] is done, returning the context's error.
+	The context is checked before every stage of the expression is run,
+	and is passed to any `ContextExpressionFunction`, and any accessor method whose first argument is a `context.Context`.
+
+	Note that a function or method which is already running will not be interrupted; it's expected to watch [ctx] itself.
+*/
+func (this EvaluableExpression) EvalContext(ctx context.Context, parameters Parameters) (interface{}, error) {
+
 	if this.evaluationStages == nil {
 		return nil, nil
 	}
 
 	if parameters != nil {
-		parameters = &sanitizedParameters{parameters}
+		parameters = &sanitizedParameters{parameters, this.parsingOptions.NumericMode}
 	} else {
 		parameters = DUMMY_PARAMETERS
 	}
 
-	return this.evaluateStage(this.evaluationStages, parameters)
+	state := &evaluationState{
+		ctx: ctx,
+	}
+	return this.evaluateStage(state, this.evaluationStages, parameters)
 }
 
-func (this EvaluableExpression) evaluateStage(stage *evaluationStage, parameters Parameters) (interface{}, error) {
+/*
+	Holds everything which is specific to one evaluation of an expression.
+*/
+type evaluationState struct {
+	ctx   context.Context
+	steps int
+}
+
+func (this EvaluableExpression) evaluateStage(state *evaluationState, stage *evaluationStage, parameters Parameters) (interface{}, error) {
 
 	var left, right interface{}
 	var err error
 
+	select {
+	case <-state.ctx.Done():
+		return nil, state.ctx.Err()
+	default:
+	}
+
+	state.steps++
+	if this.Options.MaxSteps > 0 && state.steps > this.Options.MaxSteps {
+		return nil, LimitExceededError{STEP_LIMIT, this.Options.MaxSteps}
+	}
+
 	if stage.leftStage != nil {
-		left, err = this.evaluateStage(stage.leftStage, parameters)
+		left, err = this.evaluateStage(state, stage.leftStage, parameters)
 		if err != nil {
 			return nil, err
 		}
@@ -202,34 +298,63 @@ func (this EvaluableExpression) evaluateStage(stage *evaluationStage, parameters
 	}
 
 	if right != shortCircuitHolder && stage.rightStage != nil {
-		right, err = this.evaluateStage(stage.rightStage, parameters)
+		right, err = this.evaluateStage(state, stage.rightStage, parameters)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	return this.operateStage(state, stage, left, right, parameters)
+}
+
+/*
+	Type checks and runs the operator of the given [stage], once its [left] and [right] sides have been evaluated.
+*/
+func (this EvaluableExpression) operateStage(state *evaluationState, stage *evaluationStage, left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	var ret interface{}
+	var err error
+
 	if this.ChecksTypes {
 		if stage.typeCheck == nil {
 
 			err = typeCheck(stage.leftTypeCheck, left, stage.symbol, stage.typeErrorFormat)
 			if err != nil {
-				return nil, err
+				return nil, this.locateError(state, stage, err)
 			}
 
 			err = typeCheck(stage.rightTypeCheck, right, stage.symbol, stage.typeErrorFormat)
 			if err != nil {
-				return nil, err
+				return nil, this.locateError(state, stage, err)
 			}
 		} else {
 			// special case where the type check needs to know both sides to determine if the operator can handle it
 			if !stage.typeCheck(left, right) {
-				errorMsg := fmt.Sprintf(stage.typeErrorFormat, left, stage.symbol.String())
-				return nil, errors.New(errorMsg)
+				err = makeTypeError(left, stage.symbol, stage.typeErrorFormat)
+				return nil, this.locateError(state, stage, err)
 			}
 		}
 	}
 
-	return stage.operator(left, right, parameters)
+	if stage.contextOperator != nil {
+		ret, err = stage.contextOperator(state.ctx, left, right, parameters)
+	} else {
+		ret, err = stage.operator(left, right, parameters)
+	}
+	if err != nil {
+		return ret, this.locateError(state, stage, err)
+	}
+
+	// values reached through accessors haven't been through the sanitization that parameters have.
+	if stage.symbol == ACCESS {
+		ret = sanitizeNumeric(ret, this.parsingOptions.NumericMode)
+	}
+
+	err = this.Options.checkResult(stage.symbol, ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
 }
 
 func typeCheck(check stageTypeCheck, value interface{}, symbol OperatorSymbol, format string) error {
@@ -242,8 +367,50 @@ func typeCheck(check stageTypeCheck, value interface{}, symbol OperatorSymbol, f
 		return nil
 	}
 
-	errorMsg := fmt.Sprintf(format, value, symbol.String())
-	return errors.New(errorMsg)
+	return makeTypeError(value, symbol, format)
+}
+
+func makeTypeError(value interface{}, symbol OperatorSymbol, format string) TypeError {
+
+	return TypeError{
+		Message: fmt.Sprintf(format, value, symbol.String()),
+		Symbol:  symbol,
+		Value:   value,
+	}
+}
+
+/*
+	Adds the location of the given [stage] to an error which happened while evaluating it.
+	Errors from functions and accessors are wrapped in a FunctionError, unless they're just the cancellation of the evaluation itself.
+*/
+func (this EvaluableExpression) locateError(state *evaluationState, stage *evaluationStage, err error) error {
+
+	switch err.(type) {
+	case TypeError:
+		typeError := err.(TypeError)
+		typeError.Position = stage.source.start
+		typeError.Token = stage.token
+		return typeError
+
+	case MissingParameterError:
+		missingError := err.(MissingParameterError)
+		missingError.Position = stage.source.start
+		missingError.Symbol = stage.symbol
+		missingError.Token = stage.token
+		return missingError
+	}
+
+	if err == state.ctx.Err() {
+		return err
+	}
+
+	switch stage.symbol {
+	case FUNCTIONAL:
+		return FunctionError{stage.source.name, stage.source.start, stage.token, err}
+	case ACCESS:
+		return FunctionError{strings.Join(stage.token.Value.([]string), "."), stage.source.start, stage.token, err}
+	}
+	return err
 }
 
 /*
@@ -256,9 +423,15 @@ func (this EvaluableExpression) Tokens() []ExpressionToken {
 
 /*
 	Returns the original expression used to create this EvaluableExpression.
+	Expressions which weren't parsed from a string (such as those made from tokens, or rewritten) return their `Formatted()` text instead,
+	or an empty string if they can't be formatted.
 */
 func (this EvaluableExpression) String() string {
 
+	if this.inputExpression == "" {
+		ret, _ := this.Formatted()
+		return ret
+	}
 	return this.inputExpression
 }
 
@@ -0,0 +1,157 @@
+package govaluate
+
+/*
+	Returns the abstract syntax tree of this expression, or nil if the expression is empty.
+	The tree follows the same precedence and associativity that evaluation does, so it can be used to analyze or translate
+	the expression without parsing its tokens again. Parenthesis which only group operators don't have their own node.
+
+	Unlike evaluation, the tree has every literal of the original expression; constant parts (like `1 + 2`) are not precomputed.
+*/
+func (this EvaluableExpression) AST() ASTNode {
+
+	// these tokens were already planned when this expression was made, so planning can't fail here.
+	stage, err := planStageTree(this.tokens, this.sources)
+	if err != nil || stage == nil {
+		return nil
+	}
+	return this.makeASTNode(stage)
+}
+
+/*
+	Returns a new expression made from the AST of this one, after it's been transformed by the given [rewriter] (see `Rewrite`).
+	The new expression has the same options as this one, and is parsed with the same functions and NumericMode.
+	So to call a different function, a FunctionNode can be given the Name of any function this expression was parsed with,
+	and its Function set to nil.
+
+	Returns an error if the rewritten tree isn't a valid expression.
+*/
+func (this EvaluableExpression) Rewrite(rewriter func(node ASTNode) ASTNode) (*EvaluableExpression, error) {
+
+	ret, err := NewEvaluableExpressionFromAST(Rewrite(this.AST(), rewriter), this.parsingOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.QueryDateFormat = this.QueryDateFormat
+	ret.ChecksTypes = this.ChecksTypes
+	ret.Options = this.Options
+	return ret, nil
+}
+
+func (this EvaluableExpression) makeASTNode(stage *evaluationStage) ASTNode {
+
+	var ret ASTNode
+	var span Span
+
+	span = sourceSpan(stage.source)
+
+	switch stage.symbol {
+
+	case NOOP:
+
+		// empty parenthesis are an empty array.
+		if stage.rightStage == nil {
+			ret = &ArrayNode{}
+		} else {
+			ret = this.makeASTNode(stage.rightStage)
+		}
+		span = span.extend(sourceSpan(stage.closingSource))
+
+	case LITERAL:
+		ret = &LiteralNode{Value: stage.token.Value}
+
+	case VALUE:
+		ret = &VariableNode{Name: stage.token.Value.(string)}
+
+	case FUNCTIONAL:
+		ret = &FunctionNode{
+			Name:      stage.source.name,
+			Function:  stage.token.Value,
+			Arguments: this.makeArguments(stage.rightStage),
+		}
+		span = span.extend(sourceSpan(stage.rightStage.closingSource))
+
+	case ACCESS:
+		ret = &AccessorNode{
+			Path:      stage.token.Value.([]string),
+			Called:    stage.rightStage != nil,
+			Arguments: this.makeArguments(stage.rightStage),
+		}
+		if stage.rightStage != nil {
+			span = span.extend(sourceSpan(stage.rightStage.closingSource))
+		}
+
+	case SEPARATE:
+		ret = &ArrayNode{Elements: this.makeElements(stage)}
+
+	case NEGATE:
+		fallthrough
+	case INVERT:
+		fallthrough
+	case BITWISE_NOT:
+		ret = &UnaryNode{
+			Operator: stage.symbol,
+			Operand:  this.makeASTNode(stage.rightStage),
+		}
+
+	case TERNARY_TRUE:
+		ret = &TernaryNode{
+			Condition: this.makeASTNode(stage.leftStage),
+			Then:      this.makeASTNode(stage.rightStage),
+		}
+
+	case TERNARY_FALSE:
+
+		// `a ? b : c` is planned as the "false" half (`:`) of the ternary, whose left side is the "true" half (`a ? b`).
+		if stage.leftStage.symbol == TERNARY_TRUE {
+
+			ternary := this.makeASTNode(stage.leftStage).(*TernaryNode)
+			ternary.Else = this.makeASTNode(stage.rightStage)
+			ret = ternary
+			break
+		}
+		fallthrough
+
+	default:
+		ret = &BinaryNode{
+			Operator: stage.symbol,
+			Left:     this.makeASTNode(stage.leftStage),
+			Right:    this.makeASTNode(stage.rightStage),
+		}
+	}
+
+	// a node covers its own token, and every node below it.
+	span = span.extend(ret.Span())
+	for _, child := range ret.Children() {
+		span = span.extend(child.Span())
+	}
+
+	ret.setSpan(span)
+	return ret
+}
+
+/*
+	Returns the arguments given to a function or accessor, from the parenthesis [stage] which holds them.
+*/
+func (this EvaluableExpression) makeArguments(stage *evaluationStage) []ASTNode {
+
+	if stage == nil || stage.rightStage == nil {
+		return nil
+	}
+	return this.makeElements(stage.rightStage)
+}
+
+/*
+	Returns each element of a list of values separated by commas. If [stage] isn't a list, it's the only element.
+*/
+func (this EvaluableExpression) makeElements(stage *evaluationStage) []ASTNode {
+
+	if stage.symbol != SEPARATE {
+		return []ASTNode{this.makeASTNode(stage)}
+	}
+	return append(this.makeElements(stage.leftStage), this.makeElements(stage.rightStage)...)
+}
+
+func sourceSpan(source tokenSource) Span {
+	return Span{source.start, source.end}
+}
@@ -0,0 +1,656 @@
+package govaluate
+
+import (
+	"context"
+	"math"
+)
+
+// the number of rows of columns which are evaluated together.
+const columnChunkSize int = 1024
+
+/*
+	Columns holds the parameters of many rows, with a slice of values (one per row) for each parameter.
+	Parameters are looked for in Numbers, then Strings, then Booleans, then Values.
+	Rows past the end of a shorter column are missing that parameter.
+*/
+type Columns struct {
+	Numbers  map[string][]float64
+	Strings  map[string][]string
+	Booleans map[string][]bool
+
+	// any other values, such as times, structs used by accessors, or columns which have more than one type.
+	Values map[string][]interface{}
+}
+
+/*
+	Evaluates this expression once for each of the given [rows] of parameters, the same as `Eval`.
+	Returns the result and error of each row, in the same order as the rows; the error of a row which succeeded is nil.
+*/
+func (this EvaluableExpression) EvalBatch(rows []Parameters) ([]interface{}, []error) {
+
+	results := make([]interface{}, len(rows))
+	errors := make([]error, len(rows))
+
+	if this.evaluationStages == nil {
+		return results, errors
+	}
+
+	// every row uses the same state and wrapper, rather than making them again for each.
+	state := &evaluationState{ctx: context.Background()}
+	sanitized := &sanitizedParameters{numericMode: this.parsingOptions.NumericMode}
+
+	for i, parameters := range rows {
+
+		var rowParameters Parameters = DUMMY_PARAMETERS
+
+		if parameters != nil {
+			sanitized.orig = parameters
+			rowParameters = sanitized
+		}
+
+		state.steps = 0
+		results[i], errors[i] = this.evaluateStage(state, this.evaluationStages, rowParameters)
+	}
+
+	return results, errors
+}
+
+/*
+	Evaluates this expression for every row of the given [columns], giving the same results and errors as `EvalBatch` would for the same rows.
+	The number of rows is the length of the longest column.
+
+	Rather than evaluating each row in turn, every stage of the expression is evaluated for many rows at once.
+	Arithmetic and comparisons between columns of numbers (or strings), and logic between columns of booleans, run in tight loops
+	without converting values to interfaces; anything else (such as functions, accessors, or regexes) is still run one row at a time.
+*/
+func (this EvaluableExpression) EvalColumns(columns Columns) ([]interface{}, []error) {
+
+	length := columns.length()
+	results := make([]interface{}, length)
+	errors := make([]error, length)
+
+	if this.evaluationStages == nil {
+		return results, errors
+	}
+
+	evaluator := &columnEvaluator{
+		expression: this,
+		columns:    &columns,
+		state:      &evaluationState{ctx: context.Background()},
+	}
+	evaluator.row.columns = &columns
+	evaluator.row.numericMode = this.parsingOptions.NumericMode
+
+	for start := 0; start < length; start += columnChunkSize {
+
+		end := start + columnChunkSize
+		if end > length {
+			end = length
+		}
+
+		evaluator.start = start
+		evaluator.errors = errors[start:end]
+		evaluator.steps = make([]int, end-start)
+
+		active := make([]bool, end-start)
+		for row := range active {
+			active[row] = true
+		}
+
+		vector := evaluator.evaluate(this.evaluationStages, active)
+
+		for row := range active {
+			if errors[start+row] == nil {
+				results[start+row] = vector.get(row)
+			}
+		}
+	}
+
+	return results, errors
+}
+
+/*
+	Returns the length of the longest column.
+*/
+func (this Columns) length() int {
+
+	var ret int
+
+	for _, column := range this.Numbers {
+		ret = maxInt(ret, len(column))
+	}
+	for _, column := range this.Strings {
+		ret = maxInt(ret, len(column))
+	}
+	for _, column := range this.Booleans {
+		ret = maxInt(ret, len(column))
+	}
+	for _, column := range this.Values {
+		ret = maxInt(ret, len(column))
+	}
+	return ret
+}
+
+/*
+	The values of one stage for every row of a chunk. Only one of the slices is set, or none if every row has the same value.
+*/
+type columnVector struct {
+	constant interface{}
+
+	numbers  []float64
+	strings  []string
+	booleans []bool
+	values   []interface{}
+}
+
+func (this columnVector) get(row int) interface{} {
+
+	switch {
+	case this.numbers != nil:
+		return this.numbers[row]
+	case this.strings != nil:
+		return this.strings[row]
+	case this.booleans != nil:
+		return this.booleans[row]
+	case this.values != nil:
+		return this.values[row]
+	}
+	return this.constant
+}
+
+func (this columnVector) isNumbers() bool {
+
+	if this.numbers != nil {
+		return true
+	}
+	return this.strings == nil && this.booleans == nil && this.values == nil && isFloat64(this.constant)
+}
+
+func (this columnVector) isStrings() bool {
+
+	if this.strings != nil {
+		return true
+	}
+	return this.numbers == nil && this.booleans == nil && this.values == nil && isString(this.constant)
+}
+
+func (this columnVector) isBooleans() bool {
+
+	if this.booleans != nil {
+		return true
+	}
+	return this.numbers == nil && this.strings == nil && this.values == nil && isBool(this.constant)
+}
+
+func (this columnVector) number(row int) float64 {
+
+	if this.numbers != nil {
+		return this.numbers[row]
+	}
+	return this.constant.(float64)
+}
+
+func (this columnVector) string(row int) string {
+
+	if this.strings != nil {
+		return this.strings[row]
+	}
+	return this.constant.(string)
+}
+
+func (this columnVector) boolean(row int) bool {
+
+	if this.booleans != nil {
+		return this.booleans[row]
+	}
+	return this.constant.(bool)
+}
+
+/*
+	Evaluates stages for a chunk of rows of columns.
+*/
+type columnEvaluator struct {
+	expression EvaluableExpression
+	columns    *Columns
+	state      *evaluationState
+
+	// the first row of the chunk, and the errors and steps of each of its rows.
+	start  int
+	errors []error
+	steps  []int
+
+	// the parameters of the row being evaluated by itself.
+	row columnParameters
+}
+
+/*
+	Evaluates the given [stage] for every [active] row of the chunk which hasn't already failed.
+	The values of other rows in the returned vector are meaningless.
+*/
+func (this *columnEvaluator) evaluate(stage *evaluationStage, active []bool) columnVector {
+
+	var left, right columnVector
+	var ret columnVector
+	var isVector bool
+
+	maxSteps := this.expression.Options.MaxSteps
+	if maxSteps > 0 {
+
+		for row := range active {
+
+			if !this.isActive(active, row) {
+				continue
+			}
+
+			this.steps[row]++
+			if this.steps[row] > maxSteps {
+				this.errors[row] = LimitExceededError{STEP_LIMIT, maxSteps}
+			}
+		}
+	}
+
+	switch stage.symbol {
+
+	case LITERAL:
+		value, _ := stage.operator(nil, nil, nil)
+		return columnVector{constant: value}
+
+	case VALUE:
+
+		name, isName := stage.token.Value.(string)
+		if isName && stage.token.Kind == VARIABLE {
+			return this.load(stage, name, active)
+		}
+
+	case NOOP:
+
+		// parenthesis only pass along their contents.
+		if stage.leftStage == nil && stage.rightStage != nil {
+			return this.evaluate(stage.rightStage, active)
+		}
+	}
+
+	if stage.leftStage != nil {
+		left = this.evaluate(stage.leftStage, active)
+	}
+
+	// rows which short-circuit don't evaluate the right side.
+	rightActive := active
+	shortCircuits := stage.isShortCircuitable() && stage.leftStage != nil && stage.rightStage != nil
+
+	if shortCircuits {
+
+		rightActive = make([]bool, len(active))
+		for row := range active {
+			rightActive[row] = this.isActive(active, row) && !isShortCircuited(stage.symbol, left.get(row))
+		}
+	}
+
+	if stage.rightStage != nil {
+		right = this.evaluate(stage.rightStage, rightActive)
+	}
+
+	ret, isVector = operateVectors(stage, left, right, len(active))
+	if isVector {
+		return ret
+	}
+
+	// anything else is run one row at a time, just as it would be by itself.
+	values := make([]interface{}, len(active))
+
+	for row := range active {
+
+		var leftValue, rightValue interface{}
+
+		if !this.isActive(active, row) {
+			continue
+		}
+
+		if stage.leftStage != nil {
+			leftValue = left.get(row)
+		}
+
+		if shortCircuits && !rightActive[row] {
+
+			switch stage.symbol {
+			case TERNARY_TRUE:
+				fallthrough
+			case TERNARY_FALSE:
+				rightValue = shortCircuitHolder
+			default:
+				values[row] = leftValue
+				continue
+			}
+
+		} else if stage.rightStage != nil {
+			rightValue = right.get(row)
+		}
+
+		this.row.index = this.start + row
+
+		value, err := this.expression.operateStage(this.state, stage, leftValue, rightValue, &this.row)
+		if err != nil {
+			this.errors[row] = err
+			continue
+		}
+		values[row] = value
+	}
+
+	return columnVector{values: values}
+}
+
+/*
+	Returns the column of the parameter of the given [name], with an error for each active row which doesn't have it.
+*/
+func (this *columnEvaluator) load(stage *evaluationStage, name string, active []bool) columnVector {
+
+	var length int
+	var ret columnVector
+
+	numericMode := this.expression.parsingOptions.NumericMode
+	start := this.start
+	end := start + len(active)
+
+	numbers, isNumbers := this.columns.Numbers[name]
+	strings, isStrings := this.columns.Strings[name]
+	booleans, isBooleans := this.columns.Booleans[name]
+	values, isValues := this.columns.Values[name]
+
+	switch {
+
+	// numbers are only kept as floats if that's how the expression represents them.
+	case isNumbers && numericMode != DECIMAL_NUMERICS:
+		length = len(numbers)
+		ret.numbers = make([]float64, len(active))
+		copy(ret.numbers, numbers[minInt(start, length):minInt(end, length)])
+
+	case isNumbers:
+		length = len(numbers)
+		ret.values = make([]interface{}, len(active))
+		for row := start; row < minInt(end, length); row++ {
+			ret.values[row-start] = sanitizeNumeric(numbers[row], numericMode)
+		}
+
+	case isStrings:
+		length = len(strings)
+		ret.strings = make([]string, len(active))
+		copy(ret.strings, strings[minInt(start, length):minInt(end, length)])
+
+	case isBooleans:
+		length = len(booleans)
+		ret.booleans = make([]bool, len(active))
+		copy(ret.booleans, booleans[minInt(start, length):minInt(end, length)])
+
+	case isValues:
+		length = len(values)
+		ret.values = make([]interface{}, len(active))
+		for row := start; row < minInt(end, length); row++ {
+			ret.values[row-start] = sanitizeNumeric(values[row], numericMode)
+		}
+	}
+
+	for row := range active {
+
+		if start+row >= length && this.isActive(active, row) {
+			this.errors[row] = this.expression.locateError(this.state, stage, MissingParameterError{Name: name})
+		}
+	}
+	return ret
+}
+
+func (this *columnEvaluator) isActive(active []bool, row int) bool {
+	return active[row] && this.errors[row] == nil
+}
+
+/*
+	Returns whether the given short-circuiting operator can skip its right side, given its [left] side.
+*/
+func isShortCircuited(symbol OperatorSymbol, left interface{}) bool {
+
+	switch symbol {
+	case AND:
+		return left == false
+	case OR:
+		return left == true
+	case TERNARY_TRUE:
+		return left == false
+	case TERNARY_FALSE:
+		fallthrough
+	case COALESCE:
+		return left != nil
+	}
+	return false
+}
+
+/*
+	Runs the operator of the given [stage] on every row of [left] and [right] at once, if they're of types it can do that for.
+	Returns false if the stage has to be run one row at a time instead.
+*/
+func operateVectors(stage *evaluationStage, left columnVector, right columnVector, length int) (columnVector, bool) {
+
+	var ret columnVector
+
+	hasLeft := stage.leftStage != nil
+	hasRight := stage.rightStage != nil
+
+	// stages which only need one side.
+	if !hasLeft && hasRight {
+
+		switch {
+		case stage.symbol == NEGATE && right.isNumbers():
+			ret.numbers = make([]float64, length)
+			for row := range ret.numbers {
+				ret.numbers[row] = -right.number(row)
+			}
+			return ret, true
+
+		case stage.symbol == INVERT && right.isBooleans():
+			ret.booleans = make([]bool, length)
+			for row := range ret.booleans {
+				ret.booleans[row] = !right.boolean(row)
+			}
+			return ret, true
+		}
+		return ret, false
+	}
+
+	if !hasLeft || !hasRight {
+		return ret, false
+	}
+
+	// rows which short-circuit are already false (for AND) or true (for OR) on the left, so they give the same result either way.
+	if (stage.symbol == AND || stage.symbol == OR) && left.isBooleans() && right.isBooleans() {
+
+		ret.booleans = make([]bool, length)
+		for row := range ret.booleans {
+
+			if stage.symbol == AND {
+				ret.booleans[row] = left.boolean(row) && right.boolean(row)
+			} else {
+				ret.booleans[row] = left.boolean(row) || right.boolean(row)
+			}
+		}
+		return ret, true
+	}
+
+	if left.isNumbers() && right.isNumbers() {
+
+		switch stage.symbol {
+		case PLUS:
+			fallthrough
+		case MINUS:
+			fallthrough
+		case MULTIPLY:
+			fallthrough
+		case DIVIDE:
+			fallthrough
+		case MODULUS:
+			fallthrough
+		case EXPONENT:
+
+			ret.numbers = make([]float64, length)
+			for row := range ret.numbers {
+				ret.numbers[row] = operateNumbers(stage.symbol, left.number(row), right.number(row))
+			}
+			return ret, true
+
+		case EQ:
+			fallthrough
+		case NEQ:
+			fallthrough
+		case GT:
+			fallthrough
+		case LT:
+			fallthrough
+		case GTE:
+			fallthrough
+		case LTE:
+
+			ret.booleans = make([]bool, length)
+			for row := range ret.booleans {
+				ret.booleans[row] = compareNumbers(stage.symbol, left.number(row), right.number(row))
+			}
+			return ret, true
+		}
+	}
+
+	if left.isStrings() && right.isStrings() {
+
+		switch stage.symbol {
+		case EQ:
+			fallthrough
+		case NEQ:
+			fallthrough
+		case GT:
+			fallthrough
+		case LT:
+			fallthrough
+		case GTE:
+			fallthrough
+		case LTE:
+
+			ret.booleans = make([]bool, length)
+			for row := range ret.booleans {
+				ret.booleans[row] = compareStrings(stage.symbol, left.string(row), right.string(row))
+			}
+			return ret, true
+		}
+	}
+
+	return ret, false
+}
+
+func operateNumbers(symbol OperatorSymbol, left float64, right float64) float64 {
+
+	switch symbol {
+	case PLUS:
+		return left + right
+	case MINUS:
+		return left - right
+	case MULTIPLY:
+		return left * right
+	case DIVIDE:
+		return left / right
+	case MODULUS:
+		return math.Mod(left, right)
+	}
+	return math.Pow(left, right)
+}
+
+func compareNumbers(symbol OperatorSymbol, left float64, right float64) bool {
+
+	switch symbol {
+	case EQ:
+		return left == right
+	case NEQ:
+		return left != right
+	case GT:
+		return left > right
+	case LT:
+		return left < right
+	case GTE:
+		return left >= right
+	}
+	return left <= right
+}
+
+func compareStrings(symbol OperatorSymbol, left string, right string) bool {
+
+	switch symbol {
+	case EQ:
+		return left == right
+	case NEQ:
+		return left != right
+	case GT:
+		return left > right
+	case LT:
+		return left < right
+	case GTE:
+		return left >= right
+	}
+	return left <= right
+}
+
+/*
+	The parameters of one row of columns, for stages which are run one row at a time (such as accessors).
+*/
+type columnParameters struct {
+	columns     *Columns
+	index       int
+	numericMode NumericMode
+}
+
+func (this *columnParameters) Get(name string) (interface{}, error) {
+
+	var value interface{}
+	var found bool
+
+	numbers, isNumbers := this.columns.Numbers[name]
+	strings, isStrings := this.columns.Strings[name]
+	booleans, isBooleans := this.columns.Booleans[name]
+	values, isValues := this.columns.Values[name]
+
+	switch {
+	case isNumbers:
+		found = this.index < len(numbers)
+		if found {
+			value = numbers[this.index]
+		}
+	case isStrings:
+		found = this.index < len(strings)
+		if found {
+			value = strings[this.index]
+		}
+	case isBooleans:
+		found = this.index < len(booleans)
+		if found {
+			value = booleans[this.index]
+		}
+	case isValues:
+		found = this.index < len(values)
+		if found {
+			value = values[this.index]
+		}
+	}
+
+	if !found {
+		return nil, MissingParameterError{Name: name}
+	}
+	return sanitizeNumeric(value, this.numericMode), nil
+}
+
+func minInt(left int, right int) int {
+
+	if left < right {
+		return left
+	}
+	return right
+}
+
+func maxInt(left int, right int) int {
+
+	if left > right {
+		return left
+	}
+	return right
+}
@@ -0,0 +1,245 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+/*
+	Returns this expression as an Elasticsearch (or OpenSearch) query, made of maps and slices which can be marshalled as JSON
+	and given as the "query" of a search request.
+
+	Comparisons become `term` and `range` queries, `=~` becomes `regexp`, `in` with a list of values becomes `terms`,
+	and `&&`, `||` and `!` become the `must`, `should` and `must_not` of `bool` queries. For example,
+	`age >= 18 && status IN ('new', 'open')` becomes:
+
+		{"bool": {"must": [
+			{"range": {"age": {"gte": 18}}},
+			{"terms": {"status": ["new", "open"]}}
+		]}}
+
+	Parameters are fields of the same name, and accessors (such as `user.Address.City`) are the dotted paths of object fields.
+	Times are formatted with this.QueryDateFormat, and exact decimals (see DECIMAL_NUMERICS) are given as the closest float64.
+	Note that Elasticsearch regexes always match the whole value, and don't support anchors like `^` or `$`.
+
+	Elasticsearch can only compare fields with constants, so anything else (such as arithmetic, comparing two fields,
+	or functions) is an error.
+*/
+func (this EvaluableExpression) ToElasticsearchQuery() (map[string]interface{}, error) {
+
+	stage, err := planStageTree(this.tokens, this.sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if stage == nil {
+		return map[string]interface{}{"match_all": map[string]interface{}{}}, nil
+	}
+
+	writer := elasticsearchWriter{dateFormat: this.QueryDateFormat}
+	return writer.writeQuery(stage)
+}
+
+type elasticsearchWriter struct {
+	dateFormat string
+}
+
+func (this elasticsearchWriter) writeQuery(stage *evaluationStage) (map[string]interface{}, error) {
+
+	var field string
+	var isField bool
+
+	switch stage.symbol {
+
+	case NOOP:
+
+		if stage.rightStage != nil && stage.rightStage.symbol != SEPARATE {
+			return this.writeQuery(stage.rightStage)
+		}
+
+	case AND:
+		fallthrough
+	case OR:
+
+		left, err := this.writeQuery(stage.leftStage)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := this.writeQuery(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+
+		if stage.symbol == AND {
+			return combineElasticsearchQueries("must", left, right), nil
+		}
+
+		ret := combineElasticsearchQueries("should", left, right)
+		ret["bool"].(map[string]interface{})["minimum_should_match"] = 1
+		return ret, nil
+
+	case INVERT:
+
+		inner, err := this.writeQuery(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+		return makeElasticsearchBool("must_not", inner), nil
+
+	case LITERAL:
+
+		if stage.token.Kind != BOOLEAN {
+			return nil, fmt.Errorf("Unable to use the constant '%v' as an Elasticsearch query", stage.token.Value)
+		}
+
+		if stage.token.Value.(bool) {
+			return map[string]interface{}{"match_all": map[string]interface{}{}}, nil
+		}
+		return map[string]interface{}{"match_none": map[string]interface{}{}}, nil
+
+	case VALUE:
+		fallthrough
+	case ACCESS:
+
+		// a parameter on its own is a boolean field.
+		field, isField = findFieldPath(stage)
+		if isField {
+			return makeElasticsearchQuery("term", field, true), nil
+		}
+		return nil, errors.New("Unable to write a method call in an Elasticsearch query")
+
+	case FUNCTIONAL:
+		return nil, fmt.Errorf("Unable to write function '%s' in an Elasticsearch query", stage.source.name)
+
+	case EQ:
+		fallthrough
+	case NEQ:
+		fallthrough
+	case GT:
+		fallthrough
+	case LT:
+		fallthrough
+	case GTE:
+		fallthrough
+	case LTE:
+
+		symbol := stage.symbol
+
+		field, isField = findFieldPath(stage.leftStage)
+		value, isValue := findConstant(stage.rightStage, this.makeValue)
+
+		// constants on the left are compared the other way around, so that `1 < foo` is `foo > 1`.
+		if !isField || !isValue {
+
+			field, isField = findFieldPath(stage.rightStage)
+			value, isValue = findConstant(stage.leftStage, this.makeValue)
+			symbol = flipComparator(symbol)
+		}
+
+		if !isField || !isValue {
+			break
+		}
+
+		switch symbol {
+		case EQ:
+			return makeElasticsearchQuery("term", field, value), nil
+		case NEQ:
+			return makeElasticsearchBool("must_not", makeElasticsearchQuery("term", field, value)), nil
+		case GT:
+			return makeElasticsearchQuery("range", field, map[string]interface{}{"gt": value}), nil
+		case LT:
+			return makeElasticsearchQuery("range", field, map[string]interface{}{"lt": value}), nil
+		case GTE:
+			return makeElasticsearchQuery("range", field, map[string]interface{}{"gte": value}), nil
+		}
+		return makeElasticsearchQuery("range", field, map[string]interface{}{"lte": value}), nil
+
+	case REQ:
+		fallthrough
+	case NREQ:
+
+		field, isField = findFieldPath(stage.leftStage)
+		pattern, isPattern := findConstantPattern(stage.rightStage)
+
+		if !isField || !isPattern {
+			break
+		}
+
+		ret := makeElasticsearchQuery("regexp", field, pattern)
+		if stage.symbol == NREQ {
+			return makeElasticsearchBool("must_not", ret), nil
+		}
+		return ret, nil
+
+	case IN:
+
+		field, isField = findFieldPath(stage.leftStage)
+		values, isList := findConstantList(stage.rightStage, this.makeValue)
+
+		if isField && isList {
+			return makeElasticsearchQuery("terms", field, values), nil
+		}
+	}
+
+	if findOperatorPrecedenceForSymbol(stage.symbol) == comparatorPrecedence {
+		return nil, fmt.Errorf("Unable to write '%s' in an Elasticsearch query, which can only compare a field with a constant", symbolText(stage.symbol))
+	}
+	return nil, fmt.Errorf("Unable to write '%s' in an Elasticsearch query", symbolText(stage.symbol))
+}
+
+func (this elasticsearchWriter) makeValue(token ExpressionToken) (interface{}, error) {
+
+	switch token.Kind {
+	case STRING:
+		return token.Value.(string), nil
+	case BOOLEAN:
+		return token.Value.(bool), nil
+	case TIME:
+		return token.Value.(time.Time).Format(this.dateFormat), nil
+	case NUMERIC:
+
+		switch token.Value.(type) {
+		case Decimal:
+			return token.Value.(Decimal).Float64(), nil
+		}
+		return token.Value, nil
+	}
+
+	return nil, fmt.Errorf("Unable to write token '%v' of kind '%s' in an Elasticsearch query", token.Value, token.Kind)
+}
+
+/*
+	Returns a query of the given [kind] on a single field, such as `{"term": {"name": "bob"}}`.
+*/
+func makeElasticsearchQuery(kind string, field string, value interface{}) map[string]interface{} {
+	return map[string]interface{}{kind: map[string]interface{}{field: value}}
+}
+
+func makeElasticsearchBool(occurrence string, queries ...interface{}) map[string]interface{} {
+	return map[string]interface{}{"bool": map[string]interface{}{occurrence: queries}}
+}
+
+/*
+	Joins two queries in a bool query's [occurrence] (such as "must"), keeping it flat if either side is already
+	a bool query with only that occurrence.
+*/
+func combineElasticsearchQueries(occurrence string, left map[string]interface{}, right map[string]interface{}) map[string]interface{} {
+
+	var queries []interface{}
+
+	for _, query := range []map[string]interface{}{left, right} {
+
+		boolQuery, isBool := query["bool"].(map[string]interface{})
+		nested, isNested := boolQuery[occurrence].([]interface{})
+
+		// "should" queries have a minimum_should_match as well.
+		if isBool && isNested && len(query) == 1 && (len(boolQuery) == 1 || (occurrence == "should" && len(boolQuery) == 2)) {
+			queries = append(queries, nested...)
+		} else {
+			queries = append(queries, query)
+		}
+	}
+	return makeElasticsearchBool(occurrence, queries...)
+}
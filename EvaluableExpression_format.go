@@ -0,0 +1,196 @@
+package govaluate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/*
+	Returns this expression written out in a canonical form, regenerated from its AST. Expressions which mean the same thing
+	are written the same way, no matter how they were originally spaced, quoted, or parenthesized.
+
+	Operators are surrounded by single spaces, strings are in single quotes, and only the parenthesis that precedence requires are used.
+	Parsing the result (with the same functions) gives an equivalent expression.
+
+	Returns an error if the expression can't be written out, such as when it was made from tokens with functions that have no names.
+*/
+func (this EvaluableExpression) Formatted() (string, error) {
+
+	return FormatAST(this.AST())
+}
+
+/*
+	Writes out the given AST in the same canonical form as `EvaluableExpression.Formatted()`.
+	Returns an error if the tree can't be written as an expression.
+*/
+func FormatAST(node ASTNode) (string, error) {
+
+	var buffer bytes.Buffer
+	var text string
+	var err error
+
+	if node == nil {
+		return "", nil
+	}
+
+	writer := &astTokenWriter{formatting: true}
+
+	err = writer.writeNode(node)
+	if err != nil {
+		return "", err
+	}
+
+	for i, token := range writer.tokens {
+
+		text, err = formatToken(token, writer.sources[i])
+		if err != nil {
+			return "", err
+		}
+		buffer.WriteString(text)
+	}
+
+	return buffer.String(), nil
+}
+
+func formatToken(token ExpressionToken, source tokenSource) (string, error) {
+
+	switch token.Kind {
+
+	case COMPARATOR:
+		fallthrough
+	case LOGICALOP:
+		fallthrough
+	case MODIFIER:
+		fallthrough
+	case TERNARY:
+		return " " + token.Value.(string) + " ", nil
+
+	case PREFIX:
+		return token.Value.(string), nil
+	case SEPARATOR:
+		return ", ", nil
+	case CLAUSE:
+		return "(", nil
+	case CLAUSE_CLOSE:
+		return ")", nil
+
+	case NUMERIC:
+		return formatNumber(token.Value)
+	case STRING:
+		return quoteString(token.Value.(string)), nil
+	case PATTERN:
+		return quoteString(token.Value.(*regexp.Regexp).String()), nil
+	case TIME:
+		return quoteString(token.Value.(time.Time).Format(isoDateFormat)), nil
+	case DURATION:
+		return token.Value.(time.Duration).String(), nil
+	case BOOLEAN:
+		return strconv.FormatBool(token.Value.(bool)), nil
+
+	case VARIABLE:
+		return formatVariable(token.Value.(string)), nil
+
+	case ACCESSOR:
+
+		path := token.Value.([]string)
+		for _, name := range path {
+
+			if !isPlainName(name) {
+				return "", fmt.Errorf("Unable to format accessor '%s'", strings.Join(path, "."))
+			}
+		}
+		return strings.Join(path, "."), nil
+
+	case FUNCTION:
+		return source.name, nil
+	}
+
+	return "", fmt.Errorf("Unable to format token kind: '%s'", token.Kind.String())
+}
+
+func formatNumber(value interface{}) (string, error) {
+
+	switch value.(type) {
+	case Decimal:
+		return value.(Decimal).String(), nil
+	case int64:
+		return strconv.FormatInt(value.(int64), 10), nil
+	case float64:
+
+		number := value.(float64)
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return "", fmt.Errorf("Unable to format '%v' as a number", number)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	}
+
+	return "", errors.New("Unable to format a non-numeric value as a number")
+}
+
+/*
+	Quotes the given [text] as a string, escaping anything which would otherwise end it.
+*/
+func quoteString(text string) string {
+
+	var buffer bytes.Buffer
+
+	buffer.WriteRune('\'')
+	for _, character := range text {
+
+		if character == '\\' || !isNotQuote(character) {
+			buffer.WriteRune('\\')
+		}
+		buffer.WriteRune(character)
+	}
+	buffer.WriteRune('\'')
+
+	return buffer.String()
+}
+
+/*
+	Writes a parameter name as-is if it would be read back as the same parameter, otherwise in brackets.
+*/
+func formatVariable(name string) string {
+
+	var buffer bytes.Buffer
+
+	if isPlainName(name) && name != "true" && name != "false" && name != "in" && name != "IN" {
+		return name
+	}
+
+	buffer.WriteRune('[')
+	for _, character := range name {
+
+		if character == '\\' || character == ']' {
+			buffer.WriteRune('\\')
+		}
+		buffer.WriteRune(character)
+	}
+	buffer.WriteRune(']')
+
+	return buffer.String()
+}
+
+/*
+	Returns true if [name] would be read as a single parameter (or accessor field) name, without brackets.
+*/
+func isPlainName(name string) bool {
+
+	if name == "" || !unicode.IsLetter(getFirstRune(name)) {
+		return false
+	}
+
+	for _, character := range name {
+		if character == '.' || !isVariableName(character) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,283 @@
+package govaluate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+/*
+	The options used to parse expressions when they're unmarshalled, since unmarshalling gives no way to pass them in.
+	Changed with `RegisterFunction`, `RegisterContextFunction`, and `SetUnmarshalOptions`.
+*/
+var unmarshalOptions = ParsingOptions{
+	Functions:        map[string]ExpressionFunction{},
+	ContextFunctions: map[string]ContextExpressionFunction{},
+}
+var unmarshalOptionsLock sync.RWMutex
+
+/*
+	The form an expression is marshalled in when it has settings which its text can't hold,
+	such as a numeric mode other than FLOAT_NUMERICS, limits, or unchecked types.
+*/
+type marshalledExpression struct {
+	Expression       string
+	NumericMode      string           `json:",omitempty"`
+	DecimalPrecision int              `json:",omitempty"`
+	DecimalRounding  big.RoundingMode `json:",omitempty"`
+	QueryDateFormat  string           `json:",omitempty"`
+	ChecksTypes      bool
+	Options          EvaluationOptions
+}
+
+/*
+	Makes the given [function] available to expressions which are unmarshalled (from JSON, YAML, or anything else which
+	uses `encoding.TextUnmarshaler`), under the given [name]. Registering a name again replaces its function.
+	Expressions which are parsed with NewEvaluableExpression* are unaffected, and still use only the functions they're given.
+
+	The registry is shared by the whole process, so every package which unmarshals expressions sees the same functions.
+	To keep functions to one caller, unmarshal with `NewEvaluableExpressionFromText` instead.
+*/
+func RegisterFunction(name string, function ExpressionFunction) {
+
+	unmarshalOptionsLock.Lock()
+	defer unmarshalOptionsLock.Unlock()
+
+	unmarshalOptions.Functions[name] = function
+}
+
+/*
+	Same as `RegisterFunction`, except the function receives the context given to `EvalContext`.
+*/
+func RegisterContextFunction(name string, function ContextExpressionFunction) {
+
+	unmarshalOptionsLock.Lock()
+	defer unmarshalOptionsLock.Unlock()
+
+	unmarshalOptions.ContextFunctions[name] = function
+}
+
+/*
+	Replaces the options used to parse expressions which are unmarshalled, such as their numeric mode,
+	along with all of the functions which have been registered.
+	Expressions marshalled in a numeric mode other than FLOAT_NUMERICS keep their own mode, so this mode is only used for
+	expressions which are just text, such as those written by hand.
+*/
+func SetUnmarshalOptions(options ParsingOptions) {
+
+	unmarshalOptionsLock.Lock()
+	defer unmarshalOptionsLock.Unlock()
+
+	unmarshalOptions = copyParsingOptions(options)
+}
+
+/*
+	Parses an expression marshalled by `MarshalText` or `MarshalJSON`, using the functions in the given [options]
+	rather than those given to `RegisterFunction`.
+	Expressions marshalled with their settings are parsed in the numeric mode they were marshalled with,
+	otherwise the numeric mode of [options] is used.
+*/
+func NewEvaluableExpressionFromText(text []byte, options ParsingOptions) (*EvaluableExpression, error) {
+
+	if !isMarshalledObject(text) {
+		return NewEvaluableExpressionWithOptions(string(text), options)
+	}
+
+	var marshalled marshalledExpression
+
+	decoder := json.NewDecoder(bytes.NewReader(text))
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(&marshalled)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to unmarshal expression: %v", err)
+	}
+
+	options.NumericMode, err = parseNumericMode(marshalled.NumericMode)
+	if err != nil {
+		return nil, err
+	}
+	options.DecimalPrecision = marshalled.DecimalPrecision
+	options.DecimalRounding = marshalled.DecimalRounding
+
+	ret, err := NewEvaluableExpressionWithOptions(marshalled.Expression, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if marshalled.QueryDateFormat != "" {
+		ret.QueryDateFormat = marshalled.QueryDateFormat
+	}
+	ret.ChecksTypes = marshalled.ChecksTypes
+	ret.Options = marshalled.Options
+	return ret, nil
+}
+
+/*
+	Returns the text of this expression; either the text it was parsed from, or (for expressions made from tokens or ASTs)
+	its `Formatted()` text. Implements `encoding.TextMarshaler`, so that expressions can be kept in YAML, TOML, and similar.
+
+	If the expression has settings which its text can't hold (a numeric mode other than FLOAT_NUMERICS, a QueryDateFormat,
+	limits in its Options, or ChecksTypes turned off), it's instead marshalled as a JSON object of its text and those settings,
+	so that unmarshalling gives the same expression.
+*/
+func (this EvaluableExpression) MarshalText() ([]byte, error) {
+
+	text, err := this.text()
+	if err != nil {
+		return nil, err
+	}
+
+	if !this.hasMarshalledSettings() {
+		return []byte(text), nil
+	}
+
+	marshalled := marshalledExpression{
+		Expression:       text,
+		DecimalPrecision: this.parsingOptions.DecimalPrecision,
+		DecimalRounding:  this.parsingOptions.DecimalRounding,
+		ChecksTypes:      this.ChecksTypes,
+		Options:          this.Options,
+	}
+
+	if this.parsingOptions.NumericMode != FLOAT_NUMERICS {
+		marshalled.NumericMode = this.parsingOptions.NumericMode.String()
+	}
+	if this.QueryDateFormat != isoDateFormat {
+		marshalled.QueryDateFormat = this.QueryDateFormat
+	}
+	return json.Marshal(marshalled)
+}
+
+/*
+	Parses the given [text] into this expression, using the functions given to `RegisterFunction`.
+	Text marshalled with its settings is parsed in its own numeric mode, and any other text in the mode given to `SetUnmarshalOptions`.
+	Implements `encoding.TextUnmarshaler`.
+*/
+func (this *EvaluableExpression) UnmarshalText(text []byte) error {
+
+	// the expression keeps its options, so it gets its own copy of the functions, which later registrations can't change.
+	unmarshalOptionsLock.RLock()
+	options := copyParsingOptions(unmarshalOptions)
+	unmarshalOptionsLock.RUnlock()
+
+	expression, err := NewEvaluableExpressionFromText(text, options)
+	if err != nil {
+		return err
+	}
+
+	*this = *expression
+	return nil
+}
+
+/*
+	Returns this expression as a JSON string of its text or, if it has settings, as a JSON object. See `MarshalText`.
+*/
+func (this EvaluableExpression) MarshalJSON() ([]byte, error) {
+
+	text, err := this.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+
+	if this.hasMarshalledSettings() {
+		return text, nil
+	}
+	return json.Marshal(string(text))
+}
+
+/*
+	Parses this expression from a JSON string or object, see `UnmarshalText`. A JSON null leaves the expression unchanged.
+*/
+func (this *EvaluableExpression) UnmarshalJSON(data []byte) error {
+
+	var text string
+
+	if string(data) == "null" {
+		return nil
+	}
+
+	if isMarshalledObject(data) {
+		return this.UnmarshalText(data)
+	}
+
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return err
+	}
+	return this.UnmarshalText([]byte(text))
+}
+
+/*
+	Returns the text this expression was parsed from, or its formatted text if it wasn't parsed from any.
+*/
+func (this EvaluableExpression) text() (string, error) {
+
+	if this.inputExpression != "" {
+		return this.inputExpression, nil
+	}
+	return this.Formatted()
+}
+
+/*
+	Returns true if this expression has any settings which can't be kept in its text.
+*/
+func (this EvaluableExpression) hasMarshalledSettings() bool {
+
+	return this.parsingOptions.NumericMode != FLOAT_NUMERICS ||
+		this.parsingOptions.DecimalPrecision != 0 ||
+		this.parsingOptions.DecimalRounding != big.ToNearestEven ||
+		this.QueryDateFormat != isoDateFormat ||
+		!this.ChecksTypes ||
+		this.Options != EvaluationOptions{}
+}
+
+/*
+	Returns true if the given marshalled [text] is an object of an expression and its settings, rather than just its text.
+	No expression can start with a brace, so the two can't be confused.
+*/
+func isMarshalledObject(text []byte) bool {
+
+	text = bytes.TrimSpace(text)
+	return len(text) > 0 && text[0] == '{'
+}
+
+/*
+	Returns the numeric mode with the given [name], as given by `NumericMode.String()`. An empty name is FLOAT_NUMERICS.
+*/
+func parseNumericMode(name string) (NumericMode, error) {
+
+	switch name {
+	case "":
+		fallthrough
+	case "FLOAT_NUMERICS":
+		return FLOAT_NUMERICS, nil
+	case "INTEGER_NUMERICS":
+		return INTEGER_NUMERICS, nil
+	case "DECIMAL_NUMERICS":
+		return DECIMAL_NUMERICS, nil
+	}
+	return FLOAT_NUMERICS, fmt.Errorf("Unable to unmarshal expression with unknown numeric mode '%s'", name)
+}
+
+/*
+	Returns a copy of [options] with its own maps of functions, which are never nil.
+*/
+func copyParsingOptions(options ParsingOptions) ParsingOptions {
+
+	functions := make(map[string]ExpressionFunction, len(options.Functions))
+	for name, function := range options.Functions {
+		functions[name] = function
+	}
+
+	contextFunctions := make(map[string]ContextExpressionFunction, len(options.ContextFunctions))
+	for name, function := range options.ContextFunctions {
+		contextFunctions[name] = function
+	}
+
+	options.Functions = functions
+	options.ContextFunctions = contextFunctions
+	return options
+}
@@ -0,0 +1,422 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+/*
+	Returns this expression as a MongoDB query filter, such as can be given to `collection.Find()`.
+	The filter is made of plain maps and slices, which any bson encoder accepts as a document.
+
+	Comparisons between a parameter and a constant are written as query operators, so `age > 18 && name =~ '^a'` becomes
+	`{"$and": [{"age": {"$gt": 18}}, {"name": {"$regex": "^a"}}]}`, which can use indexes.
+	Anything else, such as arithmetic or a comparison between two parameters, is written as an aggregation expression with `$expr`.
+	Parameters are fields of the same name, and accessors (such as `user.Address.City`) are paths into embedded documents.
+
+	Times are given as `time.Time`, and exact decimals (see DECIMAL_NUMERICS) as the closest float64.
+	Returns an error if the expression uses something which can't be written for Mongo, such as functions, durations, or bit shifts.
+*/
+func (this EvaluableExpression) ToMongoQuery() (map[string]interface{}, error) {
+
+	stage, err := planStageTree(this.tokens, this.sources)
+	if err != nil {
+		return nil, err
+	}
+
+	// an empty expression filters nothing.
+	if stage == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	return writeMongoFilter(stage)
+}
+
+/*
+	Writes the given stage as a query filter document, using query operators wherever possible.
+*/
+func writeMongoFilter(stage *evaluationStage) (map[string]interface{}, error) {
+
+	var field string
+	var value interface{}
+	var isField, isValue bool
+
+	switch stage.symbol {
+
+	case NOOP:
+
+		if stage.rightStage != nil && stage.rightStage.symbol != SEPARATE {
+			return writeMongoFilter(stage.rightStage)
+		}
+
+	case AND:
+		fallthrough
+	case OR:
+
+		left, err := writeMongoFilter(stage.leftStage)
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := writeMongoFilter(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+
+		operator := "$and"
+		if stage.symbol == OR {
+			operator = "$or"
+		}
+		return combineMongoFilters(operator, left, right), nil
+
+	case INVERT:
+
+		inner, err := writeMongoFilter(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+
+		// a single condition on a field can be negated with $not, anything else has to be in $nor.
+		if len(inner) == 1 {
+			for key, condition := range inner {
+
+				_, isOperator := condition.(map[string]interface{})
+				if isOperator && !strings.HasPrefix(key, "$") {
+					return map[string]interface{}{key: map[string]interface{}{"$not": condition}}, nil
+				}
+			}
+		}
+		return map[string]interface{}{"$nor": []interface{}{inner}}, nil
+
+	case VALUE:
+		fallthrough
+	case ACCESS:
+
+		// a parameter on its own is a boolean field.
+		field, isField = findFieldPath(stage)
+		if isField {
+			return map[string]interface{}{field: map[string]interface{}{"$eq": true}}, nil
+		}
+
+	case EQ:
+		fallthrough
+	case NEQ:
+		fallthrough
+	case GT:
+		fallthrough
+	case LT:
+		fallthrough
+	case GTE:
+		fallthrough
+	case LTE:
+
+		symbol := stage.symbol
+
+		field, isField = findFieldPath(stage.leftStage)
+		value, isValue = findConstant(stage.rightStage, makeMongoValue)
+
+		// constants on the left are compared the other way around, so that `1 < foo` is `foo > 1`.
+		if !isField || !isValue {
+
+			field, isField = findFieldPath(stage.rightStage)
+			value, isValue = findConstant(stage.leftStage, makeMongoValue)
+			symbol = flipComparator(symbol)
+		}
+
+		if isField && isValue {
+			return map[string]interface{}{field: map[string]interface{}{findMongoOperator(symbol): value}}, nil
+		}
+
+	case REQ:
+		fallthrough
+	case NREQ:
+
+		field, isField = findFieldPath(stage.leftStage)
+		pattern, isPattern := findConstantPattern(stage.rightStage)
+
+		if isField && isPattern {
+
+			var condition interface{} = map[string]interface{}{"$regex": pattern}
+			if stage.symbol == NREQ {
+				condition = map[string]interface{}{"$not": condition}
+			}
+			return map[string]interface{}{field: condition}, nil
+		}
+
+	case IN:
+
+		field, isField = findFieldPath(stage.leftStage)
+		values, isList := findConstantList(stage.rightStage, makeMongoValue)
+
+		if isField && isList {
+			return map[string]interface{}{field: map[string]interface{}{"$in": values}}, nil
+		}
+	}
+
+	// anything which can't be a query operator is an aggregation expression.
+	expression, err := writeMongoExpression(stage)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"$expr": expression}, nil
+}
+
+/*
+	Writes the given stage as an aggregation expression, as used in $expr.
+*/
+func writeMongoExpression(stage *evaluationStage) (interface{}, error) {
+
+	var left, right interface{}
+	var err error
+
+	switch stage.symbol {
+
+	case LITERAL:
+
+		value, err := makeMongoValue(stage.token)
+		if err != nil {
+			return nil, err
+		}
+
+		// strings which start with "$" would otherwise be read as field paths.
+		text, isString := value.(string)
+		if isString && strings.HasPrefix(text, "$") {
+			return map[string]interface{}{"$literal": text}, nil
+		}
+		return value, nil
+
+	case VALUE:
+		fallthrough
+	case ACCESS:
+
+		field, isField := findFieldPath(stage)
+		if !isField {
+			return nil, fmt.Errorf("Unable to write method call '%s' in a Mongo query", strings.Join(stage.token.Value.([]string), "."))
+		}
+		return "$" + field, nil
+
+	case NOOP:
+
+		if stage.rightStage == nil {
+			return []interface{}{}, nil
+		}
+
+		if stage.rightStage.symbol == SEPARATE {
+			return writeMongoExpressions(findListElements(stage.rightStage))
+		}
+		return writeMongoExpression(stage.rightStage)
+
+	case NEGATE:
+
+		value, isValue := findConstant(stage, makeMongoValue)
+		if isValue {
+			return value, nil
+		}
+
+		right, err = writeMongoExpression(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$multiply": []interface{}{-1, right}}, nil
+
+	case INVERT:
+		fallthrough
+	case BITWISE_NOT:
+
+		right, err = writeMongoExpression(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+
+		if stage.symbol == INVERT {
+			return map[string]interface{}{"$not": []interface{}{right}}, nil
+		}
+		return map[string]interface{}{"$bitNot": right}, nil
+
+	case TERNARY_TRUE:
+		fallthrough
+	case TERNARY_FALSE:
+		return writeMongoTernary(stage)
+
+	case FUNCTIONAL:
+		return nil, fmt.Errorf("Unable to write function '%s' in a Mongo query", stage.source.name)
+
+	case SEPARATE:
+		return nil, errors.New("Unable to write a list outside of parenthesis in a Mongo query")
+
+	case BITWISE_LSHIFT:
+		fallthrough
+	case BITWISE_RSHIFT:
+		return nil, errors.New("Bit shifts can't be written in a Mongo query")
+	}
+
+	// everything else has a left and right side.
+	left, err = writeMongoExpression(stage.leftStage)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err = writeMongoExpression(stage.rightStage)
+	if err != nil {
+		return nil, err
+	}
+
+	switch stage.symbol {
+
+	case REQ:
+		fallthrough
+	case NREQ:
+
+		match := map[string]interface{}{"$regexMatch": map[string]interface{}{"input": left, "regex": right}}
+		if stage.symbol == NREQ {
+			return map[string]interface{}{"$not": []interface{}{match}}, nil
+		}
+		return match, nil
+
+	case PLUS:
+
+		if isStringStage(stage.leftStage) || isStringStage(stage.rightStage) {
+			return map[string]interface{}{"$concat": []interface{}{left, right}}, nil
+		}
+
+	case COALESCE:
+		return map[string]interface{}{"$ifNull": []interface{}{left, right}}, nil
+	}
+
+	return map[string]interface{}{findMongoOperator(stage.symbol): []interface{}{left, right}}, nil
+}
+
+func writeMongoExpressions(stages []*evaluationStage) ([]interface{}, error) {
+
+	var ret []interface{}
+
+	for _, stage := range stages {
+
+		value, err := writeMongoExpression(stage)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, value)
+	}
+	return ret, nil
+}
+
+/*
+	Writes a ternary as $cond. A ternary without an else (`foo ? 1`) gives null when its condition is false.
+*/
+func writeMongoTernary(stage *evaluationStage) (interface{}, error) {
+
+	var otherwise interface{}
+	var err error
+
+	ifStage := stage
+	if stage.symbol == TERNARY_FALSE {
+
+		ifStage = stage.leftStage
+		if ifStage == nil || ifStage.symbol != TERNARY_TRUE {
+			return nil, errors.New("Unable to write ':' without a matching '?' in a Mongo query")
+		}
+
+		otherwise, err = writeMongoExpression(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	arguments, err := writeMongoExpressions([]*evaluationStage{ifStage.leftStage, ifStage.rightStage})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"$cond": append(arguments, otherwise)}, nil
+}
+
+/*
+	Joins two filters with $and or $or, keeping them flat if either side already uses the same operator.
+*/
+func combineMongoFilters(operator string, left map[string]interface{}, right map[string]interface{}) map[string]interface{} {
+
+	var filters []interface{}
+
+	for _, filter := range []map[string]interface{}{left, right} {
+
+		nested, isNested := filter[operator].([]interface{})
+		if isNested && len(filter) == 1 {
+			filters = append(filters, nested...)
+		} else {
+			filters = append(filters, filter)
+		}
+	}
+	return map[string]interface{}{operator: filters}
+}
+
+func makeMongoValue(token ExpressionToken) (interface{}, error) {
+
+	switch token.Kind {
+	case STRING:
+		return token.Value.(string), nil
+	case PATTERN:
+		return token.Value.(*regexp.Regexp).String(), nil
+	case BOOLEAN:
+		return token.Value.(bool), nil
+	case TIME:
+		return token.Value.(time.Time), nil
+	case NUMERIC:
+
+		switch token.Value.(type) {
+		case Decimal:
+			return token.Value.(Decimal).Float64(), nil
+		}
+		return token.Value, nil
+
+	case DURATION:
+		return nil, errors.New("Durations can't be written in a Mongo query")
+	}
+
+	return nil, fmt.Errorf("Unable to write token '%v' of kind '%s' in a Mongo query", token.Value, token.Kind)
+}
+
+func findMongoOperator(symbol OperatorSymbol) string {
+
+	switch symbol {
+	case EQ:
+		return "$eq"
+	case NEQ:
+		return "$ne"
+	case GT:
+		return "$gt"
+	case LT:
+		return "$lt"
+	case GTE:
+		return "$gte"
+	case LTE:
+		return "$lte"
+	case IN:
+		return "$in"
+	case AND:
+		return "$and"
+	case OR:
+		return "$or"
+	case PLUS:
+		return "$add"
+	case MINUS:
+		return "$subtract"
+	case MULTIPLY:
+		return "$multiply"
+	case DIVIDE:
+		return "$divide"
+	case MODULUS:
+		return "$mod"
+	case EXPONENT:
+		return "$pow"
+	case BITWISE_AND:
+		return "$bitAnd"
+	case BITWISE_OR:
+		return "$bitOr"
+	case BITWISE_XOR:
+		return "$bitXor"
+	}
+	return ""
+}
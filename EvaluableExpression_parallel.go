@@ -0,0 +1,247 @@
+package govaluate
+
+import (
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+/*
+	The error given to rows which weren't evaluated, because an earlier row failed and ParallelOptions.StopOnError was set.
+*/
+var EVALUATION_STOPPED = errors.New("Evaluation stopped because an earlier row failed")
+
+/*
+	Controls how `EvalParallel` and `EvalStream` spread evaluations across goroutines.
+*/
+type ParallelOptions struct {
+
+	/*
+		The number of goroutines which evaluate rows. Defaults to GOMAXPROCS when zero.
+	*/
+	Workers int
+
+	/*
+		Whether to stop evaluating rows as soon as one of them fails.
+	*/
+	StopOnError bool
+}
+
+/*
+	The result of evaluating one row given to `EvalStream`.
+*/
+type EvaluationResult struct {
+
+	// the position of the row, counting from zero, in the order the rows were given.
+	Index int
+
+	Value interface{}
+	Error error
+}
+
+func (this ParallelOptions) workers() int {
+
+	if this.Workers > 0 {
+		return this.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+/*
+	Evaluates this expression for each of the given [rows] of parameters, using several goroutines at once.
+	Returns the result and error of each row in the same order as the rows, the same as `EvalBatch`.
+
+	If [options] has StopOnError, rows after one which fails aren't evaluated if they haven't started yet, and have the error EVALUATION_STOPPED.
+	Every row before a failed one is still evaluated, so the first error (in the order of the rows) is never EVALUATION_STOPPED.
+*/
+func (this EvaluableExpression) EvalParallel(ctx context.Context, rows []Parameters, options ParallelOptions) ([]interface{}, []error) {
+
+	var group sync.WaitGroup
+	var next int64 = -1
+
+	// the lowest index of a row which has failed, once StopOnError applies.
+	var stopAt int64 = math.MaxInt64
+
+	results := make([]interface{}, len(rows))
+	failures := make([]error, len(rows))
+
+	workers := minInt(options.workers(), len(rows))
+	group.Add(workers)
+
+	for i := 0; i < workers; i++ {
+
+		go func() {
+
+			defer group.Done()
+
+			// rows are taken in order, so every row before one which fails has already been started.
+			for {
+
+				index := int(atomic.AddInt64(&next, 1))
+				if index >= len(rows) {
+					return
+				}
+
+				// only rows after a failure are stopped; rows before it are still evaluated, even if they're claimed after it failed.
+				if int64(index) > atomic.LoadInt64(&stopAt) {
+					failures[index] = EVALUATION_STOPPED
+					continue
+				}
+
+				results[index], failures[index] = this.EvalContext(ctx, rows[index])
+
+				if failures[index] != nil && options.StopOnError {
+					lowerInt64(&stopAt, int64(index))
+				}
+			}
+		}()
+	}
+
+	group.Wait()
+	return results, failures
+}
+
+/*
+	Evaluates this expression for each row received from [rows], using several goroutines at once.
+	Results are sent on the returned channel in the same order as the rows were received, and it's closed once [rows] is closed and every row has a result.
+
+	If [options] has StopOnError, the channel is closed right after the first result which has an error.
+	If [ctx] is done, evaluation stops, and the channel is closed without waiting for the results of the remaining rows.
+	In either case, no more rows are received.
+
+	The caller must receive every result until the channel is closed, or cancel [ctx].
+*/
+func (this EvaluableExpression) EvalStream(ctx context.Context, rows <-chan Parameters, options ParallelOptions) <-chan EvaluationResult {
+
+	var group sync.WaitGroup
+
+	workers := options.workers()
+	ctx, cancel := context.WithCancel(ctx)
+
+	jobs := make(chan indexedParameters)
+	finished := make(chan EvaluationResult, workers)
+	results := make(chan EvaluationResult)
+
+	// limits how many rows can be waiting on an earlier, slower, row to finish.
+	window := make(chan struct{}, workers*2)
+
+	// sends rows to the workers, with their index.
+	go func() {
+
+		defer close(jobs)
+
+		for index := 0; ; index++ {
+
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case parameters, open := <-rows:
+
+				if !open {
+					return
+				}
+
+				select {
+				case jobs <- indexedParameters{index, parameters}:
+				case <-ctx.Done():
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	group.Add(workers)
+	for i := 0; i < workers; i++ {
+
+		go func() {
+
+			defer group.Done()
+
+			for job := range jobs {
+
+				value, err := this.EvalContext(ctx, job.parameters)
+				finished <- EvaluationResult{job.index, value, err}
+			}
+		}()
+	}
+
+	go func() {
+		group.Wait()
+		close(finished)
+	}()
+
+	// puts results back in order.
+	go func() {
+
+		defer close(results)
+		defer cancel()
+
+		pending := make(map[int]EvaluationResult)
+		next := 0
+		sending := true
+
+		for result := range finished {
+
+			// once sending has stopped, results are only received so that the workers can finish.
+			if !sending {
+				continue
+			}
+
+			pending[result.Index] = result
+
+			for sending {
+
+				ready, found := pending[next]
+				if !found {
+					break
+				}
+
+				delete(pending, next)
+				next++
+				<-window
+
+				select {
+				case results <- ready:
+				case <-ctx.Done():
+					sending = false
+					continue
+				}
+
+				if ready.Error != nil && options.StopOnError {
+					sending = false
+					cancel()
+				}
+			}
+		}
+	}()
+
+	return results
+}
+
+/*
+	Atomically sets [target] to [value], if [value] is lower.
+*/
+func lowerInt64(target *int64, value int64) {
+
+	for {
+		current := atomic.LoadInt64(target)
+		if value >= current || atomic.CompareAndSwapInt64(target, current, value) {
+			return
+		}
+	}
+}
+
+type indexedParameters struct {
+	index      int
+	parameters Parameters
+}
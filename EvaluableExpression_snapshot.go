@@ -0,0 +1,640 @@
+package govaluate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"time"
+)
+
+// the first bytes of every snapshot, the last of which is the version of its format.
+var snapshotHeader = []byte{'g', 'v', 's', 2}
+
+/*
+	The kinds of value which can be kept in a snapshot, each written as a single byte before the value itself.
+*/
+const (
+	snapshotNil byte = iota
+	snapshotBool
+	snapshotFloat
+	snapshotInt
+	snapshotDecimal
+	snapshotString
+	snapshotTime
+	snapshotDuration
+	snapshotPattern
+	snapshotPath
+	snapshotRune
+	snapshotFunction
+)
+
+/*
+	Flags which say what each stage of a snapshot has.
+*/
+const (
+	snapshotHasLeft byte = 1 << iota
+	snapshotHasRight
+	snapshotHasToken
+	snapshotHasClosingToken
+)
+
+// the deepest a snapshot's stages can be nested, so that loading a corrupt (or malicious) snapshot can't exhaust the stack.
+const maxSnapshotDepth = 1 << 16
+
+/*
+	Returns a compact binary snapshot of this expression, which `NewEvaluableExpressionFromSnapshot` can load
+	much faster than parsing the expression again.
+
+	The snapshot holds the expression's planned stages as they are, including precomputed constants and the sources
+	of its regexes, along with its tokens and options. Functions are kept only by name, so they need to be given again when it's loaded.
+
+	Returns an error if the expression has something that can't be kept, such as a function without a name
+	(from `NewEvaluableExpressionFromTokens`), or a constant of a type other than those of literals.
+*/
+func (this EvaluableExpression) Snapshot() ([]byte, error) {
+
+	writer := new(snapshotWriter)
+	writer.buffer.Write(snapshotHeader)
+
+	writer.writeUvarint(uint64(this.parsingOptions.NumericMode))
+	writer.writeVarint(int64(this.parsingOptions.DecimalPrecision))
+	writer.writeUvarint(uint64(this.parsingOptions.DecimalRounding))
+
+	writer.writeString(this.QueryDateFormat)
+	writer.writeBool(this.ChecksTypes)
+	writer.writeVarint(int64(this.Options.MaxSteps))
+	writer.writeVarint(int64(this.Options.MaxStringLength))
+	writer.writeVarint(int64(this.Options.MaxArrayLength))
+
+	writer.writeString(this.inputExpression)
+
+	writer.writeUvarint(uint64(len(this.tokens)))
+	for i, token := range this.tokens {
+
+		err := writer.writeToken(token, this.sources[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	writer.writeBool(this.evaluationStages != nil)
+	if this.evaluationStages != nil {
+
+		err := writer.writeStage(this.evaluationStages, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return writer.buffer.Bytes(), nil
+}
+
+/*
+	Loads an expression from a snapshot made by `Snapshot()`.
+	Functions are found by name in the Functions and ContextFunctions of the given [options], and it's an error if any are missing.
+	The other options (such as the numeric mode) are ignored, since they're part of the snapshot.
+*/
+func NewEvaluableExpressionFromSnapshot(snapshot []byte, options ParsingOptions) (*EvaluableExpression, error) {
+
+	if len(snapshot) < len(snapshotHeader) || !bytes.HasPrefix(snapshot, snapshotHeader[:3]) {
+		return nil, errors.New("Not an expression snapshot")
+	}
+
+	if snapshot[3] != snapshotHeader[3] {
+		return nil, fmt.Errorf("Unable to load expression snapshot of version %d, only version %d is supported", snapshot[3], snapshotHeader[3])
+	}
+
+	reader := &snapshotReader{data: snapshot[len(snapshotHeader):], options: options}
+	ret := new(EvaluableExpression)
+
+	ret.parsingOptions = options
+	ret.parsingOptions.NumericMode = NumericMode(reader.readUvarint())
+	ret.parsingOptions.DecimalPrecision = int(reader.readVarint())
+	ret.parsingOptions.DecimalRounding = big.RoundingMode(reader.readUvarint())
+
+	ret.QueryDateFormat = reader.readString()
+	ret.ChecksTypes = reader.readBool()
+	ret.Options.MaxSteps = int(reader.readVarint())
+	ret.Options.MaxStringLength = int(reader.readVarint())
+	ret.Options.MaxArrayLength = int(reader.readVarint())
+
+	ret.inputExpression = reader.readString()
+
+	count := reader.readUvarint()
+	for i := uint64(0); i < count && reader.err == nil; i++ {
+
+		token, source := reader.readToken()
+		ret.tokens = append(ret.tokens, token)
+		ret.sources = append(ret.sources, source)
+	}
+
+	if reader.readBool() {
+		ret.evaluationStages = reader.readStage(ret.tokens, ret.sources, 0)
+	}
+
+	if reader.err != nil {
+		return nil, reader.err
+	}
+
+	if len(reader.data) > 0 {
+		return nil, errors.New("Unexpected data at the end of expression snapshot")
+	}
+
+	if ret.parsingOptions.NumericMode == DECIMAL_NUMERICS {
+		planDecimalDivision(ret.evaluationStages, ret.parsingOptions.decimalPrecision(), ret.parsingOptions.DecimalRounding)
+	}
+
+	return ret, nil
+}
+
+type snapshotWriter struct {
+	buffer  bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (this *snapshotWriter) writeUvarint(value uint64) {
+
+	length := binary.PutUvarint(this.scratch[:], value)
+	this.buffer.Write(this.scratch[:length])
+}
+
+func (this *snapshotWriter) writeVarint(value int64) {
+
+	length := binary.PutVarint(this.scratch[:], value)
+	this.buffer.Write(this.scratch[:length])
+}
+
+func (this *snapshotWriter) writeString(value string) {
+
+	this.writeUvarint(uint64(len(value)))
+	this.buffer.WriteString(value)
+}
+
+func (this *snapshotWriter) writeBool(value bool) {
+
+	if value {
+		this.buffer.WriteByte(1)
+	} else {
+		this.buffer.WriteByte(0)
+	}
+}
+
+func (this *snapshotWriter) writePosition(position Position) {
+
+	this.writeUvarint(uint64(position.Offset))
+	this.writeUvarint(uint64(position.Line))
+	this.writeUvarint(uint64(position.Column))
+}
+
+func (this *snapshotWriter) writeToken(token ExpressionToken, source tokenSource) error {
+
+	if token.Kind == FUNCTION && source.name == "" {
+		return errors.New("Unable to snapshot a function without a name")
+	}
+
+	this.writeUvarint(uint64(token.Kind))
+	this.writePosition(source.start)
+	this.writePosition(source.end)
+	this.writeString(source.name)
+
+	return this.writeValue(token.Value)
+}
+
+/*
+	Writes a stage at the given [depth], followed by its left and right stages (if it has them).
+	Its tokens are written as their index in the expression's tokens, which were already written.
+*/
+func (this *snapshotWriter) writeStage(stage *evaluationStage, depth int) error {
+
+	var flags byte
+	var err error
+
+	if depth >= maxSnapshotDepth {
+		return fmt.Errorf("Unable to snapshot an expression nested more than %d stages deep", maxSnapshotDepth)
+	}
+
+	if stage.leftStage != nil {
+		flags |= snapshotHasLeft
+	}
+	if stage.rightStage != nil {
+		flags |= snapshotHasRight
+	}
+
+	// stages which were computed ahead of time have no tokens.
+	if stage.token.Kind != UNKNOWN {
+		flags |= snapshotHasToken
+	}
+	if stage.closingToken.Kind != UNKNOWN {
+		flags |= snapshotHasClosingToken
+	}
+
+	this.buffer.WriteByte(flags)
+	this.writeUvarint(uint64(stage.symbol))
+
+	if flags&snapshotHasToken != 0 {
+		this.writeUvarint(uint64(stage.tokenIndex))
+	}
+	if flags&snapshotHasClosingToken != 0 {
+		this.writeUvarint(uint64(stage.closingIndex))
+	}
+
+	// literals which were computed ahead of time have no token to hold their value.
+	if stage.symbol == LITERAL && flags&snapshotHasToken == 0 {
+
+		value, err := stage.operator(nil, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		err = this.writeValue(value)
+		if err != nil {
+			return err
+		}
+	}
+
+	if stage.leftStage != nil {
+
+		err = this.writeStage(stage.leftStage, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	if stage.rightStage != nil {
+		return this.writeStage(stage.rightStage, depth+1)
+	}
+	return nil
+}
+
+func (this *snapshotWriter) writeValue(value interface{}) error {
+
+	switch value.(type) {
+
+	case nil:
+		this.buffer.WriteByte(snapshotNil)
+
+	case bool:
+		this.buffer.WriteByte(snapshotBool)
+		this.writeBool(value.(bool))
+
+	case float64:
+		this.buffer.WriteByte(snapshotFloat)
+		binary.BigEndian.PutUint64(this.scratch[:8], math.Float64bits(value.(float64)))
+		this.buffer.Write(this.scratch[:8])
+
+	case int64:
+		this.buffer.WriteByte(snapshotInt)
+		this.writeVarint(value.(int64))
+
+	case Decimal:
+		this.buffer.WriteByte(snapshotDecimal)
+		this.writeString(value.(Decimal).String())
+
+	case string:
+		this.buffer.WriteByte(snapshotString)
+		this.writeString(value.(string))
+
+	case time.Time:
+
+		data, err := value.(time.Time).MarshalBinary()
+		if err != nil {
+			return err
+		}
+
+		this.buffer.WriteByte(snapshotTime)
+		this.writeString(string(data))
+
+	case time.Duration:
+		this.buffer.WriteByte(snapshotDuration)
+		this.writeVarint(int64(value.(time.Duration)))
+
+	case *regexp.Regexp:
+		this.buffer.WriteByte(snapshotPattern)
+		this.writeString(value.(*regexp.Regexp).String())
+
+	case []string:
+
+		this.buffer.WriteByte(snapshotPath)
+		this.writeUvarint(uint64(len(value.([]string))))
+		for _, name := range value.([]string) {
+			this.writeString(name)
+		}
+
+	case rune:
+		this.buffer.WriteByte(snapshotRune)
+		this.writeVarint(int64(value.(rune)))
+
+	// functions are found by the name of their token when loaded.
+	case ExpressionFunction:
+		this.buffer.WriteByte(snapshotFunction)
+	case ContextExpressionFunction:
+		this.buffer.WriteByte(snapshotFunction)
+
+	default:
+		return fmt.Errorf("Unable to snapshot a value of type %T", value)
+	}
+
+	return nil
+}
+
+/*
+	Reads the parts of a snapshot in order. The first error stops all reading, and is kept in [err].
+*/
+type snapshotReader struct {
+	data    []byte
+	options ParsingOptions
+	err     error
+}
+
+func (this *snapshotReader) fail(err error) {
+
+	if this.err == nil {
+		this.err = err
+	}
+	this.data = nil
+}
+
+func (this *snapshotReader) readByte() byte {
+
+	if len(this.data) == 0 {
+		this.fail(errors.New("Unexpected end of expression snapshot"))
+		return 0
+	}
+
+	ret := this.data[0]
+	this.data = this.data[1:]
+	return ret
+}
+
+func (this *snapshotReader) readUvarint() uint64 {
+
+	ret, length := binary.Uvarint(this.data)
+	if length <= 0 {
+		this.fail(errors.New("Unexpected end of expression snapshot"))
+		return 0
+	}
+
+	this.data = this.data[length:]
+	return ret
+}
+
+func (this *snapshotReader) readVarint() int64 {
+
+	ret, length := binary.Varint(this.data)
+	if length <= 0 {
+		this.fail(errors.New("Unexpected end of expression snapshot"))
+		return 0
+	}
+
+	this.data = this.data[length:]
+	return ret
+}
+
+func (this *snapshotReader) readString() string {
+
+	length := this.readUvarint()
+	if length > uint64(len(this.data)) {
+		this.fail(errors.New("Unexpected end of expression snapshot"))
+		return ""
+	}
+
+	ret := string(this.data[:length])
+	this.data = this.data[length:]
+	return ret
+}
+
+func (this *snapshotReader) readBool() bool {
+	return this.readByte() != 0
+}
+
+func (this *snapshotReader) readPosition() Position {
+
+	return Position{
+		Offset: int(this.readUvarint()),
+		Line:   int(this.readUvarint()),
+		Column: int(this.readUvarint()),
+	}
+}
+
+func (this *snapshotReader) readToken() (ExpressionToken, tokenSource) {
+
+	var ret ExpressionToken
+	var source tokenSource
+
+	ret.Kind = TokenKind(this.readUvarint())
+	source.start = this.readPosition()
+	source.end = this.readPosition()
+	source.name = this.readString()
+	ret.Value = this.readValue()
+
+	if ret.Kind != FUNCTION || this.err != nil {
+		return ret, source
+	}
+
+	// the same order as the parser, which prefers context functions.
+	contextFunction, found := this.options.ContextFunctions[source.name]
+	if found {
+		ret.Value = contextFunction
+		return ret, source
+	}
+
+	function, found := this.options.Functions[source.name]
+	if found {
+		ret.Value = function
+		return ret, source
+	}
+
+	this.fail(fmt.Errorf("Expression snapshot calls function '%s', which wasn't given", source.name))
+	return ret, source
+}
+
+/*
+	Reads a stage at the given [depth], along with its left and right stages.
+	Its tokens are found by their index in the given [tokens] and [sources].
+*/
+func (this *snapshotReader) readStage(tokens []ExpressionToken, sources []tokenSource, depth int) *evaluationStage {
+
+	if depth >= maxSnapshotDepth {
+		this.fail(fmt.Errorf("Expression snapshot is nested more than %d stages deep", maxSnapshotDepth))
+		return nil
+	}
+
+	ret := new(evaluationStage)
+
+	flags := this.readByte()
+	ret.symbol = OperatorSymbol(this.readUvarint())
+
+	if flags&snapshotHasToken != 0 {
+		ret.token, ret.source, ret.tokenIndex = this.readTokenIndex(tokens, sources)
+	}
+	if flags&snapshotHasClosingToken != 0 {
+		ret.closingToken, ret.closingSource, ret.closingIndex = this.readTokenIndex(tokens, sources)
+	}
+	if this.err != nil {
+		return nil
+	}
+
+	switch ret.symbol {
+
+	case LITERAL:
+
+		if flags&snapshotHasToken != 0 {
+			ret.operator = makeLiteralStage(ret.token.Value)
+		} else {
+			ret.operator = makeLiteralStage(this.readValue())
+		}
+
+	case VALUE:
+
+		name, isName := ret.token.Value.(string)
+		if !isName {
+			this.fail(errors.New("Expression snapshot has a parameter without a name"))
+			return nil
+		}
+		ret.operator = makeParameterStage(name)
+
+	case NOOP:
+		ret.operator = noopStageRight
+
+	// the rest are made by the planner, the same as when they were first planned.
+	case FUNCTIONAL:
+
+		stage, found := makeFunctionCallStage(ret.token)
+		if !found {
+			this.fail(errors.New("Expression snapshot has a function stage without a function"))
+			return nil
+		}
+		stage.source = ret.source
+		stage.tokenIndex = ret.tokenIndex
+		ret = stage
+
+	case ACCESS:
+
+		stage, found := makeAccessStage(ret.token)
+		if !found {
+			this.fail(errors.New("Expression snapshot has an accessor without a path"))
+			return nil
+		}
+		stage.source = ret.source
+		stage.tokenIndex = ret.tokenIndex
+		ret = stage
+
+	default:
+
+		stage, found := makeOperatorStage(ret.symbol)
+		if !found {
+			this.fail(fmt.Errorf("Expression snapshot has an unknown operator (%d)", ret.symbol))
+			return nil
+		}
+		stage.token = ret.token
+		stage.source = ret.source
+		stage.tokenIndex = ret.tokenIndex
+		ret = stage
+	}
+
+	if flags&snapshotHasLeft != 0 {
+		ret.leftStage = this.readStage(tokens, sources, depth+1)
+	}
+	if flags&snapshotHasRight != 0 {
+		ret.rightStage = this.readStage(tokens, sources, depth+1)
+	}
+
+	if this.err != nil {
+		return nil
+	}
+	return ret
+}
+
+/*
+	Reads the index of one of the given [tokens], and returns that token, its source, and the index.
+*/
+func (this *snapshotReader) readTokenIndex(tokens []ExpressionToken, sources []tokenSource) (ExpressionToken, tokenSource, int) {
+
+	index := this.readUvarint()
+	if index >= uint64(len(tokens)) {
+		this.fail(errors.New("Expression snapshot has a stage with a token it doesn't have"))
+		return ExpressionToken{}, tokenSource{}, 0
+	}
+	return tokens[index], sources[index], int(index)
+}
+
+func (this *snapshotReader) readValue() interface{} {
+
+	switch this.readByte() {
+
+	case snapshotNil:
+		return nil
+
+	case snapshotBool:
+		return this.readBool()
+
+	case snapshotFloat:
+
+		if len(this.data) < 8 {
+			this.fail(errors.New("Unexpected end of expression snapshot"))
+			return nil
+		}
+
+		ret := math.Float64frombits(binary.BigEndian.Uint64(this.data))
+		this.data = this.data[8:]
+		return ret
+
+	case snapshotInt:
+		return this.readVarint()
+
+	case snapshotDecimal:
+
+		ret, err := ParseDecimal(this.readString())
+		if err != nil {
+			this.fail(err)
+		}
+		return ret
+
+	case snapshotString:
+		return this.readString()
+
+	case snapshotTime:
+
+		var ret time.Time
+
+		err := ret.UnmarshalBinary([]byte(this.readString()))
+		if err != nil {
+			this.fail(err)
+		}
+		return ret
+
+	case snapshotDuration:
+		return time.Duration(this.readVarint())
+
+	case snapshotPattern:
+
+		ret, err := regexp.Compile(this.readString())
+		if err != nil {
+			this.fail(err)
+		}
+		return ret
+
+	case snapshotPath:
+
+		var ret []string
+
+		count := this.readUvarint()
+		for i := uint64(0); i < count && this.err == nil; i++ {
+			ret = append(ret, this.readString())
+		}
+		return ret
+
+	case snapshotRune:
+		return rune(this.readVarint())
+
+	case snapshotFunction:
+		return nil
+	}
+
+	this.fail(errors.New("Expression snapshot has a value of an unknown kind"))
+	return nil
+}
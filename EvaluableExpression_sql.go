@@ -1,9 +1,11 @@
 package govaluate
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -11,157 +13,598 @@ import (
 	Returns a string representing this expression as if it were written in SQL.
 	This function assumes that all parameters exist within the same table, and that the table essentially represents
 	a serialized object of some sort (e.g., hibernate).
-	If your data model is more normalized, you may need to consider iterating through each actual token given by `Tokens()`
-	to create your query.
+	If your data model is more normalized, or holds JSON, give a dialect a ResolveIdentifier to write parameters and accessors
+	as qualified columns or JSON paths.
 
-	Boolean values are considered to be "1" for true, "0" for false.
+	The query is written in the DEFAULT_SQL_DIALECT, in which boolean values are considered to be "1" for true, "0" for false.
+	See `ToSQLQueryWithDialect` for other databases.
 
 	Times are formatted according to this.QueryDateFormat.
+	Quotes in strings are escaped by doubling them, but values from untrusted sources should use `ToSQLParameterized` instead.
 */
 func (this EvaluableExpression) ToSQLQuery() (string, error) {
 
-	var stream *tokenStream
-	var transactions *expressionOutputStream
-	var transaction string
+	return this.ToSQLQueryWithDialect(DEFAULT_SQL_DIALECT)
+}
+
+/*
+	Same as `ToSQLQuery`, except the query is written in the given [dialect], such as POSTGRES_SQL_DIALECT.
+	Returns an error if the expression uses something the dialect doesn't support.
+*/
+func (this EvaluableExpression) ToSQLQueryWithDialect(dialect SQLDialect) (string, error) {
+
+	return this.writeSQL(&sqlWriter{dialect: dialect})
+}
+
+/*
+	Represents the way that placeholders for arguments are written by `ToSQLParameterized`.
+	Different databases (and drivers) expect different styles.
+*/
+type SQLPlaceholderStyle int
+
+const (
+
+	/*
+		Placeholders are written as `?`, as used by MySQL and SQLite.
+	*/
+	QUESTION_PLACEHOLDERS SQLPlaceholderStyle = iota
+
+	/*
+		Placeholders are numbered, as `$1`, `$2`, and so on. As used by PostgreSQL.
+	*/
+	DOLLAR_PLACEHOLDERS
+
+	/*
+		Placeholders are numbered, as `@p1`, `@p2`, and so on. As used by SQL Server.
+	*/
+	AT_PLACEHOLDERS
+
+	/*
+		Placeholders are named, as `:p1`, `:p2`, and so on. As used by Oracle.
+		Each argument is a `sql.NamedArg` with the same name as its placeholder.
+	*/
+	NAMED_PLACEHOLDERS
+)
+
+func (this SQLPlaceholderStyle) String() string {
+
+	switch this {
+	case QUESTION_PLACEHOLDERS:
+		return "QUESTION_PLACEHOLDERS"
+	case DOLLAR_PLACEHOLDERS:
+		return "DOLLAR_PLACEHOLDERS"
+	case AT_PLACEHOLDERS:
+		return "AT_PLACEHOLDERS"
+	case NAMED_PLACEHOLDERS:
+		return "NAMED_PLACEHOLDERS"
+	}
+	return "UNKNOWN"
+}
+
+/*
+	Same as `ToSQLQuery`, except that literal strings, numbers, times, and patterns are not written into the query.
+	Instead, each is written as a placeholder in the given [style], and its value is returned in the same order as the placeholders,
+	ready to be given to `database/sql` along with the query. e.g.:
+
+		query, arguments, err := expression.ToSQLParameterized(govaluate.QUESTION_PLACEHOLDERS)
+		rows, err := db.Query("SELECT * FROM users WHERE " + query, arguments...)
+
+	Times are given as `time.Time`, and exact decimals (see DECIMAL_NUMERICS) as their string.
+	Booleans and durations are still written into the query, since they're always one of a known set of keywords or numbers.
+*/
+func (this EvaluableExpression) ToSQLParameterized(style SQLPlaceholderStyle) (string, []interface{}, error) {
+
+	return this.ToSQLParameterizedWithDialect(DEFAULT_SQL_DIALECT, style)
+}
+
+/*
+	Same as `ToSQLParameterized`, except the query is written in the given [dialect].
+*/
+func (this EvaluableExpression) ToSQLParameterizedWithDialect(dialect SQLDialect, style SQLPlaceholderStyle) (string, []interface{}, error) {
+
+	writer := &sqlWriter{
+		dialect:       dialect,
+		parameterized: true,
+		placeholders:  style,
+	}
+
+	query, err := this.writeSQL(writer)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, writer.arguments, nil
+}
+
+/*
+	Writes an expression as SQL, keeping track of the arguments for placeholders as it goes.
+*/
+type sqlWriter struct {
+	dialect    SQLDialect
+	dateFormat string
+
+	// if true, literal values are written as placeholders, and their values kept in [arguments].
+	parameterized bool
+	placeholders  SQLPlaceholderStyle
+	arguments     []interface{}
+}
+
+func (this EvaluableExpression) writeSQL(writer *sqlWriter) (string, error) {
+
+	if writer.dialect.QuoteIdentifier == nil {
+		writer.dialect.QuoteIdentifier = DEFAULT_SQL_DIALECT.QuoteIdentifier
+	}
+
+	writer.dateFormat = writer.dialect.DateFormat
+	if writer.dateFormat == "" {
+		writer.dateFormat = this.QueryDateFormat
+	}
+
+	// the planned stages (before optimization) have the structure of the expression, including the parenthesis it was written with.
+	stage, err := planStageTree(this.tokens, this.sources)
+	if err != nil || stage == nil {
+		return "", err
+	}
+
+	return writer.writeStage(stage)
+}
+
+func (this *sqlWriter) writeStage(stage *evaluationStage) (string, error) {
+
+	var left, right string
 	var err error
 
-	stream = newTokenStream(this.tokens)
-	transactions = new(expressionOutputStream)
+	switch stage.symbol {
+
+	case LITERAL:
+		return this.writeLiteral(stage.token)
 
-	for stream.hasNext() {
+	case VALUE:
+		return this.writeIdentifier([]string{stage.token.Value.(string)})
 
-		transaction, err = this.findNextSQLString(stream, transactions)
+	case NOOP:
+
+		if stage.rightStage == nil {
+			return "( )", nil
+		}
+
+		right, err = this.writeStage(stage.rightStage)
 		if err != nil {
 			return "", err
 		}
+		return "( " + right + " )", nil
 
-		transactions.add(transaction)
-	}
+	case NEGATE:
+		fallthrough
+	case BITWISE_NOT:
+		fallthrough
+	case INVERT:
 
-	return transactions.createString(" "), nil
-}
+		right, err = this.writeStage(stage.rightStage)
+		if err != nil {
+			return "", err
+		}
 
-func (this EvaluableExpression) findNextSQLString(stream *tokenStream, transactions *expressionOutputStream) (string, error) {
+		if stage.symbol == INVERT {
+			return "NOT " + right, nil
+		}
+		return stage.token.Value.(string) + right, nil
 
-	var token ExpressionToken
-	var ret string
+	case IN:
+		return this.writeIn(stage)
 
-	token = stream.next()
+	case TERNARY_TRUE:
+		fallthrough
+	case TERNARY_FALSE:
+		return this.writeTernary(stage)
 
-	switch token.Kind {
+	case FUNCTIONAL:
+		return this.writeFunction(stage)
 
-	case STRING:
-		ret = fmt.Sprintf("'%v'", token.Value)
-	case PATTERN:
-		ret = fmt.Sprintf("'%s'", token.Value.(*regexp.Regexp).String())
-	case TIME:
-		ret = fmt.Sprintf("'%s'", token.Value.(time.Time).Format(this.QueryDateFormat))
+	case ACCESS:
 
-	case LOGICALOP:
-		switch logicalSymbols[token.Value.(string)] {
+		path := stage.token.Value.([]string)
 
-		case AND:
-			ret = "AND"
-		case OR:
-			ret = "OR"
+		if stage.rightStage != nil {
+			return "", fmt.Errorf("Unable to write method call '%s' in SQL", strings.Join(path, "."))
 		}
 
-	case BOOLEAN:
-		if token.Value.(bool) {
-			ret = "1"
-		} else {
-			ret = "0"
+		if this.dialect.ResolveIdentifier == nil {
+			return "", fmt.Errorf("Unable to write accessor '%s' in SQL without a ResolveIdentifier in the dialect", strings.Join(path, "."))
 		}
+		return this.writeIdentifier(path)
+	}
 
-	case VARIABLE:
-		ret = fmt.Sprintf("[%s]", token.Value.(string))
+	// everything else has a left and right side.
+	left, err = this.writeStage(stage.leftStage)
+	if err != nil {
+		return "", err
+	}
 
-	case NUMERIC:
-		ret = fmt.Sprintf("%g", token.Value.(float64))
+	right, err = this.writeStage(stage.rightStage)
+	if err != nil {
+		return "", err
+	}
+
+	switch stage.symbol {
 
-	case COMPARATOR:
-		switch comparatorSymbols[token.Value.(string)] {
+	case EQ:
+		return left + " = " + right, nil
+	case NEQ:
+		return left + " <> " + right, nil
 
-		case EQ:
-			ret = "="
-		case NEQ:
-			ret = "<>"
-		case REQ:
-			ret = "RLIKE"
-		case NREQ:
-			ret = "NOT RLIKE"
-		default:
-			ret = fmt.Sprintf("%s", token.Value.(string))
+	case REQ:
+		fallthrough
+	case NREQ:
+
+		operator := this.dialect.RegexOperator
+		if stage.symbol == NREQ {
+			operator = this.dialect.NotRegexOperator
 		}
 
-	case TERNARY:
+		if operator == "" {
+			return "", fmt.Errorf("Regex comparisons are unsupported by the %s SQL dialect", this.dialect.Name)
+		}
+		return left + " " + operator + " " + right, nil
 
-		switch ternarySymbols[token.Value.(string)] {
+	case AND:
+		return left + " AND " + right, nil
+	case OR:
+		return left + " OR " + right, nil
 
-		case COALESCE:
+	case EXPONENT:
+		return fmt.Sprintf(this.dialect.ExponentFormat, left, right), nil
+	case MODULUS:
+		return fmt.Sprintf(this.dialect.ModulusFormat, left, right), nil
 
-			left := transactions.rollback()
-			right, err := this.findNextSQLString(stream, transactions)
-			if err != nil {
-				return "", err
-			}
+	case COALESCE:
+		return fmt.Sprintf("COALESCE(%s, %s)", left, right), nil
 
-			ret = fmt.Sprintf("COALESCE(%v, %v)", left, right)
-		case TERNARY_TRUE:
-			fallthrough
-		case TERNARY_FALSE:
-			return "", errors.New("Ternary operators are unsupported in SQL output")
+	case SEPARATE:
+		return left + " , " + right, nil
+
+	case PLUS:
+
+		if isStringStage(stage.leftStage) || isStringStage(stage.rightStage) {
+			return fmt.Sprintf(this.dialect.ConcatFormat, left, right), nil
 		}
-	case PREFIX:
-		switch prefixSymbols[token.Value.(string)] {
 
-		case INVERT:
-			ret = fmt.Sprintf("NOT")
-		default:
+	case BITWISE_XOR:
+
+		if this.dialect.BitwiseXorFormat == "" {
+			return "", fmt.Errorf("Bitwise XOR is unsupported by the %s SQL dialect", this.dialect.Name)
+		}
+		return fmt.Sprintf(this.dialect.BitwiseXorFormat, groupSQLOperand(stage.leftStage, left), groupSQLOperand(stage.rightStage, right)), nil
+
+	case BITWISE_AND:
+		fallthrough
+	case BITWISE_OR:
+		fallthrough
+	case BITWISE_LSHIFT:
+		fallthrough
+	case BITWISE_RSHIFT:
+		left = groupSQLOperand(stage.leftStage, left)
+		right = groupSQLOperand(stage.rightStage, right)
+	}
+
+	return left + " " + stage.token.Value.(string) + " " + right, nil
+}
+
+/*
+	Writes a parameter or accessor with the dialect's ResolveIdentifier, or just quotes parameter names if it has none.
+*/
+func (this *sqlWriter) writeIdentifier(path []string) (string, error) {
 
-			right, err := this.findNextSQLString(stream, transactions)
+	if this.dialect.ResolveIdentifier == nil {
+		return this.dialect.QuoteIdentifier(path[0]), nil
+	}
+
+	ret, err := this.dialect.ResolveIdentifier(path)
+	if err != nil {
+		return "", fmt.Errorf("Unable to write '%s' in SQL: %v", strings.Join(path, "."), err)
+	}
+	return ret, nil
+}
+
+/*
+	Writes a call to a function with the SQLFunction the dialect has for it.
+*/
+func (this *sqlWriter) writeFunction(stage *evaluationStage) (string, error) {
+
+	name := stage.source.name
+	if name == "" {
+		return "", errors.New("Unable to write a function without a name in SQL")
+	}
+
+	function, found := this.dialect.Functions[name]
+	if !found || function == nil {
+		return "", fmt.Errorf("Function '%s' has no SQL mapping in the %s SQL dialect", name, this.dialect.Name)
+	}
+
+	var arguments []string
+
+	// the arguments are in a parenthesis stage, which holds either a single argument or a list separated by commas.
+	if stage.rightStage != nil && stage.rightStage.rightStage != nil {
+
+		for _, argumentStage := range findListElements(stage.rightStage.rightStage) {
+
+			argument, err := this.writeStage(argumentStage)
 			if err != nil {
 				return "", err
 			}
+			arguments = append(arguments, argument)
+		}
+	}
+
+	ret, err := function(arguments)
+	if err != nil {
+		return "", fmt.Errorf("Unable to write function '%s' in SQL: %v", name, err)
+	}
+	return ret, nil
+}
+
+/*
+	Writes a ternary as a CASE expression. A ternary without an else (`foo ? 1`) gives NULL when its condition is false,
+	the same as CASE without an ELSE.
+*/
+func (this *sqlWriter) writeTernary(stage *evaluationStage) (string, error) {
+
+	var conditionStage, thenStage, elseStage *evaluationStage
+	var condition, then, otherwise string
+	var err error
+
+	conditionStage, thenStage, elseStage, err = splitTernary(stage)
+	if err != nil {
+		return "", err
+	}
+
+	// each part is written in the order it appears in the expression, so that the placeholders of their literals are in order too.
+	condition, err = this.writeStage(conditionStage)
+	if err != nil {
+		return "", err
+	}
 
-			ret = fmt.Sprintf("%s%s", token.Value.(string), right)
+	then, err = this.writeStage(thenStage)
+	if err != nil {
+		return "", err
+	}
+
+	if elseStage != nil {
+
+		otherwise, err = this.writeStage(elseStage)
+		if err != nil {
+			return "", err
 		}
-	case MODIFIER:
+		otherwise = " ELSE " + otherwise
+	}
+
+	return "CASE WHEN " + condition + " THEN " + then + otherwise + " END", nil
+}
 
-		switch modifierSymbols[token.Value.(string)] {
+/*
+	Returns the condition, then, and else (which may be nil) of the given ternary [stage].
 
-		case EXPONENT:
+	Ternaries are planned from left to right, so one in the then of another (`a ? b ? 1 : 2 : 3`)
+	is planned as `((a ? b) ? 1 : 2) : 3`. When `a` is false, that's `nil ? 1`, which fails to evaluate;
+	otherwise it gives `b ? 1 : 2`. So it's split as if it were `a ? (b ? 1 : 2) : 3`,
+	with new stages for the inner ternary.
+*/
+func splitTernary(stage *evaluationStage) (*evaluationStage, *evaluationStage, *evaluationStage, error) {
 
-			left := transactions.rollback()
-			right, err := this.findNextSQLString(stream, transactions)
-			if err != nil {
-				return "", err
+	var condition, then, otherwise *evaluationStage
+	var err error
+
+	if stage.symbol == TERNARY_TRUE {
+
+		if !isTernaryStage(stage.leftStage) {
+			return stage.leftStage, stage.rightStage, nil, nil
+		}
+
+		condition, then, otherwise, err = splitTernary(stage.leftStage)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		// a complete ternary can be a condition, but `(a ? b) ? 1` is `a ? (b ? 1)`.
+		if otherwise != nil {
+			return stage.leftStage, stage.rightStage, nil, nil
+		}
+
+		then = &evaluationStage{
+			symbol:     TERNARY_TRUE,
+			leftStage:  then,
+			rightStage: stage.rightStage,
+		}
+		return condition, then, nil, nil
+	}
+
+	if !isTernaryStage(stage.leftStage) {
+		return nil, nil, nil, errors.New("Unable to write ':' without a matching '?' in SQL")
+	}
+
+	condition, then, otherwise, err = splitTernary(stage.leftStage)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if otherwise != nil {
+		return nil, nil, nil, errors.New("Unable to write ':' without a matching '?' in SQL")
+	}
+
+	// an else belongs to the innermost ternary which doesn't have one yet.
+	if isTernaryStage(then) {
+
+		_, _, otherwise, err = splitTernary(then)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if otherwise == nil {
+
+			then = &evaluationStage{
+				symbol:     TERNARY_FALSE,
+				leftStage:  then,
+				rightStage: stage.rightStage,
 			}
+			return condition, then, nil, nil
+		}
+	}
 
-			ret = fmt.Sprintf("POW(%s, %s)", left, right)
-		case MODULUS:
+	return condition, then, stage.rightStage, nil
+}
 
-			left := transactions.rollback()
-			right, err := this.findNextSQLString(stream, transactions)
-			if err != nil {
-				return "", err
+func isTernaryStage(stage *evaluationStage) bool {
+	return stage != nil && (stage.symbol == TERNARY_TRUE || stage.symbol == TERNARY_FALSE)
+}
+
+func (this *sqlWriter) writeIn(stage *evaluationStage) (string, error) {
+
+	left, err := this.writeStage(stage.leftStage)
+	if err != nil {
+		return "", err
+	}
+
+	// a parameter which holds an array, rather than a list of values.
+	if stage.rightStage.symbol != NOOP {
+
+		if this.dialect.InArrayFormat == "" {
+			return "", fmt.Errorf("Membership in a parameter is unsupported by the %s SQL dialect, use a list of values instead", this.dialect.Name)
+		}
+
+		right, err := this.writeStage(stage.rightStage)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(this.dialect.InArrayFormat, left, right), nil
+	}
+
+	// most databases don't accept an empty list, but nothing is ever in one.
+	if stage.rightStage.rightStage == nil {
+		return "1 = 0", nil
+	}
+
+	right, err := this.writeStage(stage.rightStage)
+	if err != nil {
+		return "", err
+	}
+	return left + " " + this.dialect.InOperator + " " + right, nil
+}
+
+func (this *sqlWriter) writeLiteral(token ExpressionToken) (string, error) {
+
+	switch token.Kind {
+
+	case STRING:
+
+		if this.parameterized {
+			return this.bind(token.Value), nil
+		}
+		return quoteSQLString(token.Value.(string)), nil
+
+	case PATTERN:
+
+		if this.parameterized {
+			return this.bind(token.Value.(*regexp.Regexp).String()), nil
+		}
+		return quoteSQLString(token.Value.(*regexp.Regexp).String()), nil
+
+	case TIME:
+
+		if this.parameterized {
+			return this.bind(token.Value), nil
+		}
+		return quoteSQLString(token.Value.(time.Time).Format(this.dateFormat)), nil
+
+	case DURATION:
+
+		if this.dialect.IntervalFormat == "" {
+			return "", fmt.Errorf("Durations are unsupported by the %s SQL dialect", this.dialect.Name)
+		}
+		return fmt.Sprintf(this.dialect.IntervalFormat, token.Value.(time.Duration).Seconds()), nil
+
+	case BOOLEAN:
+
+		if token.Value.(bool) {
+			return this.dialect.TrueLiteral, nil
+		}
+		return this.dialect.FalseLiteral, nil
+
+	case NUMERIC:
+
+		if this.parameterized {
+
+			if isDecimal(token.Value) {
+				return this.bind(token.Value.(Decimal).String()), nil
 			}
+			return this.bind(token.Value), nil
+		}
 
-			ret = fmt.Sprintf("MOD(%s, %s)", left, right)
-		default:
-			ret = fmt.Sprintf("%s", token.Value.(string))
+		switch token.Value.(type) {
+		case int64:
+			return fmt.Sprintf("%d", token.Value.(int64)), nil
+		case Decimal:
+			return token.Value.(Decimal).String(), nil
 		}
-	case CLAUSE:
-		ret = "("
-	case CLAUSE_CLOSE:
-		ret = ")"
-	case SEPARATOR:
-		ret = ","
+		return fmt.Sprintf("%g", token.Value.(float64)), nil
+	}
+
+	errorMsg := fmt.Sprintf("Unrecognized query token '%s' of kind '%s'", token.Value, token.Kind)
+	return "", errors.New(errorMsg)
+}
 
-	default:
-		errorMsg := fmt.Sprintf("Unrecognized query token '%s' of kind '%s'", token.Value, token.Kind)
-		return "", errors.New(errorMsg)
+/*
+	Returns true if the given stage is known to give a string, which makes `+` a concatenation.
+	Parameters could be anything, so only literal strings (and what's concatenated with them) count.
+*/
+func isStringStage(stage *evaluationStage) bool {
+
+	switch stage.symbol {
+	case LITERAL:
+		return stage.token.Kind == STRING
+	case NOOP:
+		return stage.rightStage != nil && isStringStage(stage.rightStage)
+	case PLUS:
+		return isStringStage(stage.leftStage) || isStringStage(stage.rightStage)
 	}
+	return false
+}
 
-	return ret, nil
+/*
+	Databases don't agree on the precedence of bitwise operators (or with govaluate, where they're all the same),
+	so any operation next to one is put in parenthesis to be sure it means the same thing.
+*/
+func groupSQLOperand(stage *evaluationStage, sql string) string {
+
+	if stage.leftStage == nil || stage.rightStage == nil {
+		return sql
+	}
+	return "(" + sql + ")"
+}
+
+/*
+	Keeps the given [value] as an argument, and returns the placeholder which refers to it.
+*/
+func (this *sqlWriter) bind(value interface{}) string {
+
+	this.arguments = append(this.arguments, value)
+	number := len(this.arguments)
+
+	switch this.placeholders {
+	case DOLLAR_PLACEHOLDERS:
+		return fmt.Sprintf("$%d", number)
+	case AT_PLACEHOLDERS:
+		return fmt.Sprintf("@p%d", number)
+	case NAMED_PLACEHOLDERS:
+
+		name := fmt.Sprintf("p%d", number)
+		this.arguments[number-1] = sql.Named(name, value)
+		return ":" + name
+	}
+	return "?"
+}
+
+/*
+	Quotes the given [text] as a SQL string literal, doubling any single quotes within it.
+*/
+func quoteSQLString(text string) string {
+	return "'" + strings.Replace(text, "'", "''", -1) + "'"
 }
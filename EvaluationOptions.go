@@ -0,0 +1,103 @@
+package govaluate
+
+import (
+	"fmt"
+)
+
+/*
+	Represents the limits which can be placed upon a single evaluation of an expression.
+	These are meant for expressions which come from untrusted sources, where an expression may otherwise
+	consume an arbitrary amount of time or memory.
+
+	Each limit is disabled when it is zero (the default).
+*/
+type EvaluationOptions struct {
+
+	/*
+		The maximum number of stages (operators, values, function calls, and accessors) that can be run in one evaluation.
+	*/
+	MaxSteps int
+
+	/*
+		The maximum length, in bytes, of any string produced by concatenation, or returned by a function.
+	*/
+	MaxStringLength int
+
+	/*
+		The maximum number of elements of any array produced by the separator, or returned by a function.
+	*/
+	MaxArrayLength int
+}
+
+/*
+	Represents one of the limits in an EvaluationOptions.
+*/
+type EvaluationLimit int
+
+const (
+	STEP_LIMIT EvaluationLimit = iota
+	STRING_LENGTH_LIMIT
+	ARRAY_LENGTH_LIMIT
+)
+
+func (this EvaluationLimit) String() string {
+
+	switch this {
+	case STEP_LIMIT:
+		return "MaxSteps"
+	case STRING_LENGTH_LIMIT:
+		return "MaxStringLength"
+	case ARRAY_LENGTH_LIMIT:
+		return "MaxArrayLength"
+	}
+	return "UNKNOWN"
+}
+
+/*
+	Returned by evaluation when it has been stopped for exceeding one of the limits in the expression's EvaluationOptions.
+*/
+type LimitExceededError struct {
+	Limit   EvaluationLimit
+	Maximum int
+}
+
+func (this LimitExceededError) Error() string {
+	return fmt.Sprintf("Evaluation exceeded limit %s (%d)", this.Limit.String(), this.Maximum)
+}
+
+/*
+	Checks the [result] of the given stage against the string and array limits.
+*/
+func (this EvaluationOptions) checkResult(symbol OperatorSymbol, result interface{}) error {
+
+	switch symbol {
+	case PLUS:
+		return this.checkString(result)
+	case SEPARATE:
+		return this.checkArray(result)
+	case FUNCTIONAL:
+		// functions can build strings and arrays of any size, so are held to the same limits as the operators which do.
+		err := this.checkString(result)
+		if err != nil {
+			return err
+		}
+		return this.checkArray(result)
+	}
+	return nil
+}
+
+func (this EvaluationOptions) checkString(result interface{}) error {
+
+	if this.MaxStringLength > 0 && isString(result) && len(result.(string)) > this.MaxStringLength {
+		return LimitExceededError{STRING_LENGTH_LIMIT, this.MaxStringLength}
+	}
+	return nil
+}
+
+func (this EvaluationOptions) checkArray(result interface{}) error {
+
+	if this.MaxArrayLength > 0 && isArray(result) && len(result.([]interface{})) > this.MaxArrayLength {
+		return LimitExceededError{ARRAY_LENGTH_LIMIT, this.MaxArrayLength}
+	}
+	return nil
+}
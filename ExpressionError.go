@@ -0,0 +1,99 @@
+package govaluate
+
+/*
+	Returned when an expression string can't be read as tokens,
+	such as when a string literal is never closed, or a number can't be parsed.
+*/
+type ParseError struct {
+	Message string
+
+	// where the token which couldn't be read starts.
+	Position Position
+
+	// as much of the token as was read, with the text it was read from as its value.
+	// Its Kind is UNKNOWN if the kind of token couldn't be told.
+	Token ExpressionToken
+}
+
+func (this ParseError) Error() string {
+	return this.Message
+}
+
+/*
+	Returned when tokens are valid on their own, but not in the order they were given,
+	such as two comparators in a row, a call to an undefined function, or unbalanced parenthesis.
+*/
+type SyntaxError struct {
+	Message  string
+	Position Position
+
+	// the token which could not be used, and the operator it would have been planned as.
+	// Tokens which aren't operators give the symbol of the value they're part of, such as LITERAL, FUNCTIONAL, or NOOP for parenthesis.
+	Symbol OperatorSymbol
+	Token  ExpressionToken
+}
+
+func (this SyntaxError) Error() string {
+	return this.Message
+}
+
+/*
+	Returned during evaluation when an operator is given a value it can't operate on,
+	such as a string given to `*`.
+*/
+type TypeError struct {
+	Message  string
+	Position Position
+
+	// the operator, and its token.
+	Symbol OperatorSymbol
+	Token  ExpressionToken
+
+	// the value which was rejected.
+	Value interface{}
+}
+
+func (this TypeError) Error() string {
+	return this.Message
+}
+
+/*
+	Returned during evaluation when an expression uses a parameter which wasn't given.
+	`MapParameters` (and the maps given to `Evaluate`) return this when they don't contain the parameter,
+	other `Parameters` implementations may too.
+*/
+type MissingParameterError struct {
+	Name string
+
+	// where the parameter is used in the expression, if it's known.
+	// Symbol is VALUE for a parameter, or ACCESS for an accessor.
+	Position Position
+	Symbol   OperatorSymbol
+	Token    ExpressionToken
+}
+
+func (this MissingParameterError) Error() string {
+	return "No parameter '" + this.Name + "' found."
+}
+
+/*
+	Returned during evaluation when a function, or a method called through an accessor, fails.
+	The original error is available through `errors.Unwrap`, `errors.Is` and `errors.As`.
+*/
+type FunctionError struct {
+
+	// the name of the function or accessor (such as "foo.Bar"). May be empty for expressions built from tokens.
+	Name     string
+	Position Position
+	Token    ExpressionToken
+
+	Err error
+}
+
+func (this FunctionError) Error() string {
+	return this.Err.Error()
+}
+
+func (this FunctionError) Unwrap() error {
+	return this.Err
+}
@@ -1,5 +1,9 @@
 package govaluate
 
+import (
+	"fmt"
+)
+
 /*
 	Represents a single parsed token.
 */
@@ -7,3 +11,36 @@ type ExpressionToken struct {
 	Kind  TokenKind
 	Value interface{}
 }
+
+/*
+	Where a token was read from in the original expression, and the name it was read as.
+	These are kept in a slice alongside an expression's tokens rather than in the tokens themselves,
+	so that tokens stay as simple to make and compare as they've always been.
+	Tokens which weren't read from an expression (such as those given to `NewEvaluableExpressionFromTokens`) have a zero source.
+*/
+type tokenSource struct {
+
+	// where the token starts, and where it ends (exclusive).
+	start Position
+	end   Position
+
+	// the name a FUNCTION token was called by.
+	name string
+}
+
+/*
+	Represents a location within the original expression string.
+*/
+type Position struct {
+
+	// the number of bytes before this location, starting from 0.
+	Offset int
+
+	// the line and column of this location, both starting from 1. Columns count characters, not bytes.
+	Line   int
+	Column int
+}
+
+func (this Position) String() string {
+	return fmt.Sprintf("line %d, column %d", this.Line, this.Column)
+}
@@ -0,0 +1,41 @@
+package govaluate
+
+/*
+	Represents the way that numbers are represented when an expression is evaluated.
+*/
+type NumericMode int
+
+const (
+
+	/*
+		All numeric literals and parameters are converted to `float64`. This is the default.
+	*/
+	FLOAT_NUMERICS NumericMode = iota
+
+	/*
+		Integer literals and integer parameters are kept as `int64`, and all other numbers are `float64`.
+		Operators between two `int64` values follow Go's integer rules.
+		Operators between an `int64` and a `float64` convert the `int64` to a `float64` first.
+	*/
+	INTEGER_NUMERICS
+
+	/*
+		All numeric literals and parameters are converted to an exact `Decimal`.
+		Floats are converted using their shortest decimal representation, so a `float64` parameter of 0.1 becomes exactly 0.1.
+		Division is done with the precision and rounding given in ParsingOptions.
+	*/
+	DECIMAL_NUMERICS
+)
+
+func (this NumericMode) String() string {
+
+	switch this {
+	case FLOAT_NUMERICS:
+		return "FLOAT_NUMERICS"
+	case INTEGER_NUMERICS:
+		return "INTEGER_NUMERICS"
+	case DECIMAL_NUMERICS:
+		return "DECIMAL_NUMERICS"
+	}
+	return "UNKNOWN"
+}
@@ -220,6 +220,44 @@ var separatorSymbols = map[string]OperatorSymbol{
 	",": SEPARATE,
 }
 
+/*
+	Returns the symbol of the stage which the given [token] is planned as. Tokens which aren't operators
+	give the symbol of the value they're part of, such as LITERAL, FUNCTIONAL, or NOOP for parenthesis.
+*/
+func findTokenSymbol(token ExpressionToken) OperatorSymbol {
+
+	value, _ := token.Value.(string)
+
+	switch token.Kind {
+	case COMPARATOR:
+		return comparatorSymbols[value]
+	case LOGICALOP:
+		return logicalSymbols[value]
+	case MODIFIER:
+		return modifierSymbols[value]
+	case PREFIX:
+		return prefixSymbols[value]
+	case TERNARY:
+		return ternarySymbols[value]
+	case SEPARATOR:
+		return SEPARATE
+	case FUNCTION:
+		return FUNCTIONAL
+	case ACCESSOR:
+		return ACCESS
+	case CLAUSE:
+		fallthrough
+	case CLAUSE_CLOSE:
+		return NOOP
+	case VARIABLE:
+		fallthrough
+	case UNKNOWN:
+		return VALUE
+	}
+
+	return LITERAL
+}
+
 /*
 	Returns true if this operator is contained by the given array of candidate symbols.
 	False otherwise.
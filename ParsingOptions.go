@@ -0,0 +1,51 @@
+package govaluate
+
+import (
+	"math/big"
+)
+
+// the number of digits kept after the decimal point by decimal division, unless otherwise specified.
+const defaultDecimalPrecision int = 16
+
+/*
+	Represents the options which control how an expression is parsed, see `NewEvaluableExpressionWithOptions`.
+	The zero value parses expressions the same way as `NewEvaluableExpression`.
+*/
+type ParsingOptions struct {
+
+	/*
+		Functions which will be available to the expression.
+	*/
+	Functions map[string]ExpressionFunction
+
+	/*
+		Functions which will be available to the expression, and which receive the context given to `EvalContext`.
+		If a name is present in both this and [Functions], this one is used.
+	*/
+	ContextFunctions map[string]ContextExpressionFunction
+
+	/*
+		How numeric literals and parameters are represented during evaluation.
+	*/
+	NumericMode NumericMode
+
+	/*
+		Only used with DECIMAL_NUMERICS. The number of digits kept after the decimal point when dividing
+		(or using a negative exponent). If zero, 16 digits are kept.
+	*/
+	DecimalPrecision int
+
+	/*
+		Only used with DECIMAL_NUMERICS. How to round the digits discarded when dividing.
+		Defaults to big.ToNearestEven ("banker's rounding").
+	*/
+	DecimalRounding big.RoundingMode
+}
+
+func (this ParsingOptions) decimalPrecision() int {
+
+	if this.DecimalPrecision == 0 {
+		return defaultDecimalPrecision
+	}
+	return this.DecimalPrecision
+}
@@ -0,0 +1,278 @@
+package govaluate
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+	Describes how an expression is written in a particular database's flavor of SQL, see `ToSQLQueryWithDialect`.
+	To change how something is written, copy one of the built-in dialects and change its fields.
+*/
+type SQLDialect struct {
+
+	/*
+		The name of this dialect, used in error messages.
+	*/
+	Name string
+
+	/*
+		Quotes the name of a parameter as a column name.
+	*/
+	QuoteIdentifier func(name string) string
+
+	/*
+		If set, writes each parameter and accessor instead of QuoteIdentifier, such as to qualify columns with their table,
+		or to write accessors as paths into JSON columns (see `SQLQualifiedColumns` and `SQLJSONPathColumns`).
+		If nil, accessors can't be written.
+	*/
+	ResolveIdentifier IdentifierResolver
+
+	/*
+		The operators which `=~` and `!~` are written as. If empty, regexes can't be used with this dialect.
+	*/
+	RegexOperator    string
+	NotRegexOperator string
+
+	/*
+		How `true` and `false` are written.
+	*/
+	TrueLiteral  string
+	FalseLiteral string
+
+	/*
+		The layout used to format times, as given to `time.Time.Format`.
+		If empty, the QueryDateFormat of the expression is used.
+	*/
+	DateFormat string
+
+	/*
+		Formats for `**` and `%`, which are given the left and right side of the operator, in that order.
+	*/
+	ExponentFormat string
+	ModulusFormat  string
+
+	/*
+		The format used for `+` when either side is a string, which is given the left and right sides in that order.
+	*/
+	ConcatFormat string
+
+	/*
+		The format used for `^`, which is given the left and right sides in that order.
+		If empty, bitwise XOR can't be used with this dialect.
+	*/
+	BitwiseXorFormat string
+
+	/*
+		The format of durations, which is given the (fractional) number of seconds in the duration as a float64.
+		If empty, durations can't be used with this dialect.
+	*/
+	IntervalFormat string
+
+	/*
+		The keyword which `in` is written as, when it's followed by a list of values (such as `foo in (1, 2)`).
+	*/
+	InOperator string
+
+	/*
+		The format used for `in` when it's followed by a single parameter which holds an array, such as `foo in bar`.
+		It's given the left and right sides, in that order. If empty, this isn't possible with this dialect.
+	*/
+	InArrayFormat string
+
+	/*
+		How calls to functions are written, by the name they're called with in the expression.
+		None of the built-in dialects have any, since they don't know what your functions do. Calls to functions without one are an error.
+	*/
+	Functions map[string]SQLFunction
+}
+
+/*
+	Writes the parameter or accessor with the given [path] as it should be referred to in a query.
+	A parameter (such as `foo`) has a path with just its name, and an accessor (such as `user.Address.City`) has a path of every
+	name in it (in this case "user", "Address", "City").
+*/
+type IdentifierResolver func(path []string) (string, error)
+
+/*
+	Returns an IdentifierResolver which quotes each name in a path with [quote] (such as a dialect's QuoteIdentifier),
+	and joins them with dots. So `users.city` becomes `"users"."city"`, a column qualified with its table.
+*/
+func SQLQualifiedColumns(quote func(name string) string) IdentifierResolver {
+
+	return func(path []string) (string, error) {
+
+		names := make([]string, len(path))
+		for i, name := range path {
+			names[i] = quote(name)
+		}
+		return strings.Join(names, "."), nil
+	}
+}
+
+/*
+	Returns an IdentifierResolver which writes accessors as paths into a PostgreSQL JSON(B) column,
+	so `data.Address.City` becomes `"data"->'Address'->>'City'`. The first name is the column, quoted with [quote],
+	and the rest are keys within it. The last key is read as text, to be compared with strings.
+	Parameters are written as just their (quoted) column.
+*/
+func SQLJSONPathColumns(quote func(name string) string) IdentifierResolver {
+
+	return func(path []string) (string, error) {
+
+		ret := quote(path[0])
+		for i, key := range path[1:] {
+
+			if i == len(path)-2 {
+				ret += "->>" + quoteSQLString(key)
+			} else {
+				ret += "->" + quoteSQLString(key)
+			}
+		}
+		return ret, nil
+	}
+}
+
+/*
+	Writes a call to a function as SQL, given the SQL of each of its [arguments].
+
+	When placeholders are being used, the arguments may contain them, and the returned SQL should use each argument once,
+	in the order given, so that they still line up with the values returned by `ToSQLParameterized`.
+*/
+type SQLFunction func(arguments []string) (string, error)
+
+/*
+	Returns a SQLFunction which calls the SQL function of the given [name] with the same arguments,
+	so `lower(name)` could become `LOWER("name")`.
+*/
+func SQLFunctionName(name string) SQLFunction {
+
+	return func(arguments []string) (string, error) {
+		return name + "(" + strings.Join(arguments, ", ") + ")", nil
+	}
+}
+
+/*
+	Returns a SQLFunction which writes its arguments into the given [format], as given to `fmt.Sprintf`.
+	For example, "%s BETWEEN %s AND %s" could be used for a function `between(x, low, high)`.
+	The function must be called with as many arguments as the format uses, or writing it is an error.
+*/
+func SQLFunctionFormat(format string) SQLFunction {
+
+	return func(arguments []string) (string, error) {
+
+		values := make([]interface{}, len(arguments))
+		for i, argument := range arguments {
+			values[i] = argument
+		}
+
+		// fmt writes mistakes (like missing or extra arguments) into its output, starting with "%!".
+		ret := fmt.Sprintf(format, values...)
+		if strings.Contains(ret, "%!") && !strings.Contains(format, "%!") {
+			return "", fmt.Errorf("%d arguments don't fit the format '%s'", len(arguments), format)
+		}
+		return ret, nil
+	}
+}
+
+/*
+	The dialect used by `ToSQLQuery`. Parameters are quoted with brackets, regexes use `RLIKE`, and booleans are `1` and `0`.
+*/
+var DEFAULT_SQL_DIALECT = SQLDialect{
+	Name:             "default",
+	QuoteIdentifier:  quoteWith("[", "]", "]"),
+	RegexOperator:    "RLIKE",
+	NotRegexOperator: "NOT RLIKE",
+	TrueLiteral:      "1",
+	FalseLiteral:     "0",
+	ExponentFormat:   "POW(%s, %s)",
+	ModulusFormat:    "MOD(%s, %s)",
+	IntervalFormat:   "INTERVAL %g SECOND",
+	ConcatFormat:     "CONCAT(%s, %s)",
+	BitwiseXorFormat: "%s ^ %s",
+	InOperator:       "in",
+	InArrayFormat:    "%s in %s",
+}
+
+/*
+	PostgreSQL. Regexes use `~`, bitwise XOR is `#`, and arrays held by a parameter are matched with `= ANY(...)`.
+*/
+var POSTGRES_SQL_DIALECT = SQLDialect{
+	Name:             "PostgreSQL",
+	QuoteIdentifier:  quoteWith("\"", "\"", "\""),
+	RegexOperator:    "~",
+	NotRegexOperator: "!~",
+	TrueLiteral:      "TRUE",
+	FalseLiteral:     "FALSE",
+	DateFormat:       "2006-01-02 15:04:05.999999-07:00",
+	ExponentFormat:   "POWER(%s, %s)",
+	ModulusFormat:    "MOD(%s, %s)",
+	IntervalFormat:   "INTERVAL '%g seconds'",
+	ConcatFormat:     "%s || %s",
+	BitwiseXorFormat: "%s # %s",
+	InOperator:       "IN",
+	InArrayFormat:    "%s = ANY(%s)",
+}
+
+/*
+	MySQL (and MariaDB). Times are written without a time zone, since MySQL's DATETIME doesn't have one.
+*/
+var MYSQL_SQL_DIALECT = SQLDialect{
+	Name:             "MySQL",
+	QuoteIdentifier:  quoteWith("`", "`", "`"),
+	RegexOperator:    "REGEXP",
+	NotRegexOperator: "NOT REGEXP",
+	TrueLiteral:      "TRUE",
+	FalseLiteral:     "FALSE",
+	DateFormat:       "2006-01-02 15:04:05.999999",
+	ExponentFormat:   "POW(%s, %s)",
+	ModulusFormat:    "MOD(%s, %s)",
+	IntervalFormat:   "INTERVAL %g SECOND",
+	ConcatFormat:     "CONCAT(%s, %s)",
+	BitwiseXorFormat: "%s ^ %s",
+	InOperator:       "IN",
+}
+
+/*
+	SQLite. Regexes use `REGEXP`, which needs a regexp() function to be registered with the database.
+	Exponents use `POWER()`, which needs SQLite to be built with its math functions, and there's no bitwise XOR.
+*/
+var SQLITE_SQL_DIALECT = SQLDialect{
+	Name:             "SQLite",
+	QuoteIdentifier:  quoteWith("\"", "\"", "\""),
+	RegexOperator:    "REGEXP",
+	NotRegexOperator: "NOT REGEXP",
+	TrueLiteral:      "1",
+	FalseLiteral:     "0",
+	DateFormat:       "2006-01-02 15:04:05.999",
+	ExponentFormat:   "POWER(%s, %s)",
+	ModulusFormat:    "%s %% %s",
+	ConcatFormat:     "%s || %s",
+	InOperator:       "IN",
+}
+
+/*
+	Microsoft SQL Server (T-SQL). SQL Server has no regex operators, so regexes can't be used.
+*/
+var SQL_SERVER_SQL_DIALECT = SQLDialect{
+	Name:             "SQL Server",
+	QuoteIdentifier:  quoteWith("[", "]", "]"),
+	TrueLiteral:      "1",
+	FalseLiteral:     "0",
+	DateFormat:       "2006-01-02T15:04:05.9999999Z07:00",
+	ExponentFormat:   "POWER(%s, %s)",
+	ModulusFormat:    "%s %% %s",
+	ConcatFormat:     "%s + %s",
+	BitwiseXorFormat: "%s ^ %s",
+	InOperator:       "IN",
+}
+
+/*
+	Returns a function which quotes names between [opening] and [closing], doubling any [escaped] character within them.
+*/
+func quoteWith(opening string, closing string, escaped string) func(name string) string {
+
+	return func(name string) string {
+		return opening + strings.Replace(name, escaped, escaped+escaped, -1) + closing
+	}
+}
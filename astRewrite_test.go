@@ -0,0 +1,461 @@
+package govaluate
+
+import (
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of rewriting an expression.
+	[Expected] is the AST of the rewritten expression, as produced by `describeNode`.
+*/
+type RewriteTest struct {
+	Name       string
+	Input      string
+	Rewriter   func(node ASTNode) ASTNode
+	Parameters map[string]interface{}
+	Expected   string
+	Value      interface{}
+}
+
+func TestInspect(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("a > 1 && (b.Name == 'x' || c in (d, 2))")
+
+	var names []string
+	Inspect(expression.AST(), func(node ASTNode) bool {
+
+		switch node.(type) {
+		case *VariableNode:
+			names = append(names, node.(*VariableNode).Name)
+		case *AccessorNode:
+			names = append(names, strings.Join(node.(*AccessorNode).Path, "."))
+		}
+		return true
+	})
+
+	if strings.Join(names, " ") != "a b.Name c d" {
+		test.Errorf("Expected to inspect 'a b.Name c d', got '%s'", strings.Join(names, " "))
+	}
+
+	// not descending into a node skips everything below it.
+	names = nil
+	Inspect(expression.AST(), func(node ASTNode) bool {
+
+		variable, isVariable := node.(*VariableNode)
+		if isVariable {
+			names = append(names, variable.Name)
+		}
+		return node == nil || node.Symbol() != OR
+	})
+
+	if strings.Join(names, " ") != "a" {
+		test.Errorf("Expected to inspect 'a', got '%s'", strings.Join(names, " "))
+	}
+}
+
+/*
+	Records the depth of each variable, to check that Walk visits nil after a node's children.
+*/
+type depthVisitor struct {
+	depth  *int
+	depths map[string]int
+}
+
+func (this depthVisitor) Visit(node ASTNode) ASTVisitor {
+
+	if node == nil {
+		*this.depth--
+		return nil
+	}
+
+	variable, isVariable := node.(*VariableNode)
+	if isVariable {
+		this.depths[variable.Name] = *this.depth
+	}
+
+	*this.depth++
+	return this
+}
+
+func TestWalk(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("a + (b * c) - d")
+
+	depth := 0
+	visitor := depthVisitor{&depth, make(map[string]int)}
+	Walk(visitor, expression.AST())
+
+	expected := map[string]int{"a": 2, "b": 3, "c": 3, "d": 1}
+	for name, expectedDepth := range expected {
+		if visitor.depths[name] != expectedDepth {
+			test.Errorf("Expected '%s' at depth %d, got %d", name, expectedDepth, visitor.depths[name])
+		}
+	}
+
+	if depth != 0 {
+		test.Errorf("Expected every visited node to be left, ended at depth %d", depth)
+	}
+}
+
+func TestRewrite(test *testing.T) {
+
+	renameVariable := func(from string, to string) func(node ASTNode) ASTNode {
+		return func(node ASTNode) ASTNode {
+
+			variable, isVariable := node.(*VariableNode)
+			if isVariable && variable.Name == from {
+				variable.Name = to
+			}
+			return node
+		}
+	}
+
+	replaceVariable := func(name string, replacement ASTNode) func(node ASTNode) ASTNode {
+		return func(node ASTNode) ASTNode {
+
+			variable, isVariable := node.(*VariableNode)
+			if isVariable && variable.Name == name {
+				return replacement
+			}
+			return node
+		}
+	}
+
+	rewriteTests := []RewriteTest{
+
+		RewriteTest{
+
+			Name:       "Rename variable",
+			Input:      "old > 1 && other == old",
+			Rewriter:   renameVariable("old", "new"),
+			Parameters: map[string]interface{}{"new": 2, "other": 2},
+			Expected:   "(&& (> new 1) (= other new))",
+			Value:      true,
+		},
+		RewriteTest{
+
+			Name:       "Replace variable with lower precedence",
+			Input:      "a * b",
+			Rewriter:   replaceVariable("b", &BinaryNode{Operator: PLUS, Left: &VariableNode{Name: "c"}, Right: &LiteralNode{Value: 1}}),
+			Parameters: map[string]interface{}{"a": 2, "c": 3},
+			Expected:   "(* a (+ c 1))",
+			Value:      8.0,
+		},
+		RewriteTest{
+
+			Name:       "Replace right side with same precedence",
+			Input:      "a - b",
+			Rewriter:   replaceVariable("b", &BinaryNode{Operator: MINUS, Left: &VariableNode{Name: "c"}, Right: &VariableNode{Name: "d"}}),
+			Parameters: map[string]interface{}{"a": 10, "c": 5, "d": 3},
+			Expected:   "(- a (- c d))",
+			Value:      8.0,
+		},
+		RewriteTest{
+
+			Name:       "Replace prefixed value",
+			Input:      "-a + 1",
+			Rewriter:   replaceVariable("a", &BinaryNode{Operator: MINUS, Left: &VariableNode{Name: "b"}, Right: &LiteralNode{Value: -2.0}}),
+			Parameters: map[string]interface{}{"b": 1},
+			Expected:   "(+ (- (- b -2)) 1)",
+			Value:      -2.0,
+		},
+		RewriteTest{
+
+			Name:       "Replace with negative literal under prefix",
+			Input:      "-a",
+			Rewriter:   replaceVariable("a", &LiteralNode{Value: -2}),
+			Parameters: map[string]interface{}{},
+			Expected:   "(- -2)",
+			Value:      2.0,
+		},
+		RewriteTest{
+
+			Name:       "Rename variable compared to a regex",
+			Input:      "(old =~ '^a') ? 1 : 2",
+			Rewriter:   renameVariable("old", "new"),
+			Parameters: map[string]interface{}{"new": "abc"},
+			Expected:   "(? (=~ new /^a/) 1 2)",
+			Value:      1.0,
+		},
+		RewriteTest{
+
+			Name:       "Replace with ternary",
+			Input:      "a ? b : c",
+			Rewriter:   replaceVariable("b", &TernaryNode{Condition: &VariableNode{Name: "d"}, Then: &LiteralNode{Value: "x"}, Else: &LiteralNode{Value: "y"}}),
+			Parameters: map[string]interface{}{"a": true, "d": false, "c": "z"},
+			Expected:   "(? a (? d 'x' 'y') c)",
+			Value:      "y",
+		},
+		RewriteTest{
+
+			Name:  "Replace operator",
+			Input: "a + b * c",
+			Rewriter: func(node ASTNode) ASTNode {
+
+				binary, isBinary := node.(*BinaryNode)
+				if isBinary && binary.Operator == PLUS {
+					binary.Operator = MULTIPLY
+				}
+				return node
+			},
+			Parameters: map[string]interface{}{"a": 2, "b": 3, "c": 4},
+			Expected:   "(* a (* b c))",
+			Value:      24.0,
+		},
+		RewriteTest{
+
+			Name:  "Replace array elements",
+			Input: "a in (1, 2)",
+			Rewriter: func(node ASTNode) ASTNode {
+
+				array, isArray := node.(*ArrayNode)
+				if isArray {
+					array.Elements = append(array.Elements, &LiteralNode{Value: 3})
+				}
+				return node
+			},
+			Parameters: map[string]interface{}{"a": 3},
+			Expected:   "(in a [1 2 3])",
+			Value:      true,
+		},
+		RewriteTest{
+
+			Name:  "Replace accessor with variable",
+			Input: "foo.Int + 1",
+			Rewriter: func(node ASTNode) ASTNode {
+
+				accessor, isAccessor := node.(*AccessorNode)
+				if isAccessor {
+					return &VariableNode{Name: strings.Join(accessor.Path, "_")}
+				}
+				return node
+			},
+			Parameters: map[string]interface{}{"foo_Int": 2},
+			Expected:   "(+ foo_Int 1)",
+			Value:      3.0,
+		},
+	}
+
+	runRewriteTests(rewriteTests, test)
+}
+
+func TestRewriteFunctions(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"strlen": func(arguments ...interface{}) (interface{}, error) {
+			return float64(len(arguments[0].(string))), nil
+		},
+		"length": func(arguments ...interface{}) (interface{}, error) {
+			return float64(len(arguments[0].(string))) * 10, nil
+		},
+	}
+
+	expression, err := NewEvaluableExpressionWithFunctions("strlen(name) > 20", functions)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	rewritten, err := expression.Rewrite(func(node ASTNode) ASTNode {
+
+		function, isFunction := node.(*FunctionNode)
+		if isFunction && function.Name == "strlen" {
+			function.Name = "length"
+			function.Function = nil
+		}
+		return node
+	})
+	if err != nil {
+		test.Fatalf("Unable to rewrite expression: %v", err)
+	}
+
+	if describeNode(rewritten.AST()) != "(> length(name) 20)" {
+		test.Errorf("Unexpected rewritten AST: %s", describeNode(rewritten.AST()))
+	}
+
+	result, err := rewritten.Evaluate(map[string]interface{}{"name": "abc"})
+	if err != nil || result != true {
+		test.Errorf("Expected rewritten function to be called, got %v (%v)", result, err)
+	}
+
+	// renaming to a function the expression wasn't parsed with is an error.
+	_, err = expression.Rewrite(func(node ASTNode) ASTNode {
+
+		function, isFunction := node.(*FunctionNode)
+		if isFunction {
+			function.Name = "missing"
+			function.Function = nil
+		}
+		return node
+	})
+	if err == nil || !strings.Contains(err.Error(), UNDEFINED_FUNCTION) {
+		test.Errorf("Expected undefined function error, got %v", err)
+	}
+}
+
+func TestExpressionFromAST(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("role == 'admin' || role == 'owner'")
+
+	guarded := &BinaryNode{
+		Operator: AND,
+		Left:     expression.AST(),
+		Right: &BinaryNode{
+			Operator: EQ,
+			Left:     &VariableNode{Name: "tenant"},
+			Right:    &LiteralNode{Value: "x"},
+		},
+	}
+
+	rewritten, err := NewEvaluableExpressionFromAST(guarded, ParsingOptions{})
+	if err != nil {
+		test.Fatalf("Unable to make expression: %v", err)
+	}
+
+	expected := "(&& (|| (= role 'admin') (= role 'owner')) (= tenant 'x'))"
+	if describeNode(rewritten.AST()) != expected {
+		test.Errorf("Expected AST '%s', got '%s'", expected, describeNode(rewritten.AST()))
+	}
+
+	result, _ := rewritten.Evaluate(map[string]interface{}{"role": "owner", "tenant": "y"})
+	if result != false {
+		test.Errorf("Expected guard to apply to the whole expression, got %v", result)
+	}
+
+	// numbers follow the numeric mode of the new expression.
+	rewritten, err = NewEvaluableExpressionFromAST(&BinaryNode{Operator: DIVIDE, Left: &LiteralNode{Value: 7}, Right: &LiteralNode{Value: 2}}, ParsingOptions{NumericMode: INTEGER_NUMERICS})
+	if err != nil {
+		test.Fatalf("Unable to make expression: %v", err)
+	}
+
+	result, _ = rewritten.Evaluate(nil)
+	if result != int64(3) {
+		test.Errorf("Expected int64 3, got %v (%T)", result, result)
+	}
+}
+
+func TestExpressionFromInvalidAST(test *testing.T) {
+
+	invalidTrees := []ASTNode{
+		&BinaryNode{Operator: NEGATE, Left: &LiteralNode{Value: 1}, Right: &LiteralNode{Value: 2}},
+		&UnaryNode{Operator: PLUS, Operand: &LiteralNode{Value: 1}},
+		&BinaryNode{Operator: PLUS, Left: &LiteralNode{Value: 1}},
+		&LiteralNode{Value: []int{1}},
+		&AccessorNode{Path: []string{"foo"}},
+		&FunctionNode{Name: "missing"},
+	}
+
+	for _, tree := range invalidTrees {
+
+		_, err := NewEvaluableExpressionFromAST(tree, ParsingOptions{})
+		if err == nil {
+			test.Errorf("Expected an error making an expression from %s", describeNode(tree))
+		}
+	}
+}
+
+/*
+	Making an expression from the AST of another should give an identical AST, whatever parenthesis the original had.
+*/
+func TestASTRoundTrip(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"max": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	inputs := []string{
+		"1 + 2 * 3",
+		"(1 + 2) * 3",
+		"1 - (2 - 3)",
+		"(1 - 2) - 3",
+		"2 ** (3 ** 2)",
+		"-(1 + 2) * -foo",
+		"!(a && b) || !c",
+		"a && (b || c)",
+		"(a ? b : c) ? d : e",
+		"a ? (b ? c : d) : e",
+		"a ? b : (c ? d : e)",
+		"(a ?? b) ?? c",
+		"a ?? (b ?? c)",
+		"(a == b) == (c == d)",
+		"1 | 2 & 3 << 4 + 5",
+		"((1 | 2) & 3 << 4) + 5",
+		"foo in (1, (2, 3), ())",
+		"max(1, max(2, 3)) + foo.Method((1 + 2) * 3) - foo.Field",
+		"foo =~ '^a' && bar !~ baz",
+		"-(-2)",
+		"~(1 + 2)",
+		"'2014-01-02' > now && 5m + dur < 1h",
+		"foo ? 'yes'",
+		"foo =~ 'x' ? 1 : 2",
+		"(foo =~ 'x') ? 1 : 2",
+		"foo =~ 'x' ?? 2",
+		"(foo > '2014-01-02') ? 1 : 2",
+	}
+
+	for _, input := range inputs {
+
+		expression, err := NewEvaluableExpressionWithFunctions(input, functions)
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", input, err)
+			continue
+		}
+
+		copied, err := NewEvaluableExpressionFromAST(expression.AST(), ParsingOptions{})
+		if err != nil {
+			test.Errorf("Unable to make expression from the AST of '%s': %v", input, err)
+			continue
+		}
+
+		expected := describeNode(expression.AST())
+		actual := describeNode(copied.AST())
+		if actual != expected {
+			test.Errorf("Expected '%s' to have AST '%s', got '%s'", input, expected, actual)
+		}
+	}
+}
+
+func runRewriteTests(rewriteTests []RewriteTest, test *testing.T) {
+
+	for _, rewriteTest := range rewriteTests {
+
+		expression, err := NewEvaluableExpression(rewriteTest.Input)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", rewriteTest.Name, err)
+			continue
+		}
+
+		rewritten, err := expression.Rewrite(rewriteTest.Rewriter)
+		if err != nil {
+			test.Errorf("Test '%s' failed to rewrite: %v", rewriteTest.Name, err)
+			continue
+		}
+
+		actual := describeNode(rewritten.AST())
+		if actual != rewriteTest.Expected {
+			test.Errorf("Test '%s' expected AST '%s', got '%s'", rewriteTest.Name, rewriteTest.Expected, actual)
+		}
+
+		result, err := rewritten.Evaluate(rewriteTest.Parameters)
+		if err != nil {
+			test.Errorf("Test '%s' failed to evaluate: %v", rewriteTest.Name, err)
+			continue
+		}
+
+		if result != rewriteTest.Value {
+			test.Errorf("Test '%s' expected %v, got %v", rewriteTest.Name, rewriteTest.Value, result)
+		}
+	}
+
+	// the original expression shouldn't be affected by rewriting.
+	expression, _ := NewEvaluableExpression("a + 1")
+	expression.Rewrite(func(node ASTNode) ASTNode {
+		return &LiteralNode{Value: 0}
+	})
+
+	if describeNode(expression.AST()) != "(+ a 1)" {
+		test.Errorf("Rewriting changed the original expression: %s", describeNode(expression.AST()))
+	}
+}
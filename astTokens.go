@@ -0,0 +1,452 @@
+package govaluate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+/*
+	How tightly a node holds on to the values next to it, from loosest to tightest.
+	This is the order in which the stage planner handles each precedence (starting from `planSeparator`),
+	and decides where parenthesis are needed when an AST is turned back into tokens.
+*/
+type astBinding int
+
+const (
+	separatorBinding astBinding = iota
+	ternaryBinding
+	logicalOrBinding
+	logicalAndBinding
+	comparatorBinding
+	bitwiseBinding
+	bitwiseShiftBinding
+	additiveBinding
+	multiplicativeBinding
+	exponentialBinding
+	prefixBinding
+	valueBinding
+)
+
+/*
+	Turns an AST back into the tokens of an expression, adding only the parenthesis that precedence requires.
+*/
+type astTokenWriter struct {
+	tokens  []ExpressionToken
+	sources []tokenSource
+	options ParsingOptions
+
+	// whether these tokens will only be formatted as text. If so, functions only need a name, and numbers keep their exact value.
+	formatting bool
+}
+
+func makeASTTokens(node ASTNode, options ParsingOptions) ([]ExpressionToken, []tokenSource, error) {
+
+	if node == nil {
+		return nil, nil, nil
+	}
+
+	writer := &astTokenWriter{options: options}
+
+	err := writer.writeNode(node)
+	if err != nil {
+		return nil, nil, err
+	}
+	return writer.tokens, writer.sources, nil
+}
+
+func (this *astTokenWriter) writeNode(node ASTNode) error {
+
+	var err error
+
+	switch node.(type) {
+
+	case *BinaryNode:
+
+		binary := node.(*BinaryNode)
+
+		binding, kind, found := findBinaryBinding(binary.Operator)
+		if !found {
+			return makeASTError(node, fmt.Sprintf("Unable to use '%v' as a binary operator", binary.Operator))
+		}
+
+		// all operators are left-associative, so only the right side needs parenthesis for operators of the same precedence.
+		err = this.writeOperand(binary.Left, findNodeBinding(binary.Left) < binding)
+		if err != nil {
+			return err
+		}
+
+		this.write(kind, symbolText(binary.Operator))
+		return this.writeOperand(binary.Right, findNodeBinding(binary.Right) <= binding)
+
+	case *UnaryNode:
+
+		unary := node.(*UnaryNode)
+
+		if findOperatorPrecedenceForSymbol(unary.Operator) != prefixPrecedence {
+			return makeASTError(node, fmt.Sprintf("Unable to use '%v' as a prefix", unary.Operator))
+		}
+
+		this.write(PREFIX, symbolText(unary.Operator))
+		return this.writeOperand(unary.Operand, !canFollowPrefix(unary.Operand))
+
+	case *TernaryNode:
+
+		ternary := node.(*TernaryNode)
+
+		err = this.writeOperand(ternary.Condition, findNodeBinding(ternary.Condition) < ternaryBinding)
+		if err != nil {
+			return err
+		}
+
+		this.write(TERNARY, "?")
+		err = this.writeOperand(ternary.Then, findNodeBinding(ternary.Then) <= ternaryBinding)
+		if err != nil || ternary.Else == nil {
+			return err
+		}
+
+		this.write(TERNARY, ":")
+		return this.writeOperand(ternary.Else, findNodeBinding(ternary.Else) <= ternaryBinding)
+
+	case *LiteralNode:
+
+		mode := this.options.NumericMode
+		if this.formatting {
+			mode = DECIMAL_NUMERICS
+		}
+
+		token, err := makeLiteralToken(node.(*LiteralNode).Value, mode)
+		if err != nil {
+			return makeASTError(node, err.Error())
+		}
+		this.writeToken(token, tokenSource{})
+
+	case *VariableNode:
+		this.write(VARIABLE, node.(*VariableNode).Name)
+
+	case *FunctionNode:
+
+		function := node.(*FunctionNode)
+
+		value, err := this.findFunction(function)
+		if err != nil {
+			return makeASTError(node, err.Error())
+		}
+
+		// these tokens weren't read from an expression, so the only source they have is the name the function was called by.
+		this.writeToken(ExpressionToken{Kind: FUNCTION, Value: value}, tokenSource{name: function.Name})
+		return this.writeList(function.Arguments)
+
+	case *AccessorNode:
+
+		accessor := node.(*AccessorNode)
+
+		if len(accessor.Path) < 2 {
+			return makeASTError(node, "Accessors need a parameter name and at least one field or method")
+		}
+
+		this.write(ACCESSOR, append([]string{}, accessor.Path...))
+		if accessor.Called {
+			return this.writeList(accessor.Arguments)
+		}
+
+	case *ArrayNode:
+		return this.writeList(node.(*ArrayNode).Elements)
+
+	case nil:
+		return makeASTError(node, "Unable to write a missing (nil) node")
+
+	default:
+		return makeASTError(node, fmt.Sprintf("Unable to write node of type %T", node))
+	}
+
+	return nil
+}
+
+/*
+	Writes a node which is next to an operator, in parenthesis if [grouped] is true.
+*/
+func (this *astTokenWriter) writeOperand(node ASTNode, grouped bool) error {
+
+	if !grouped {
+		return this.writeNode(node)
+	}
+
+	this.write(CLAUSE, '(')
+
+	err := this.writeNode(node)
+	if err != nil {
+		return err
+	}
+
+	this.write(CLAUSE_CLOSE, ')')
+	return nil
+}
+
+/*
+	Writes the given [nodes] in parenthesis, separated by commas.
+*/
+func (this *astTokenWriter) writeList(nodes []ASTNode) error {
+
+	this.write(CLAUSE, '(')
+
+	for i, node := range nodes {
+
+		if i > 0 {
+			this.write(SEPARATOR, ",")
+		}
+
+		err := this.writeNode(node)
+		if err != nil {
+			return err
+		}
+	}
+
+	this.write(CLAUSE_CLOSE, ')')
+	return nil
+}
+
+func (this *astTokenWriter) write(kind TokenKind, value interface{}) {
+	this.writeToken(ExpressionToken{Kind: kind, Value: value}, tokenSource{})
+}
+
+func (this *astTokenWriter) writeToken(token ExpressionToken, source tokenSource) {
+	this.tokens = append(this.tokens, token)
+	this.sources = append(this.sources, source)
+}
+
+/*
+	Returns the function a FunctionNode calls; either its Function, or the function of the same name from the parsing options.
+*/
+func (this *astTokenWriter) findFunction(node *FunctionNode) (interface{}, error) {
+
+	if this.formatting {
+
+		if node.Name == "" {
+			return nil, errors.New("Unable to format a function without a name")
+		}
+		return node.Function, nil
+	}
+
+	if node.Function == nil {
+
+		contextFunction, found := this.options.ContextFunctions[node.Name]
+		if found {
+			return contextFunction, nil
+		}
+
+		function, found := this.options.Functions[node.Name]
+		if found {
+			return function, nil
+		}
+
+		return nil, fmt.Errorf("Undefined function %s", node.Name)
+	}
+
+	switch node.Function.(type) {
+	case ExpressionFunction:
+		return node.Function, nil
+	case ContextExpressionFunction:
+		return node.Function, nil
+	case func(arguments ...interface{}) (interface{}, error):
+		return ExpressionFunction(node.Function.(func(arguments ...interface{}) (interface{}, error))), nil
+	case func(ctx context.Context, arguments ...interface{}) (interface{}, error):
+		return ContextExpressionFunction(node.Function.(func(ctx context.Context, arguments ...interface{}) (interface{}, error))), nil
+	}
+
+	return nil, fmt.Errorf("Function '%s' is a %T, not an ExpressionFunction", node.Name, node.Function)
+}
+
+/*
+	Returns the token for the constant [value], converting numbers to the given [mode].
+*/
+func makeLiteralToken(value interface{}, mode NumericMode) (ExpressionToken, error) {
+
+	var kind TokenKind
+
+	switch value.(type) {
+	case string:
+		kind = STRING
+	case bool:
+		kind = BOOLEAN
+	case time.Time:
+		kind = TIME
+	case time.Duration:
+		kind = DURATION
+	case *regexp.Regexp:
+		kind = PATTERN
+	default:
+
+		value = sanitizeNumeric(value, mode)
+		if !isNumber(value) {
+			return ExpressionToken{}, fmt.Errorf("Unable to use a %T as a literal", value)
+		}
+		kind = NUMERIC
+	}
+
+	return ExpressionToken{Kind: kind, Value: value}, nil
+}
+
+func findNodeBinding(node ASTNode) astBinding {
+
+	switch node.(type) {
+
+	case *BinaryNode:
+		binding, _, _ := findBinaryBinding(node.(*BinaryNode).Operator)
+		return binding
+
+	case *UnaryNode:
+		return prefixBinding
+
+	case *TernaryNode:
+		return ternaryBinding
+
+	case *LiteralNode:
+
+		// a negative number is written with a prefix.
+		if isNegative(node.(*LiteralNode).Value) {
+			return prefixBinding
+		}
+	}
+
+	return valueBinding
+}
+
+/*
+	Returns the binding of the given binary operator, and the kind of token it's written as.
+*/
+func findBinaryBinding(symbol OperatorSymbol) (astBinding, TokenKind, bool) {
+
+	switch symbol {
+	case EQ:
+		fallthrough
+	case NEQ:
+		fallthrough
+	case GT:
+		fallthrough
+	case LT:
+		fallthrough
+	case GTE:
+		fallthrough
+	case LTE:
+		fallthrough
+	case REQ:
+		fallthrough
+	case NREQ:
+		fallthrough
+	case IN:
+		return comparatorBinding, COMPARATOR, true
+	case AND:
+		return logicalAndBinding, LOGICALOP, true
+	case OR:
+		return logicalOrBinding, LOGICALOP, true
+	case BITWISE_AND:
+		fallthrough
+	case BITWISE_OR:
+		fallthrough
+	case BITWISE_XOR:
+		return bitwiseBinding, MODIFIER, true
+	case BITWISE_LSHIFT:
+		fallthrough
+	case BITWISE_RSHIFT:
+		return bitwiseShiftBinding, MODIFIER, true
+	case PLUS:
+		fallthrough
+	case MINUS:
+		return additiveBinding, MODIFIER, true
+	case MULTIPLY:
+		fallthrough
+	case DIVIDE:
+		fallthrough
+	case MODULUS:
+		return multiplicativeBinding, MODIFIER, true
+	case EXPONENT:
+		return exponentialBinding, MODIFIER, true
+	case TERNARY_TRUE:
+		fallthrough
+	case TERNARY_FALSE:
+		fallthrough
+	case COALESCE:
+		return ternaryBinding, TERNARY, true
+	}
+
+	return valueBinding, UNKNOWN, false
+}
+
+/*
+	A prefix can only be followed by a few kinds of token, anything else has to be in parenthesis.
+*/
+func canFollowPrefix(node ASTNode) bool {
+
+	switch node.(type) {
+	case *VariableNode:
+		return true
+	case *FunctionNode:
+		return true
+	case *AccessorNode:
+		return true
+	case *ArrayNode:
+		return true
+	case *LiteralNode:
+
+		value := node.(*LiteralNode).Value
+		if isNegative(value) {
+			return false
+		}
+
+		switch value.(type) {
+		case string:
+			return false
+		case time.Time:
+			return false
+		case *regexp.Regexp:
+			return false
+		}
+		return true
+	}
+
+	return false
+}
+
+func isNegative(value interface{}) bool {
+
+	value = castToFloat64(value)
+
+	switch value.(type) {
+	case float64:
+		return value.(float64) < 0
+	case int64:
+		return value.(int64) < 0
+	case Decimal:
+		return value.(Decimal).Sign() < 0
+	case time.Duration:
+		return value.(time.Duration) < 0
+	}
+	return false
+}
+
+/*
+	Returns the text an operator is written as.
+*/
+func symbolText(symbol OperatorSymbol) string {
+
+	// the String() of EQ is "=", for the sake of error messages.
+	if symbol == EQ {
+		return "=="
+	}
+	return symbol.String()
+}
+
+func makeASTError(node ASTNode, message string) error {
+
+	var position Position
+	var symbol OperatorSymbol
+
+	if node != nil {
+		position = node.Span().Start
+		symbol = node.Symbol()
+	}
+	return SyntaxError{Message: message, Position: position, Symbol: symbol}
+}
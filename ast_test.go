@@ -0,0 +1,299 @@
+package govaluate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+/*
+	Represents a test of the AST of an expression.
+	[Expected] is the AST written as nested, parenthesized nodes, as produced by `describeNode`.
+*/
+type ASTTest struct {
+	Name      string
+	Input     string
+	Functions map[string]ExpressionFunction
+	Expected  string
+}
+
+func TestASTStructure(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"max": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+		"now": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	astTests := []ASTTest{
+
+		ASTTest{
+
+			Name:     "Literal",
+			Input:    "1",
+			Expected: "1",
+		},
+		ASTTest{
+
+			Name:     "Precedence",
+			Input:    "1 + 2 * foo",
+			Expected: "(+ 1 (* 2 foo))",
+		},
+		ASTTest{
+
+			Name:     "Left associativity",
+			Input:    "1 - 2 - 3",
+			Expected: "(- (- 1 2) 3)",
+		},
+		ASTTest{
+
+			Name:     "Grouping parenthesis",
+			Input:    "(1 - 2) * 3",
+			Expected: "(* (- 1 2) 3)",
+		},
+		ASTTest{
+
+			Name:     "Literals are not precomputed",
+			Input:    "'foo' + 'bar' == foo",
+			Expected: "(= (+ 'foo' 'bar') foo)",
+		},
+		ASTTest{
+
+			Name:     "Logical and comparators",
+			Input:    "a > 1 && b < 2 || !c",
+			Expected: "(|| (&& (> a 1) (< b 2)) (! c))",
+		},
+		ASTTest{
+
+			Name:     "Prefixes",
+			Input:    "-foo + ~2",
+			Expected: "(+ (- foo) (~ 2))",
+		},
+		ASTTest{
+
+			Name:     "Ternary",
+			Input:    "a ? 'yes' : 'no'",
+			Expected: "(? a 'yes' 'no')",
+		},
+		ASTTest{
+
+			Name:     "Ternary without else",
+			Input:    "a ? 'yes'",
+			Expected: "(? a 'yes')",
+		},
+		ASTTest{
+
+			Name:     "Coalesce",
+			Input:    "a ?? 'default'",
+			Expected: "(?? a 'default')",
+		},
+		ASTTest{
+
+			Name:     "Array",
+			Input:    "foo in (1, 2, 3)",
+			Expected: "(in foo [1 2 3])",
+		},
+		ASTTest{
+
+			Name:      "Function call",
+			Input:     "max(1, foo + 2) > 3",
+			Functions: functions,
+			Expected:  "(> max(1 (+ foo 2)) 3)",
+		},
+		ASTTest{
+
+			Name:      "Function call without arguments",
+			Input:     "now() - then",
+			Functions: functions,
+			Expected:  "(- now() then)",
+		},
+		ASTTest{
+
+			Name:     "Accessors",
+			Input:    "foo.Bar + foo.Baz(1, 'x') + foo.Qux()",
+			Expected: "(+ (+ foo.Bar foo.Baz(1 'x')) foo.Qux())",
+		},
+		ASTTest{
+
+			Name:     "Accessor call followed by operator",
+			Input:    "foo.FuncArgStr('boop') + 'hi'",
+			Expected: "(+ foo.FuncArgStr('boop') 'hi')",
+		},
+		ASTTest{
+
+			Name:     "Regex",
+			Input:    "foo =~ '^b.*'",
+			Expected: "(=~ foo /^b.*/)",
+		},
+		ASTTest{
+
+			Name:     "Escaped variable",
+			Input:    "[foo bar] > 1",
+			Expected: "(> foo bar 1)",
+		},
+	}
+
+	runASTTests(astTests, test)
+}
+
+func TestASTSpans(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"max": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	input := "max(a, 2) >= (b +\n 1.5) && c.Name == 'né'"
+	expression, err := NewEvaluableExpressionWithFunctions(input, functions)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	root := expression.AST().(*BinaryNode)
+	comparison := root.Left.(*BinaryNode)
+	function := comparison.Left.(*FunctionNode)
+	group := comparison.Right.(*BinaryNode)
+	equality := root.Right.(*BinaryNode)
+
+	expectedSpans := []struct {
+		Node     ASTNode
+		Expected string
+	}{
+		{root, input},
+		{comparison, "max(a, 2) >= (b +\n 1.5)"},
+		{function, "max(a, 2)"},
+		{function.Arguments[0], "a"},
+		{group, "(b +\n 1.5)"},
+		{group.Right, "1.5"},
+		{equality, "c.Name == 'né'"},
+		{equality.Left, "c.Name"},
+		{equality.Right, "'né'"},
+	}
+
+	for _, expected := range expectedSpans {
+
+		span := expected.Node.Span()
+		actual := input[span.Start.Offset:span.End.Offset]
+
+		if actual != expected.Expected {
+			test.Errorf("Expected span '%s', got '%s' (%+v)", expected.Expected, actual, span)
+		}
+	}
+
+	if function.Name != "max" {
+		test.Errorf("Expected function name 'max', got '%s'", function.Name)
+	}
+
+	start := group.Right.Span().Start
+	if start.Line != 2 || start.Column != 2 {
+		test.Errorf("Expected '1.5' to start at line 2, column 2, got %v", start)
+	}
+}
+
+func TestASTFromTokens(test *testing.T) {
+
+	tokens := []ExpressionToken{
+		ExpressionToken{
+			Kind:  VARIABLE,
+			Value: "foo",
+		},
+		ExpressionToken{
+			Kind:  COMPARATOR,
+			Value: ">",
+		},
+		ExpressionToken{
+			Kind:  NUMERIC,
+			Value: 1.0,
+		},
+	}
+
+	expression, err := NewEvaluableExpressionFromTokens(tokens)
+	if err != nil {
+		test.Fatalf("Unable to make expression: %v", err)
+	}
+
+	node := expression.AST()
+	if describeNode(node) != "(> foo 1)" {
+		test.Errorf("Unexpected AST: %s", describeNode(node))
+	}
+	if node.Span() != (Span{}) {
+		test.Errorf("Expected an empty span, got %+v", node.Span())
+	}
+}
+
+func runASTTests(astTests []ASTTest, test *testing.T) {
+
+	for _, astTest := range astTests {
+
+		expression, err := NewEvaluableExpressionWithFunctions(astTest.Input, astTest.Functions)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", astTest.Name, err)
+			continue
+		}
+
+		actual := describeNode(expression.AST())
+		if actual != astTest.Expected {
+			test.Errorf("Test '%s' expected AST '%s', got '%s'", astTest.Name, astTest.Expected, actual)
+		}
+	}
+}
+
+/*
+	Writes a node as a short, unambiguous string, for comparing trees.
+*/
+func describeNode(node ASTNode) string {
+
+	switch node.(type) {
+	case *BinaryNode:
+		binary := node.(*BinaryNode)
+		return fmt.Sprintf("(%v %s %s)", binary.Operator, describeNode(binary.Left), describeNode(binary.Right))
+	case *UnaryNode:
+		unary := node.(*UnaryNode)
+		return fmt.Sprintf("(%v %s)", unary.Operator, describeNode(unary.Operand))
+	case *TernaryNode:
+		ternary := node.(*TernaryNode)
+		if ternary.Else == nil {
+			return fmt.Sprintf("(? %s %s)", describeNode(ternary.Condition), describeNode(ternary.Then))
+		}
+		return fmt.Sprintf("(? %s %s %s)", describeNode(ternary.Condition), describeNode(ternary.Then), describeNode(ternary.Else))
+	case *LiteralNode:
+		switch node.(*LiteralNode).Value.(type) {
+		case string:
+			return fmt.Sprintf("'%s'", node.(*LiteralNode).Value)
+		case *regexp.Regexp:
+			return fmt.Sprintf("/%v/", node.(*LiteralNode).Value)
+		case time.Time:
+			return node.(*LiteralNode).Value.(time.Time).Format(time.RFC3339)
+		}
+		return fmt.Sprintf("%v", node.(*LiteralNode).Value)
+	case *VariableNode:
+		return node.(*VariableNode).Name
+	case *FunctionNode:
+		return node.(*FunctionNode).Name + "(" + describeNodes(node.(*FunctionNode).Arguments) + ")"
+	case *AccessorNode:
+		accessor := node.(*AccessorNode)
+		if !accessor.Called {
+			return strings.Join(accessor.Path, ".")
+		}
+		return strings.Join(accessor.Path, ".") + "(" + describeNodes(accessor.Arguments) + ")"
+	case *ArrayNode:
+		return "[" + describeNodes(node.(*ArrayNode).Elements) + "]"
+	}
+	return fmt.Sprintf("<%T>", node)
+}
+
+func describeNodes(nodes []ASTNode) string {
+
+	var descriptions []string
+
+	for _, node := range nodes {
+		descriptions = append(descriptions, describeNode(node))
+	}
+	return strings.Join(descriptions, " ")
+}
@@ -0,0 +1,235 @@
+package govaluate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of evaluating an expression over columns, which should give the same results and errors as evaluating each row by itself.
+*/
+type ColumnTest struct {
+	Name      string
+	Input     string
+	Functions map[string]ExpressionFunction
+	Options   EvaluationOptions
+	Parsing   ParsingOptions
+}
+
+func TestColumnEvaluation(test *testing.T) {
+
+	columnTests := []ColumnTest{
+
+		ColumnTest{
+			Name:  "Arithmetic",
+			Input: "(price * quantity - 1) / 2 % 7 + -price ** 2",
+		},
+		ColumnTest{
+			Name:  "Comparisons",
+			Input: "price > 50 && quantity <= 3 || price == quantity || price != 10",
+		},
+		ColumnTest{
+			Name:  "Strings",
+			Input: "name >= 'm' && name != 'row7' || name < 'b'",
+		},
+		ColumnTest{
+			Name:  "Booleans",
+			Input: "!active || (active && price > 10)",
+		},
+		ColumnTest{
+			Name:  "Concatenation",
+			Input: "name + '-' + price",
+		},
+		ColumnTest{
+			Name:  "Short-circuits avoid type errors",
+			Input: "active && mixed > 1",
+		},
+		ColumnTest{
+			Name:  "Type errors",
+			Input: "mixed > 1",
+		},
+		ColumnTest{
+			Name:  "Ternaries",
+			Input: "active ? price : name",
+		},
+		ColumnTest{
+			Name:  "Ternaries with type errors",
+			Input: "mixed ? 1 : 2",
+		},
+		ColumnTest{
+			Name:  "Coalescence",
+			Input: "optional ?? price",
+		},
+		ColumnTest{
+			Name:  "Regex",
+			Input: "name =~ '[13579]$'",
+		},
+		ColumnTest{
+			Name:  "Membership",
+			Input: "quantity IN (1, 2, 3)",
+		},
+		ColumnTest{
+			Name:  "Accessors",
+			Input: "record.Int + price",
+		},
+		ColumnTest{
+			Name:  "Functions",
+			Input: "twice(price) > quantity",
+			Functions: map[string]ExpressionFunction{
+				"twice": func(arguments ...interface{}) (interface{}, error) {
+					return arguments[0].(float64) * 2, nil
+				},
+			},
+		},
+		ColumnTest{
+			Name:  "Missing values",
+			Input: "price + short",
+		},
+		ColumnTest{
+			Name:  "Missing columns",
+			Input: "price > 1 && missing",
+		},
+		ColumnTest{
+			Name:    "Step limit",
+			Input:   "active || price + price + price > 10",
+			Options: EvaluationOptions{MaxSteps: 6},
+		},
+		ColumnTest{
+			Name:    "String limit",
+			Input:   "name + name",
+			Options: EvaluationOptions{MaxStringLength: 9},
+		},
+		ColumnTest{
+			Name:    "Integers",
+			Input:   "quantity / 2 + price",
+			Parsing: ParsingOptions{NumericMode: INTEGER_NUMERICS},
+		},
+		ColumnTest{
+			Name:    "Decimals",
+			Input:   "price / 3 + 0.1 > quantity",
+			Parsing: ParsingOptions{NumericMode: DECIMAL_NUMERICS},
+		},
+		ColumnTest{
+			Name:  "Constants",
+			Input: "1 + 2",
+		},
+	}
+
+	// more than one chunk of rows.
+	length := columnChunkSize*2 + 7
+	columns := Columns{
+		Numbers: map[string][]float64{
+			"price":    make([]float64, length),
+			"quantity": make([]float64, length),
+			"short":    make([]float64, 100),
+		},
+		Strings: map[string][]string{
+			"name": make([]string, length),
+		},
+		Booleans: map[string][]bool{
+			"active": make([]bool, length),
+		},
+		Values: map[string][]interface{}{
+			"mixed":    make([]interface{}, length),
+			"optional": make([]interface{}, length),
+			"record":   make([]interface{}, length),
+		},
+	}
+
+	for row := 0; row < length; row++ {
+
+		columns.Numbers["price"][row] = float64(row%97) * 1.5
+		columns.Numbers["quantity"][row] = float64(row % 5)
+		columns.Strings["name"][row] = fmt.Sprintf("row%d", row)
+		columns.Booleans["active"][row] = row%3 == 0
+		columns.Values["record"][row] = dummyParameter{Int: row}
+
+		if row%2 == 0 {
+			columns.Values["mixed"][row] = float64(row)
+			columns.Values["optional"][row] = int32(row)
+		} else {
+			columns.Values["mixed"][row] = "text"
+		}
+	}
+	for row := range columns.Numbers["short"] {
+		columns.Numbers["short"][row] = 1
+	}
+
+	for _, columnTest := range columnTests {
+
+		columnTest.Parsing.Functions = columnTest.Functions
+
+		expression, err := NewEvaluableExpressionWithOptions(columnTest.Input, columnTest.Parsing)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", columnTest.Name, err)
+			continue
+		}
+		expression.Options = columnTest.Options
+
+		results, errors := expression.EvalColumns(columns)
+		if len(results) != length || len(errors) != length {
+			test.Errorf("Test '%s' gave %d results and %d errors, expected %d", columnTest.Name, len(results), len(errors), length)
+			continue
+		}
+
+		rows := make([]Parameters, length)
+		for row := range rows {
+			rows[row] = &columnParameters{&columns, row, FLOAT_NUMERICS}
+		}
+
+		batchResults, batchErrors := expression.EvalBatch(rows)
+
+		for row := 0; row < length; row++ {
+
+			expected, expectedErr := expression.Eval(rows[row])
+
+			if !reflect.DeepEqual(results[row], expected) || fmt.Sprint(errors[row]) != fmt.Sprint(expectedErr) {
+				test.Errorf("Test '%s' gave %v (%v) for row %d of columns, expected %v (%v)", columnTest.Name, results[row], errors[row], row, expected, expectedErr)
+				break
+			}
+
+			if !reflect.DeepEqual(batchResults[row], expected) || fmt.Sprint(batchErrors[row]) != fmt.Sprint(expectedErr) {
+				test.Errorf("Test '%s' gave %v (%v) for row %d of batch, expected %v (%v)", columnTest.Name, batchResults[row], batchErrors[row], row, expected, expectedErr)
+				break
+			}
+		}
+	}
+}
+
+func TestBatchEvaluation(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo > 1")
+
+	results, errors := expression.EvalBatch([]Parameters{
+		MapParameters{"foo": 2},
+		MapParameters{"foo": 1},
+		MapParameters{},
+		nil,
+		MapParameters{"foo": "bar"},
+	})
+
+	expectedResults := []interface{}{true, false, nil, nil, nil}
+	if !reflect.DeepEqual(results, expectedResults) {
+		test.Errorf("Expected results %v, got %v", expectedResults, results)
+	}
+
+	expectedErrors := []string{"<nil>", "<nil>", "No parameter 'foo' found.", "No parameter 'foo' found.", "cannot be used with the comparator"}
+	for i, expected := range expectedErrors {
+
+		if !strings.Contains(fmt.Sprint(errors[i]), expected) {
+			test.Errorf("Expected error '%s' for row %d, got '%v'", expected, i, errors[i])
+		}
+	}
+}
+
+func TestEmptyColumns(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo > 1")
+
+	results, errors := expression.EvalColumns(Columns{})
+	if len(results) != 0 || len(errors) != 0 {
+		test.Errorf("Expected no rows, got %v, %v", results, errors)
+	}
+}
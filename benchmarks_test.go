@@ -44,6 +44,28 @@ func BenchmarkFullParse(bench *testing.B) {
 	}
 }
 
+/*
+  Benchmarks loading the same expression as BenchmarkFullParse from a snapshot, rather than parsing it.
+*/
+func BenchmarkFullSnapshotLoad(bench *testing.B) {
+
+	var expressionString string
+
+	expressionString = "2 > 1 &&" +
+		"'something' != 'nothing' || " +
+		"'2014-01-20' < 'Wed Jul  8 23:07:35 MDT 2015' && " +
+		"[escapedVariable name with spaces] <= unescaped\\-variableName &&" +
+		"modifierTest + 1000 / 2 > (80 * 100 % 2)"
+
+	expression, _ := NewEvaluableExpression(expressionString)
+	snapshot, _ := expression.Snapshot()
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		NewEvaluableExpressionFromSnapshot(snapshot, ParsingOptions{})
+	}
+}
+
 /*
   Benchmarks the bare-minimum evaluation time
 */
@@ -249,3 +271,102 @@ func BenchmarkNestedAccessors(bench *testing.B) {
 		expression.Evaluate(fooFailureParameters)
 	}
 }
+
+/*
+  Benchmarks the same expression as BenchmarkComplexExpression, compiled.
+*/
+func BenchmarkCompiledComplexExpression(bench *testing.B) {
+
+	var expressionString string
+
+	expressionString = "2 > 1 &&" +
+		"'something' != 'nothing' || " +
+		"'2014-01-20' < 'Wed Jul  8 23:07:35 MDT 2015' && " +
+		"[escapedVariable name with spaces] <= unescaped\\-variableName &&" +
+		"modifierTest + 1000 / 2 > (80 * 100 % 2)"
+
+	expression, _ := NewEvaluableExpression(expressionString)
+	compiled := expression.Compile()
+	parameters := map[string]interface{}{
+		"escapedVariable name with spaces": 99.0,
+		"unescaped\\-variableName":         90.0,
+		"modifierTest":                     5.0,
+	}
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		compiled.Evaluate(parameters)
+	}
+}
+
+func BenchmarkCompiledParametersModifiers(bench *testing.B) {
+
+	expression, _ := NewEvaluableExpression("(requests_made * requests_succeeded / 100) >= 90")
+	compiled := expression.Compile()
+	parameters := map[string]interface{}{
+		"requests_made":      99.0,
+		"requests_succeeded": 90.0,
+	}
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		compiled.Evaluate(parameters)
+	}
+}
+
+func BenchmarkCompiledSlots(bench *testing.B) {
+
+	expression, _ := NewEvaluableExpression("(requests_made * requests_succeeded / 100) >= 90")
+	compiled := expression.Compile()
+	values := []interface{}{99.0, 90.0}
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		compiled.EvalSlots(values)
+	}
+}
+
+/*
+  Benchmarks evaluating one expression over many rows, one row at a time.
+*/
+func BenchmarkEvaluationBatch(bench *testing.B) {
+
+	expression, _ := NewEvaluableExpression("(requests_made * requests_succeeded / 100) >= 90")
+	rows := make([]Parameters, 1000)
+
+	for i := range rows {
+		rows[i] = MapParameters{
+			"requests_made":      float64(i),
+			"requests_succeeded": float64(i / 2),
+		}
+	}
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		expression.EvalBatch(rows)
+	}
+}
+
+/*
+  Benchmarks evaluating the same rows as BenchmarkEvaluationBatch, as columns.
+*/
+func BenchmarkEvaluationColumns(bench *testing.B) {
+
+	expression, _ := NewEvaluableExpression("(requests_made * requests_succeeded / 100) >= 90")
+	columns := Columns{
+		Numbers: map[string][]float64{
+			"requests_made":      make([]float64, 1000),
+			"requests_succeeded": make([]float64, 1000),
+		},
+	}
+
+	for i := 0; i < 1000; i++ {
+		columns.Numbers["requests_made"][i] = float64(i)
+		columns.Numbers["requests_succeeded"][i] = float64(i / 2)
+	}
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		expression.EvalColumns(columns)
+	}
+}
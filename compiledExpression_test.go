@@ -0,0 +1,153 @@
+package govaluate
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of a compiled expression, which should behave exactly as the expression it was compiled from.
+*/
+type CompiledExpressionTest struct {
+	Name       string
+	Input      string
+	Parameters map[string]interface{}
+}
+
+func TestCompiledStepLimits(test *testing.T) {
+
+	compiledTests := []CompiledExpressionTest{
+
+		CompiledExpressionTest{
+			Name:       "Arithmetic",
+			Input:      "(foo + 1) * -foo / 2",
+			Parameters: map[string]interface{}{"foo": 3},
+		},
+		CompiledExpressionTest{
+			Name:       "Short-circuited AND",
+			Input:      "foo > 5 && (foo + foo + foo > 1) || foo == 3",
+			Parameters: map[string]interface{}{"foo": 3},
+		},
+		CompiledExpressionTest{
+			Name:       "Short-circuited coalescence",
+			Input:      "bar ?? (foo + foo) ?? foo",
+			Parameters: map[string]interface{}{"foo": 3, "bar": nil},
+		},
+		CompiledExpressionTest{
+			Name:       "Skipped ternaries",
+			Input:      "foo < 1 ? foo + foo : (foo > 2 ? 'big' : foo * 2)",
+			Parameters: map[string]interface{}{"foo": 3},
+		},
+		CompiledExpressionTest{
+			Name:       "Functions and arrays",
+			Input:      "foo IN (1, 2, foo + 1) || foostruct.FuncArgStr('x') == 'x'",
+			Parameters: map[string]interface{}{"foo": 3, "foostruct": dummyParameter{}},
+		},
+	}
+
+	// every limit should stop the compiled expression at the same point as the expression itself.
+	for _, compiledTest := range compiledTests {
+
+		expression, err := NewEvaluableExpression(compiledTest.Input)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", compiledTest.Name, err)
+			continue
+		}
+
+		for steps := 1; steps < 30; steps++ {
+
+			expression.Options.MaxSteps = steps
+
+			expected, expectedErr := expression.Evaluate(compiledTest.Parameters)
+			actual, err := expression.Compile().Evaluate(compiledTest.Parameters)
+
+			if expected != actual || expectedErr != err {
+				test.Errorf("Test '%s' with %d steps gave '%v' (%v), expected '%v' (%v)", compiledTest.Name, steps, actual, err, expected, expectedErr)
+			}
+		}
+	}
+}
+
+func TestCompiledShortCircuits(test *testing.T) {
+
+	var calls []string
+
+	functions := map[string]ExpressionFunction{
+		"record": func(arguments ...interface{}) (interface{}, error) {
+			calls = append(calls, arguments[0].(string))
+			return arguments[1], nil
+		},
+	}
+
+	expression, err := NewEvaluableExpressionWithFunctions(
+		"record('a', false) && record('b', true) || "+
+			"(record('c', none) ?? record('d', 1)) == 1 && "+
+			"(record('e', true) ? record('f', 2) : record('g', 3)) == 2 && "+
+			"((record('h', false) ? record('i', 4)) ?? 5) == 5",
+		functions)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	result, err := expression.Compile().Evaluate(map[string]interface{}{"none": nil})
+	if err != nil || result != true {
+		test.Errorf("Expected true, got %v (%v)", result, err)
+	}
+
+	if strings.Join(calls, "") != "acdefh" {
+		test.Errorf("Expected functions 'acdefh' to be called, got '%s'", strings.Join(calls, ""))
+	}
+}
+
+func TestCompiledCancellation(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo + 1 > 2")
+	compiled := expression.Compile()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := compiled.EvalContext(ctx, MapParameters{"foo": 2})
+	if err != context.Canceled {
+		test.Errorf("Expected cancellation error, got %v", err)
+	}
+}
+
+func TestCompiledDeepExpression(test *testing.T) {
+
+	// right-nested parenthesis need a deeper stack than most expressions.
+	input := strings.Repeat("(1 + foo * ", 40) + "1" + strings.Repeat(")", 40)
+
+	expression, err := NewEvaluableExpression(input)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	parameters := map[string]interface{}{"foo": 1}
+
+	expected, _ := expression.Evaluate(parameters)
+	result, err := expression.Compile().Evaluate(parameters)
+
+	if err != nil || result != expected || result != 41.0 {
+		test.Errorf("Expected %v, got %v (%v)", expected, result, err)
+	}
+}
+
+func TestCompiledSettings(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo > 1")
+	compiled := expression.Compile()
+
+	// the compiled expression keeps the settings it was compiled with.
+	expression.ChecksTypes = false
+
+	_, err := compiled.Evaluate(map[string]interface{}{"foo": "bar"})
+	if err == nil {
+		test.Errorf("Expected compiled expression to keep checking types")
+	}
+
+	if compiled.String() != "foo > 1" {
+		test.Errorf("Expected compiled expression to keep its text, got '%s'", compiled.String())
+	}
+}
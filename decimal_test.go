@@ -0,0 +1,158 @@
+package govaluate
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+/*
+	Represents a test of a single Decimal operation, whose result is compared by its exact text.
+*/
+type DecimalTest struct {
+	Name     string
+	Actual   func() (Decimal, error)
+	Expected string
+}
+
+func TestDecimalParsing(test *testing.T) {
+
+	cases := map[string]string{
+		"0":       "0",
+		"1.50":    "1.50",
+		"-0.05":   "-0.05",
+		".5":      "0.5",
+		"1e3":     "1000",
+		"1.5E-3":  "0.0015",
+		"+12.340": "12.340",
+	}
+
+	for input, expected := range cases {
+
+		actual, err := ParseDecimal(input)
+		if err != nil {
+			test.Errorf("Unable to parse '%s': %v", input, err)
+			continue
+		}
+		if actual.String() != expected {
+			test.Errorf("Parsed '%s' as '%s', expected '%s'", input, actual.String(), expected)
+		}
+	}
+
+	for _, input := range []string{"", ".", "-", "1.2.3", "1e", "abc"} {
+
+		_, err := ParseDecimal(input)
+		if err == nil {
+			test.Errorf("Expected an error parsing '%s'", input)
+		}
+	}
+}
+
+func TestDecimalOperations(test *testing.T) {
+
+	decimal := func(text string) Decimal {
+		ret, err := ParseDecimal(text)
+		if err != nil {
+			test.Fatalf("Unable to parse '%s': %v", text, err)
+		}
+		return ret
+	}
+
+	cases := []DecimalTest{
+		DecimalTest{
+			Name:     "Add",
+			Actual:   func() (Decimal, error) { return decimal("0.1").Add(decimal("0.2")), nil },
+			Expected: "0.3",
+		},
+		DecimalTest{
+			Name:     "Sub",
+			Actual:   func() (Decimal, error) { return decimal("1").Sub(decimal("0.01")), nil },
+			Expected: "0.99",
+		},
+		DecimalTest{
+			Name:     "Mul",
+			Actual:   func() (Decimal, error) { return decimal("19.99").Mul(decimal("0.08")), nil },
+			Expected: "1.5992",
+		},
+		DecimalTest{
+			Name:     "Quo half even",
+			Actual:   func() (Decimal, error) { return decimal("0.125").Quo(decimal("1"), 2, big.ToNearestEven) },
+			Expected: "0.12",
+		},
+		DecimalTest{
+			Name:     "Quo half away",
+			Actual:   func() (Decimal, error) { return decimal("0.125").Quo(decimal("1"), 2, big.ToNearestAway) },
+			Expected: "0.13",
+		},
+		DecimalTest{
+			Name:     "Quo negative toward negative infinity",
+			Actual:   func() (Decimal, error) { return decimal("-1").Quo(decimal("3"), 2, big.ToNegativeInf) },
+			Expected: "-0.34",
+		},
+		DecimalTest{
+			Name:     "Quo small negative away from zero",
+			Actual:   func() (Decimal, error) { return decimal("-1").Quo(decimal("300"), 2, big.AwayFromZero) },
+			Expected: "-0.01",
+		},
+		DecimalTest{
+			Name:     "Rem",
+			Actual:   func() (Decimal, error) { return decimal("-7.5").Rem(decimal("2")) },
+			Expected: "-1.5",
+		},
+		DecimalTest{
+			Name:     "Pow",
+			Actual:   func() (Decimal, error) { return decimal("1.1").Pow(3, 4, big.ToNearestEven) },
+			Expected: "1.331",
+		},
+		DecimalTest{
+			Name:     "Negative pow",
+			Actual:   func() (Decimal, error) { return decimal("2").Pow(-3, 4, big.ToNearestEven) },
+			Expected: "0.1250",
+		},
+		DecimalTest{
+			Name:     "Round",
+			Actual:   func() (Decimal, error) { return decimal("2.675").Round(2, big.ToNearestAway), nil },
+			Expected: "2.68",
+		},
+		DecimalTest{
+			Name:     "Float conversion",
+			Actual:   func() (Decimal, error) { return NewDecimalFromFloat(0.1) },
+			Expected: "0.1",
+		},
+	}
+
+	for _, testCase := range cases {
+
+		actual, err := testCase.Actual()
+		if err != nil {
+			test.Errorf("Test '%s' failed: %v", testCase.Name, err)
+			continue
+		}
+		if actual.String() != testCase.Expected {
+			test.Errorf("Test '%s' failed: got '%s', expected '%s'", testCase.Name, actual.String(), testCase.Expected)
+		}
+	}
+
+	_, err := decimal("1").Quo(Decimal{}, 2, big.ToNearestEven)
+	if err == nil {
+		test.Errorf("Expected an error dividing by zero")
+	}
+
+	// these would otherwise recurse forever, or take unbounded time and memory.
+	for _, exponent := range []int64{math.MinInt64, 1000000000, -1000000000} {
+
+		_, err = decimal("10").Pow(exponent, 2, big.ToNearestEven)
+		if err == nil {
+			test.Errorf("Expected an error raising 10 to %d", exponent)
+		}
+	}
+
+	_, err = decimal("-1").Pow(math.MaxInt64, 2, big.ToNearestEven)
+	if err != nil {
+		test.Errorf("Expected -1 to be raised to any power, got %v", err)
+	}
+
+	if decimal("1.0").Cmp(decimal("1.00")) != 0 || decimal("-1").Cmp(decimal("0.5")) != -1 {
+		test.Errorf("Decimal comparison failed")
+	}
+}
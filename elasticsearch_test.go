@@ -0,0 +1,190 @@
+package govaluate
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+/*
+	Represents a test of creating an Elasticsearch query from an expression.
+*/
+type ElasticsearchQueryTest struct {
+	Name     string
+	Input    string
+	Expected map[string]interface{}
+}
+
+func TestElasticsearchQueries(test *testing.T) {
+
+	testCases := []ElasticsearchQueryTest{
+
+		ElasticsearchQueryTest{
+
+			Name:     "Term",
+			Input:    "name == 'bob'",
+			Expected: document{"term": document{"name": "bob"}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Not equal",
+			Input:    "name != 'bob'",
+			Expected: document{"bool": document{"must_not": list{document{"term": document{"name": "bob"}}}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Ranges",
+			Input: "age >= 18 && age < 65 && 100 > score",
+			Expected: document{"bool": document{"must": list{
+				document{"range": document{"age": document{"gte": 18.0}}},
+				document{"range": document{"age": document{"lt": 65.0}}},
+				document{"range": document{"score": document{"lt": 100.0}}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Negative numbers",
+			Input:    "balance <= -5",
+			Expected: document{"range": document{"balance": document{"lte": -5.0}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Should",
+			Input: "role == 'admin' || role == 'owner' || active",
+			Expected: document{"bool": document{
+				"should": list{
+					document{"term": document{"role": "admin"}},
+					document{"term": document{"role": "owner"}},
+					document{"term": document{"active": true}},
+				},
+				"minimum_should_match": 1,
+			}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Must and should",
+			Input: "active && (role == 'admin' || !(age < 18))",
+			Expected: document{"bool": document{"must": list{
+				document{"term": document{"active": true}},
+				document{"bool": document{
+					"should": list{
+						document{"term": document{"role": "admin"}},
+						document{"bool": document{"must_not": list{document{"range": document{"age": document{"lt": 18.0}}}}}},
+					},
+					"minimum_should_match": 1,
+				}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Regexp",
+			Input: "name =~ 'a.*' && name !~ '.*z'",
+			Expected: document{"bool": document{"must": list{
+				document{"regexp": document{"name": "a.*"}},
+				document{"bool": document{"must_not": list{document{"regexp": document{"name": ".*z"}}}}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Terms",
+			Input:    "status IN ('new', 'open')",
+			Expected: document{"terms": document{"status": list{"new", "open"}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Object fields and times",
+			Input: "user.Address.City == 'Paris' && created > '2014-07-04T00:00:00Z'",
+			Expected: document{"bool": document{"must": list{
+				document{"term": document{"user.Address.City": "Paris"}},
+				document{"range": document{"created": document{"gt": "2014-07-04T00:00:00Z"}}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Constants",
+			Input:    "true || false",
+			Expected: document{"bool": document{"should": list{document{"match_all": document{}}, document{"match_none": document{}}}, "minimum_should_match": 1}},
+		},
+	}
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpression(testCase.Input)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", testCase.Name, err)
+			continue
+		}
+
+		actual, err := expression.ToElasticsearchQuery()
+		if err != nil {
+			test.Errorf("Test '%s' failed to create query: %v", testCase.Name, err)
+			continue
+		}
+
+		expected := normalizeDocument(testCase.Expected)
+		if !reflect.DeepEqual(actual, expected) {
+			test.Errorf("Test '%s' expected %v, got %v", testCase.Name, expected, actual)
+		}
+
+		_, err = json.Marshal(actual)
+		if err != nil {
+			test.Errorf("Test '%s' created a query which can't be marshalled: %v", testCase.Name, err)
+		}
+	}
+}
+
+func TestElasticsearchQueryFailures(test *testing.T) {
+
+	testCases := []QueryTest{
+
+		QueryTest{
+
+			Name:     "Comparing fields",
+			Input:    "spent > budget",
+			Expected: "Unable to write '>' in an Elasticsearch query, which can only compare a field with a constant",
+		},
+		QueryTest{
+
+			Name:     "Arithmetic",
+			Input:    "price * 2 > 10",
+			Expected: "Unable to write '>' in an Elasticsearch query, which can only compare a field with a constant",
+		},
+		QueryTest{
+
+			Name:     "Ternary",
+			Input:    "vip ? true : false",
+			Expected: "Unable to write ':' in an Elasticsearch query",
+		},
+		QueryTest{
+
+			Name:  "Function",
+			Input: "enabled()",
+			Functions: map[string]ExpressionFunction{
+				"enabled": func(arguments ...interface{}) (interface{}, error) {
+					return true, nil
+				},
+			},
+			Expected: "Unable to write function 'enabled' in an Elasticsearch query",
+		},
+		QueryTest{
+
+			Name:     "Non-boolean constant",
+			Input:    "'foo'",
+			Expected: "Unable to use the constant 'foo' as an Elasticsearch query",
+		},
+	}
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpressionWithFunctions(testCase.Input, testCase.Functions)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", testCase.Name, err)
+			continue
+		}
+
+		_, err = expression.ToElasticsearchQuery()
+		if err == nil || err.Error() != testCase.Expected {
+			test.Errorf("Test '%s' expected error '%s', got '%v'", testCase.Name, testCase.Expected, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package govaluate
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type contextKey string
+
+type dummyContextParameter struct{}
+
+func (this dummyContextParameter) Lookup(ctx context.Context, key string) string {
+	return ctx.Value(contextKey(key)).(string)
+}
+
+func TestCancelledContextEvaluation(test *testing.T) {
+
+	expression, err := NewEvaluableExpression("foo + 1 > 2")
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = expression.EvalContext(ctx, MapParameters{"foo": 2})
+	if err != context.Canceled {
+		test.Errorf("Expected cancellation error, got %v", err)
+	}
+}
+
+func TestContextDeadlineStopsFunctions(test *testing.T) {
+
+	var calls int
+
+	functions := map[string]ContextExpressionFunction{
+		"slow": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+			calls++
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	expression, err := NewEvaluableExpressionWithContextFunctions("slow() + slow()", functions)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = expression.EvalContext(ctx, nil)
+	if err != context.DeadlineExceeded {
+		test.Errorf("Expected deadline error, got %v", err)
+	}
+	if calls != 1 {
+		test.Errorf("Expected evaluation to stop after the first call, but function was called %d times", calls)
+	}
+}
+
+func TestContextPassedToFunctions(test *testing.T) {
+
+	functions := map[string]ContextExpressionFunction{
+		"lookup": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+			return ctx.Value(contextKey(arguments[0].(string))), nil
+		},
+	}
+
+	expression, err := NewEvaluableExpressionWithContextFunctions("lookup('tenant') + '-' + foo.Lookup('region')", functions)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey("tenant"), "acme")
+	ctx = context.WithValue(ctx, contextKey("region"), "emea")
+
+	result, err := expression.EvalContext(ctx, MapParameters{"foo": dummyContextParameter{}})
+	if err != nil {
+		test.Fatalf("Unexpected evaluation error: %v", err)
+	}
+	if result != "acme-emea" {
+		test.Errorf("Expected 'acme-emea', got '%v'", result)
+	}
+}
@@ -101,6 +101,49 @@ func TestNilParameterUsage(test *testing.T) {
 func TestModifierTyping(test *testing.T) {
 
 	evaluationTests := []EvaluationFailureTest{
+		EvaluationFailureTest{
+
+			Name:     "PLUS time to time",
+			Input:    "'2014-01-02' + '2014-01-01'",
+			Expected: INVALID_MODIFIER_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "MINUS duration to time",
+			Input:    "15m - '2014-01-01'",
+			Expected: INVALID_MODIFIER_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "MULTIPLY duration to duration",
+			Input:    "15m * 15m",
+			Expected: INVALID_MODIFIER_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "DIVIDE number by duration",
+			Input:    "15 / 15m",
+			Expected: INVALID_MODIFIER_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "PLUS time to number",
+			Input:    "'2014-01-02' + 86400",
+			Expected: INVALID_MODIFIER_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:       "DIVIDE duration by zero",
+			Input:      "1h / zero",
+			Parameters: map[string]interface{}{"zero": 0.0},
+			Expected:   "Duration of +Inf nanoseconds is out of range",
+		},
+		EvaluationFailureTest{
+
+			Name:     "MULTIPLY duration out of range",
+			Input:    "1h * 10 ** 20",
+			Expected: "Duration of 3.6e+32 nanoseconds is out of range",
+		},
 		EvaluationFailureTest{
 
 			Name:     "PLUS literal number to literal bool",
@@ -253,6 +296,49 @@ func TestLogicalOperatorTyping(test *testing.T) {
 func TestComparatorTyping(test *testing.T) {
 
 	evaluationTests := []EvaluationFailureTest{
+		EvaluationFailureTest{
+
+			Name:     "GT time to duration",
+			Input:    "'2014-01-02' > 15m",
+			Expected: INVALID_COMPARATOR_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "GT duration to number",
+			Input:    "15m > 60",
+			Expected: INVALID_COMPARATOR_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "LTE number to duration",
+			Input:    "900 <= 15m",
+			Expected: INVALID_COMPARATOR_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "EQ duration to number",
+			Input:    "15m == 900",
+			Expected: INVALID_COMPARATOR_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:       "NEQ number parameter to duration",
+			Input:      "timeout != 15m",
+			Parameters: map[string]interface{}{"timeout": 900},
+			Expected:   INVALID_COMPARATOR_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "IN duration to numbers",
+			Input:    "15m in (60, 900)",
+			Expected: INVALID_COMPARATOR_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "GT time to string",
+			Input:    "'2014-01-02' > 'foo'",
+			Expected: INVALID_COMPARATOR_TYPES,
+		},
 		EvaluationFailureTest{
 
 			Name:     "GT literal bool to literal bool",
@@ -539,5 +625,14 @@ func runEvaluationFailureTests(evaluationTests []EvaluationFailureTest, test *te
 			test.Fail()
 			continue
 		}
+
+		_, compiledErr := expression.Compile().Evaluate(testCase.Parameters)
+
+		if compiledErr == nil || compiledErr.Error() != err.Error() {
+
+			test.Logf("Test '%s' failed", testCase.Name)
+			test.Logf("Got compiled error: '%v', expected '%s'", compiledErr, err)
+			test.Fail()
+		}
 	}
 }
@@ -0,0 +1,138 @@
+package govaluate
+
+import (
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of an expression which should be stopped by one of its evaluation limits.
+*/
+type EvaluationLimitTest struct {
+	Name       string
+	Input      string
+	Options    EvaluationOptions
+	Functions  map[string]ExpressionFunction
+	Parameters map[string]interface{}
+	Expected   EvaluationLimit
+}
+
+// functions which can build strings and arrays larger than their arguments.
+var limitFunctions = map[string]ExpressionFunction{
+	"repeat": func(arguments ...interface{}) (interface{}, error) {
+		return strings.Repeat(arguments[0].(string), int(arguments[1].(float64))), nil
+	},
+	"zeros": func(arguments ...interface{}) (interface{}, error) {
+		return make([]interface{}, int(arguments[0].(float64))), nil
+	},
+}
+
+func TestEvaluationLimits(test *testing.T) {
+
+	limitTests := []EvaluationLimitTest{
+
+		EvaluationLimitTest{
+
+			Name:       "Step limit",
+			Input:      "foo + foo + foo + foo",
+			Options:    EvaluationOptions{MaxSteps: 5},
+			Parameters: map[string]interface{}{"foo": 1},
+			Expected:   STEP_LIMIT,
+		},
+		EvaluationLimitTest{
+
+			Name:       "String concatenation limit",
+			Input:      "foo + foo",
+			Options:    EvaluationOptions{MaxStringLength: 5},
+			Parameters: map[string]interface{}{"foo": "abc"},
+			Expected:   STRING_LENGTH_LIMIT,
+		},
+		EvaluationLimitTest{
+
+			Name:       "Mixed-type concatenation limit",
+			Input:      "foo + 12345",
+			Options:    EvaluationOptions{MaxStringLength: 5},
+			Parameters: map[string]interface{}{"foo": "abc"},
+			Expected:   STRING_LENGTH_LIMIT,
+		},
+		EvaluationLimitTest{
+
+			Name:       "Array length limit",
+			Input:      "foo in (1, 2, 3, 4)",
+			Options:    EvaluationOptions{MaxArrayLength: 3},
+			Parameters: map[string]interface{}{"foo": 1},
+			Expected:   ARRAY_LENGTH_LIMIT,
+		},
+		EvaluationLimitTest{
+
+			Name:       "Function string limit",
+			Input:      "repeat(repeat(foo, 10), 10)",
+			Options:    EvaluationOptions{MaxStringLength: 100},
+			Functions:  limitFunctions,
+			Parameters: map[string]interface{}{"foo": "aaaaaaaaaaaaaaaaaaaa"},
+			Expected:   STRING_LENGTH_LIMIT,
+		},
+		EvaluationLimitTest{
+
+			Name:      "Function array limit",
+			Input:     "0 IN zeros(4)",
+			Options:   EvaluationOptions{MaxArrayLength: 3},
+			Functions: limitFunctions,
+			Expected:  ARRAY_LENGTH_LIMIT,
+		},
+	}
+
+	for _, limitTest := range limitTests {
+
+		expression, err := NewEvaluableExpressionWithFunctions(limitTest.Input, limitTest.Functions)
+		if err != nil {
+			test.Logf("Test '%s' failed to parse: %s", limitTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		expression.Options = limitTest.Options
+
+		_, err = expression.Evaluate(limitTest.Parameters)
+
+		limitErr, ok := err.(LimitExceededError)
+		if !ok {
+			test.Logf("Test '%s' failed", limitTest.Name)
+			test.Logf("Expected LimitExceededError, got '%v'", err)
+			test.Fail()
+			continue
+		}
+
+		if limitErr.Limit != limitTest.Expected {
+			test.Logf("Test '%s' failed", limitTest.Name)
+			test.Logf("Expected limit '%v' to be exceeded, got '%v'", limitTest.Expected, limitErr.Limit)
+			test.Fail()
+			continue
+		}
+
+		_, compiledErr := expression.Compile().Evaluate(limitTest.Parameters)
+		if compiledErr != err {
+			test.Logf("Test '%s' failed", limitTest.Name)
+			test.Logf("Expected compiled expression to give '%v', got '%v'", err, compiledErr)
+			test.Fail()
+		}
+	}
+}
+
+func TestEvaluationWithinLimits(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo + 'bar' == 'foobar' && 1 in (1, 2, 3)")
+	expression.Options = EvaluationOptions{
+		MaxSteps:        20,
+		MaxStringLength: 6,
+		MaxArrayLength:  3,
+	}
+
+	result, err := expression.Evaluate(map[string]interface{}{"foo": "foo"})
+	if err != nil {
+		test.Fatalf("Unexpected evaluation error: %v", err)
+	}
+	if result != true {
+		test.Errorf("Expected true, got %v", result)
+	}
+}
@@ -1,12 +1,15 @@
 package govaluate
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 )
 
 const (
@@ -15,20 +18,41 @@ const (
 	comparatorErrorFormat string = "Value '%v' cannot be used with the comparator '%v', it is not a number"
 	ternaryErrorFormat    string = "Value '%v' cannot be used with the ternary operator '%v', it is not a bool"
 	prefixErrorFormat     string = "Value '%v' cannot be used with the prefix '%v'"
+	functionErrorFormat   string = "Unable to run function '%v': %v"
+	accessorErrorFormat   string = "Unable to access parameter field or method '%v': %v"
+
+	integerDivisionByZero string = "Integer division by zero"
+	decimalDivisionByZero string = "Decimal division by zero"
+	durationOutOfRange    string = "Duration of %v nanoseconds is out of range"
 )
 
 type evaluationOperator func(left interface{}, right interface{}, parameters Parameters) (interface{}, error)
+type contextEvaluationOperator func(ctx context.Context, left interface{}, right interface{}, parameters Parameters) (interface{}, error)
 type stageTypeCheck func(value interface{}) bool
 type stageCombinedTypeCheck func(left interface{}, right interface{}) bool
 
 type evaluationStage struct {
 	symbol OperatorSymbol
 
+	// the token this stage was planned from, and its source, used to say where errors happened.
+	token  ExpressionToken
+	source tokenSource
+
+	// for stages planned from parenthesis, the closing one.
+	closingToken  ExpressionToken
+	closingSource tokenSource
+
+	// where [token] and [closingToken] are in the expression's tokens, if the stage has them.
+	tokenIndex, closingIndex int
+
 	leftStage, rightStage *evaluationStage
 
 	// the operation that will be used to evaluate this stage (such as adding [left] to [right] and return the result)
 	operator evaluationOperator
 
+	// if specified, will be used instead of "operator". Used by stages which need the evaluation's context, such as functions and accessors.
+	contextOperator contextEvaluationOperator
+
 	// ensures that both left and right values are appropriate for this stage. Returns an error if they aren't operable.
 	leftTypeCheck  stageTypeCheck
 	rightTypeCheck stageTypeCheck
@@ -57,7 +81,14 @@ func (this *evaluationStage) swapWith(other *evaluationStage) {
 func (this *evaluationStage) setToNonStage(other evaluationStage) {
 
 	this.symbol = other.symbol
+	this.token = other.token
+	this.source = other.source
+	this.closingToken = other.closingToken
+	this.closingSource = other.closingSource
+	this.tokenIndex = other.tokenIndex
+	this.closingIndex = other.closingIndex
 	this.operator = other.operator
+	this.contextOperator = other.contextOperator
 	this.leftTypeCheck = other.leftTypeCheck
 	this.rightTypeCheck = other.rightTypeCheck
 	this.typeCheck = other.typeCheck
@@ -93,51 +124,201 @@ func addStage(left interface{}, right interface{}, parameters Parameters) (inter
 		return fmt.Sprintf("%v%v", left, right), nil
 	}
 
-	return left.(float64) + right.(float64), nil
+	switch left.(type) {
+	case time.Time:
+		return left.(time.Time).Add(right.(time.Duration)), nil
+	case time.Duration:
+		if isTime(right) {
+			return right.(time.Time).Add(left.(time.Duration)), nil
+		}
+		return left.(time.Duration) + right.(time.Duration), nil
+	}
+
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return left.(int64) + right.(int64), nil
+	case decimalRepresentation:
+		return decimalOperation(left, right, Decimal.Add)
+	}
+	return toFloat64(left) + toFloat64(right), nil
 }
 func subtractStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	return left.(float64) - right.(float64), nil
+	switch left.(type) {
+	case time.Time:
+		if isTime(right) {
+			return left.(time.Time).Sub(right.(time.Time)), nil
+		}
+		return left.(time.Time).Add(-right.(time.Duration)), nil
+	case time.Duration:
+		return left.(time.Duration) - right.(time.Duration), nil
+	}
+
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return left.(int64) - right.(int64), nil
+	case decimalRepresentation:
+		return decimalOperation(left, right, Decimal.Sub)
+	}
+	return toFloat64(left) - toFloat64(right), nil
 }
 func multiplyStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	return left.(float64) * right.(float64), nil
+	if isDuration(left) {
+		return scaleDuration(left.(time.Duration), right)
+	}
+	if isDuration(right) {
+		return scaleDuration(right.(time.Duration), left)
+	}
+
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return left.(int64) * right.(int64), nil
+	case decimalRepresentation:
+		return decimalOperation(left, right, Decimal.Mul)
+	}
+	return toFloat64(left) * toFloat64(right), nil
 }
-func divideStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	return left.(float64) / right.(float64), nil
+
+var divideStage = makeDivideStage(defaultDecimalPrecision, big.ToNearestEven)
+var exponentStage = makeExponentStage(defaultDecimalPrecision, big.ToNearestEven)
+
+/*
+	Division of decimals needs to know how many digits to keep, and how to round the rest.
+	Those are given by the ParsingOptions of an expression, so expressions using DECIMAL_NUMERICS get their own division operators.
+*/
+func makeDivideStage(precision int, rounding big.RoundingMode) evaluationOperator {
+
+	return func(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+		if isDuration(left) {
+			return divideDuration(left.(time.Duration), right)
+		}
+
+		switch findRepresentation(left, right) {
+		case integerRepresentation:
+			if right.(int64) == 0 {
+				return nil, errors.New(integerDivisionByZero)
+			}
+			return left.(int64) / right.(int64), nil
+		case decimalRepresentation:
+			leftDecimal, rightDecimal, err := decimalOperands(left, right)
+			if err != nil {
+				return nil, err
+			}
+			return decimalResult(leftDecimal.Quo(rightDecimal, precision, rounding))
+		}
+		return toFloat64(left) / toFloat64(right), nil
+	}
 }
-func exponentStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	return math.Pow(left.(float64), right.(float64)), nil
+func makeExponentStage(precision int, rounding big.RoundingMode) evaluationOperator {
+
+	return func(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+		switch findRepresentation(left, right) {
+		case integerRepresentation:
+			if right.(int64) >= 0 {
+				return integerPow(left.(int64), right.(int64)), nil
+			}
+		case decimalRepresentation:
+			leftDecimal, rightDecimal, err := decimalOperands(left, right)
+			if err != nil {
+				return nil, err
+			}
+
+			exponent, fits := rightDecimal.Int64()
+			if fits && rightDecimal.IsInteger() {
+				return decimalResult(leftDecimal.Pow(exponent, precision, rounding))
+			}
+
+			// there's no exact answer for fractional powers, so use the nearest float.
+			return decimalResult(NewDecimalFromFloat(math.Pow(leftDecimal.Float64(), rightDecimal.Float64())))
+		}
+		return math.Pow(toFloat64(left), toFloat64(right)), nil
+	}
 }
 func modulusStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	return math.Mod(left.(float64), right.(float64)), nil
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		if right.(int64) == 0 {
+			return nil, errors.New(integerDivisionByZero)
+		}
+		return left.(int64) % right.(int64), nil
+	case decimalRepresentation:
+		leftDecimal, rightDecimal, err := decimalOperands(left, right)
+		if err != nil {
+			return nil, err
+		}
+		return decimalResult(leftDecimal.Rem(rightDecimal))
+	}
+	return math.Mod(toFloat64(left), toFloat64(right)), nil
 }
 func gteStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
 	if isString(left) && isString(right) {
 		return boolIface(left.(string) >= right.(string)), nil
 	}
-	return boolIface(left.(float64) >= right.(float64)), nil
+	if isTemporal(left) || isTemporal(right) {
+		return boolIface(compareTemporal(left, right) >= 0), nil
+	}
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return boolIface(left.(int64) >= right.(int64)), nil
+	case decimalRepresentation:
+		return decimalComparison(left, right, func(comparison int) bool { return comparison >= 0 })
+	}
+	return boolIface(toFloat64(left) >= toFloat64(right)), nil
 }
 func gtStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
 	if isString(left) && isString(right) {
 		return boolIface(left.(string) > right.(string)), nil
 	}
-	return boolIface(left.(float64) > right.(float64)), nil
+	if isTemporal(left) || isTemporal(right) {
+		return boolIface(compareTemporal(left, right) > 0), nil
+	}
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return boolIface(left.(int64) > right.(int64)), nil
+	case decimalRepresentation:
+		return decimalComparison(left, right, func(comparison int) bool { return comparison > 0 })
+	}
+	return boolIface(toFloat64(left) > toFloat64(right)), nil
 }
 func lteStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
 	if isString(left) && isString(right) {
 		return boolIface(left.(string) <= right.(string)), nil
 	}
-	return boolIface(left.(float64) <= right.(float64)), nil
+	if isTemporal(left) || isTemporal(right) {
+		return boolIface(compareTemporal(left, right) <= 0), nil
+	}
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return boolIface(left.(int64) <= right.(int64)), nil
+	case decimalRepresentation:
+		return decimalComparison(left, right, func(comparison int) bool { return comparison <= 0 })
+	}
+	return boolIface(toFloat64(left) <= toFloat64(right)), nil
 }
 func ltStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
 	if isString(left) && isString(right) {
 		return boolIface(left.(string) < right.(string)), nil
 	}
-	return boolIface(left.(float64) < right.(float64)), nil
+	if isTemporal(left) || isTemporal(right) {
+		return boolIface(compareTemporal(left, right) < 0), nil
+	}
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return boolIface(left.(int64) < right.(int64)), nil
+	case decimalRepresentation:
+		return decimalComparison(left, right, func(comparison int) bool { return comparison < 0 })
+	}
+	return boolIface(toFloat64(left) < toFloat64(right)), nil
 }
 func equalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	if equal, applies := semanticEqual(left, right); applies {
+		return boolIface(equal), nil
+	}
 	return boolIface(reflect.DeepEqual(left, right)), nil
 }
 func notEqualStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	if equal, applies := semanticEqual(left, right); applies {
+		return boolIface(!equal), nil
+	}
 	return boolIface(!reflect.DeepEqual(left, right)), nil
 }
 func andStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
@@ -147,12 +328,26 @@ func orStage(left interface{}, right interface{}, parameters Parameters) (interf
 	return boolIface(left.(bool) || right.(bool)), nil
 }
 func negateStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	switch right.(type) {
+	case int64:
+		return -right.(int64), nil
+	case Decimal:
+		return right.(Decimal).Neg(), nil
+	case time.Duration:
+		return -right.(time.Duration), nil
+	}
 	return -right.(float64), nil
 }
 func invertStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
 	return boolIface(!right.(bool)), nil
 }
 func bitwiseNotStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	switch right.(type) {
+	case int64:
+		return ^right.(int64), nil
+	case Decimal:
+		return NewDecimalFromInt(^toInt64(right)), nil
+	}
 	return float64(^int64(right.(float64))), nil
 }
 func ternaryIfStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
@@ -197,19 +392,67 @@ func notRegexStage(left interface{}, right interface{}, parameters Parameters) (
 }
 
 func bitwiseOrStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	return float64(int64(left.(float64)) | int64(right.(float64))), nil
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return left.(int64) | right.(int64), nil
+	case decimalRepresentation:
+		return NewDecimalFromInt(toInt64(left) | toInt64(right)), nil
+	}
+	return float64(toInt64(left) | toInt64(right)), nil
 }
 func bitwiseAndStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	return float64(int64(left.(float64)) & int64(right.(float64))), nil
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return left.(int64) & right.(int64), nil
+	case decimalRepresentation:
+		return NewDecimalFromInt(toInt64(left) & toInt64(right)), nil
+	}
+	return float64(toInt64(left) & toInt64(right)), nil
 }
 func bitwiseXORStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	return float64(int64(left.(float64)) ^ int64(right.(float64))), nil
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return left.(int64) ^ right.(int64), nil
+	case decimalRepresentation:
+		return NewDecimalFromInt(toInt64(left) ^ toInt64(right)), nil
+	}
+	return float64(toInt64(left) ^ toInt64(right)), nil
 }
 func leftShiftStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	return float64(uint64(left.(float64)) << uint64(right.(float64))), nil
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return left.(int64) << uint64(right.(int64)), nil
+	case decimalRepresentation:
+		return NewDecimalFromInt(toInt64(left) << uint64(toInt64(right))), nil
+	}
+	return float64(uint64(toInt64(left)) << uint64(toInt64(right))), nil
 }
 func rightShiftStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
-	return float64(uint64(left.(float64)) >> uint64(right.(float64))), nil
+	switch findRepresentation(left, right) {
+	case integerRepresentation:
+		return left.(int64) >> uint64(right.(int64)), nil
+	case decimalRepresentation:
+		return NewDecimalFromInt(toInt64(left) >> uint64(toInt64(right))), nil
+	}
+	return float64(uint64(toInt64(left)) >> uint64(toInt64(right))), nil
+}
+
+/*
+	Raises [base] to the non-negative power [exponent], by squaring.
+	Overflows wrap around, the same as any other int64 arithmetic.
+*/
+func integerPow(base int64, exponent int64) int64 {
+
+	var ret int64 = 1
+
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			ret *= base
+		}
+		base *= base
+		exponent >>= 1
+	}
+	return ret
 }
 
 func makeParameterStage(parameterName string) evaluationOperator {
@@ -247,6 +490,23 @@ func makeFunctionStage(function ExpressionFunction) evaluationOperator {
 	}
 }
 
+func makeContextFunctionStage(function ContextExpressionFunction) contextEvaluationOperator {
+
+	return func(ctx context.Context, left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+		if right == nil {
+			return function(ctx)
+		}
+
+		switch right.(type) {
+		case []interface{}:
+			return function(ctx, right.([]interface{})...)
+		default:
+			return function(ctx, right)
+		}
+	}
+}
+
 func typeConvertParam(p reflect.Value, t reflect.Type) (ret reflect.Value, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -289,11 +549,22 @@ func typeConvertParams(method reflect.Value, params []reflect.Value) ([]reflect.
 	return params, nil
 }
 
-func makeAccessorStage(pair []string) evaluationOperator {
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+/*
+	Returns true if the given [method] expects a context.Context as its first argument.
+*/
+func acceptsContext(method reflect.Value) bool {
+
+	methodType := method.Type()
+	return methodType.NumIn() > 0 && methodType.In(0) == contextType
+}
+
+func makeAccessorStage(pair []string) contextEvaluationOperator {
 
 	reconstructed := strings.Join(pair, ".")
 
-	return func(left interface{}, right interface{}, parameters Parameters) (ret interface{}, err error) {
+	return func(ctx context.Context, left interface{}, right interface{}, parameters Parameters) (ret interface{}, err error) {
 
 		var params []reflect.Value
 
@@ -315,6 +586,12 @@ func makeAccessorStage(pair []string) evaluationOperator {
 
 		for i := 1; i < len(pair); i++ {
 
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			default:
+			}
+
 			coreValue := reflect.ValueOf(value)
 
 			var corePtrVal reflect.Value
@@ -364,6 +641,10 @@ func makeAccessorStage(pair []string) evaluationOperator {
 				params = []reflect.Value{reflect.ValueOf(right.(interface{}))}
 			}
 
+			if acceptsContext(method) {
+				params = append([]reflect.Value{reflect.ValueOf(&ctx).Elem()}, params...)
+			}
+
 			params, err = typeConvertParams(method, params)
 
 			if err != nil {
@@ -399,7 +680,6 @@ func makeAccessorStage(pair []string) evaluationOperator {
 			return nil, errors.New("Method call '" + pair[0] + "." + pair[1] + "' did not return either one value, or a value and an error. Cannot interpret meaning.")
 		}
 
-		value = castToFloat64(value)
 		return value, nil
 	}
 }
@@ -424,6 +704,12 @@ func inStage(left interface{}, right interface{}, parameters Parameters) (interf
 		if left == value {
 			return true, nil
 		}
+		if !equalityTypeCheck(left, value) {
+			return nil, makeTypeError(left, IN, comparatorErrorFormat)
+		}
+		if equal, applies := semanticEqual(left, value); applies && equal {
+			return true, nil
+		}
 	}
 	return false, nil
 }
@@ -466,13 +752,306 @@ func isFloat64(value interface{}) bool {
 	return false
 }
 
+func isInt64(value interface{}) bool {
+	switch value.(type) {
+	case int64:
+		return true
+	}
+	return false
+}
+
+func isDecimal(value interface{}) bool {
+	switch value.(type) {
+	case Decimal:
+		return true
+	}
+	return false
+}
+
+func isTime(value interface{}) bool {
+	switch value.(type) {
+	case time.Time:
+		return true
+	}
+	return false
+}
+
+func isDuration(value interface{}) bool {
+	switch value.(type) {
+	case time.Duration:
+		return true
+	}
+	return false
+}
+
+func isTemporal(value interface{}) bool {
+	return isTime(value) || isDuration(value)
+}
+
+func isNumberOrDuration(value interface{}) bool {
+	return isNumber(value) || isDuration(value)
+}
+
+/*
+	Numbers may be float64, int64, or Decimal, depending on the NumericMode of the expression.
+*/
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case float64:
+		return true
+	case int64:
+		return true
+	case Decimal:
+		return true
+	}
+	return false
+}
+
+/*
+	Represents the way an operator will treat a pair of numbers.
+*/
+type numericRepresentation int
+
+const (
+	floatRepresentation numericRepresentation = iota
+	integerRepresentation
+	decimalRepresentation
+)
+
+/*
+	Finds the representation that an operator should use for [left] and [right].
+	If either side is a Decimal, both are treated as Decimals. If both are int64, they're treated as integers.
+	Otherwise, they're treated as float64.
+*/
+func findRepresentation(left interface{}, right interface{}) numericRepresentation {
+
+	if isDecimal(left) || isDecimal(right) {
+		return decimalRepresentation
+	}
+	if isInt64(left) && isInt64(right) {
+		return integerRepresentation
+	}
+	return floatRepresentation
+}
+
+/*
+	Returns true if both sides are numbers, but can't be compared with reflect.DeepEqual.
+	That's either because they're of different types, or because they're Decimals (where 1.0 and 1.00 are equal).
+*/
+func needsNumericEquality(left interface{}, right interface{}) bool {
+
+	if !isNumber(left) || !isNumber(right) {
+		return false
+	}
+	return isDecimal(left) || isDecimal(right) || isInt64(left) != isInt64(right)
+}
+
+/*
+	Some values are equal even though reflect.DeepEqual says they aren't;
+	numbers of different representations, times which are the same instant in different locations,
+	and times compared to numbers (for compatibility, the same as by the other comparators, see compareTemporal).
+	Returns whether or not [left] and [right] are such values (through the second return), and if so, whether they're equal.
+*/
+func semanticEqual(left interface{}, right interface{}) (bool, bool) {
+
+	if needsNumericEquality(left, right) {
+		return numericEqual(left, right), true
+	}
+	if isTime(left) && isTime(right) {
+		return left.(time.Time).Equal(right.(time.Time)), true
+	}
+	if (isTime(left) && isNumber(right)) || (isNumber(left) && isTime(right)) {
+		return compareTemporal(left, right) == 0, true
+	}
+	return false, false
+}
+
+func numericEqual(left interface{}, right interface{}) bool {
+
+	if findRepresentation(left, right) == decimalRepresentation {
+
+		leftDecimal, rightDecimal, err := decimalOperands(left, right)
+		return err == nil && leftDecimal.Cmp(rightDecimal) == 0
+	}
+	return toFloat64(left) == toFloat64(right)
+}
+
+func toFloat64(value interface{}) float64 {
+	switch value.(type) {
+	case int64:
+		return float64(value.(int64))
+	case Decimal:
+		return value.(Decimal).Float64()
+	}
+	return value.(float64)
+}
+
+func toInt64(value interface{}) int64 {
+	switch value.(type) {
+	case float64:
+		return int64(value.(float64))
+	case Decimal:
+		ret, _ := value.(Decimal).Int64()
+		return ret
+	}
+	return value.(int64)
+}
+
+func toDecimal(value interface{}) (Decimal, error) {
+	switch value.(type) {
+	case int64:
+		return NewDecimalFromInt(value.(int64)), nil
+	case float64:
+		return NewDecimalFromFloat(value.(float64))
+	}
+	return value.(Decimal), nil
+}
+
+func decimalOperands(left interface{}, right interface{}) (Decimal, Decimal, error) {
+
+	leftDecimal, err := toDecimal(left)
+	if err != nil {
+		return Decimal{}, Decimal{}, err
+	}
+
+	rightDecimal, err := toDecimal(right)
+	if err != nil {
+		return Decimal{}, Decimal{}, err
+	}
+	return leftDecimal, rightDecimal, nil
+}
+
+func decimalOperation(left interface{}, right interface{}, operation func(Decimal, Decimal) Decimal) (interface{}, error) {
+
+	leftDecimal, rightDecimal, err := decimalOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return operation(leftDecimal, rightDecimal), nil
+}
+
+func decimalComparison(left interface{}, right interface{}, comparator func(int) bool) (interface{}, error) {
+
+	leftDecimal, rightDecimal, err := decimalOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return boolIface(comparator(leftDecimal.Cmp(rightDecimal))), nil
+}
+
+/*
+	Returns -1, 0, or +1, depending on whether [left] is before, the same as, or after [right].
+	Times can also be compared to plain numbers, for compatibility with expressions written
+	when dates were represented as float64 seconds since the epoch; in that case the time is converted to seconds.
+	Durations are only compared to durations, since a plain number has no unit.
+*/
+func compareTemporal(left interface{}, right interface{}) int {
+
+	if isTime(left) && isTime(right) {
+
+		leftTime := left.(time.Time)
+		rightTime := right.(time.Time)
+
+		if leftTime.Before(rightTime) {
+			return -1
+		}
+		if leftTime.After(rightTime) {
+			return 1
+		}
+		return 0
+	}
+
+	var leftValue, rightValue float64
+
+	if isDuration(left) && isDuration(right) {
+		leftValue = float64(left.(time.Duration))
+		rightValue = float64(right.(time.Duration))
+	} else {
+		leftValue = toSeconds(left)
+		rightValue = toSeconds(right)
+	}
+
+	if leftValue < rightValue {
+		return -1
+	}
+	if leftValue > rightValue {
+		return 1
+	}
+	return 0
+}
+
+/*
+	Converts a time to (fractional) seconds since the epoch, and any other number to a float64.
+*/
+func toSeconds(value interface{}) float64 {
+	switch value.(type) {
+	case time.Time:
+		return float64(value.(time.Time).Unix()) + float64(value.(time.Time).Nanosecond())/1e9
+	}
+	return toFloat64(value)
+}
+
+func scaleDuration(duration time.Duration, factor interface{}) (interface{}, error) {
+
+	if isInt64(factor) {
+		return duration * time.Duration(factor.(int64)), nil
+	}
+	return floatDuration(float64(duration) * toFloat64(factor))
+}
+
+/*
+	A duration divided by a number is a duration, but a duration divided by a duration is a (float64) ratio.
+*/
+func divideDuration(duration time.Duration, divisor interface{}) (interface{}, error) {
+
+	switch divisor.(type) {
+	case time.Duration:
+		return float64(duration) / float64(divisor.(time.Duration)), nil
+	case int64:
+		if divisor.(int64) == 0 {
+			return nil, errors.New(integerDivisionByZero)
+		}
+		return duration / time.Duration(divisor.(int64)), nil
+	}
+	return floatDuration(float64(duration) / toFloat64(divisor))
+}
+
+/*
+	Converts a float64 number of nanoseconds to a duration.
+	Returns an error for values a duration can't hold, such as the infinity given by dividing by zero, rather than silently converting them to garbage.
+*/
+func floatDuration(nanoseconds float64) (interface{}, error) {
+
+	if math.IsNaN(nanoseconds) || nanoseconds >= math.MaxInt64 || nanoseconds < math.MinInt64 {
+		return nil, fmt.Errorf(durationOutOfRange, nanoseconds)
+	}
+	return time.Duration(nanoseconds), nil
+}
+
+/*
+	Converts the (Decimal, error) return of a Decimal method to the return of an evaluationOperator.
+*/
+func decimalResult(value Decimal, err error) (interface{}, error) {
+
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
 /*
 	Addition usually means between numbers, but can also mean string concat.
 	String concat needs one (or both) of the sides to be a string.
 */
 func additionTypeCheck(left interface{}, right interface{}) bool {
 
-	if isFloat64(left) && isFloat64(right) {
+	if isNumber(left) && isNumber(right) {
+		return true
+	}
+	if isTemporal(left) && isDuration(right) {
+		return true
+	}
+	if isDuration(left) && isTime(right) {
 		return true
 	}
 	if !isString(left) && !isString(right) {
@@ -481,21 +1060,83 @@ func additionTypeCheck(left interface{}, right interface{}) bool {
 	return true
 }
 
+/*
+	Subtraction is between numbers, or durations, or a time and a duration (which is a time),
+	or two times (which is the duration between them).
+*/
+func subtractionTypeCheck(left interface{}, right interface{}) bool {
+
+	if isNumber(left) && isNumber(right) {
+		return true
+	}
+	if isTemporal(left) && isDuration(right) {
+		return true
+	}
+	return isTime(left) && isTime(right)
+}
+
+/*
+	Multiplication is between numbers, or a duration and a number.
+*/
+func multiplicationTypeCheck(left interface{}, right interface{}) bool {
+
+	if isNumber(left) && isNumber(right) {
+		return true
+	}
+	if isDuration(left) && isNumber(right) {
+		return true
+	}
+	return isNumber(left) && isDuration(right)
+}
+
+/*
+	Division is between numbers, or a duration and a number, or two durations.
+*/
+func divisionTypeCheck(left interface{}, right interface{}) bool {
+
+	if isNumber(left) && isNumber(right) {
+		return true
+	}
+	return isDuration(left) && isNumberOrDuration(right)
+}
+
 /*
 	Comparison can either be between numbers, or lexicographic between two strings,
 	but never between the two.
+	Times can be compared to times, and durations to durations. Times can also be compared to numbers (see compareTemporal),
+	the same as they're considered equal to numbers by semanticEqual.
 */
 func comparatorTypeCheck(left interface{}, right interface{}) bool {
 
-	if isFloat64(left) && isFloat64(right) {
+	if isNumber(left) && isNumber(right) {
 		return true
 	}
 	if isString(left) && isString(right) {
 		return true
 	}
+	if isTime(left) || isTime(right) {
+		return (isTime(left) || isNumber(left)) && (isTime(right) || isNumber(right))
+	}
+	if isDuration(left) || isDuration(right) {
+		return isDuration(left) && isDuration(right)
+	}
 	return false
 }
 
+/*
+	Anything can be tested for equality, except a duration and a plain number, since the number has no unit.
+*/
+func equalityTypeCheck(left interface{}, right interface{}) bool {
+
+	if isDuration(left) {
+		return !isNumber(right)
+	}
+	if isDuration(right) {
+		return !isNumber(left)
+	}
+	return true
+}
+
 func isArray(value interface{}) bool {
 	switch value.(type) {
 	case []interface{}:
@@ -514,3 +1155,14 @@ func boolIface(b bool) interface{} {
 	}
 	return _false
 }
+
+/*
+	Returns each element of a list of values separated by commas. If [stage] isn't a list, it's the only element.
+*/
+func findListElements(stage *evaluationStage) []*evaluationStage {
+
+	if stage.symbol != SEPARATE {
+		return []*evaluationStage{stage}
+	}
+	return append(findListElements(stage.leftStage), findListElements(stage.rightStage)...)
+}
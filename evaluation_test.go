@@ -1377,6 +1377,13 @@ func TestParameterizedEvaluation(test *testing.T) {
 			Parameters: []EvaluationParameter{fooParameter},
 			Expected:   "boophi",
 		},
+		EvaluationTest{
+
+			Name:       "Parameter function call followed by comparator",
+			Input:      "foo.FuncArgStr('boop') + 'hi' == 'boophi'",
+			Parameters: []EvaluationParameter{fooParameter},
+			Expected:   true,
+		},
 		EvaluationTest{
 
 			Name:       "Nested parameter function call",
@@ -1511,6 +1518,17 @@ func runEvaluationTests(evaluationTests []EvaluationTest, test *testing.T) {
 			test.Logf("Test '%s' failed", evaluationTest.Name)
 			test.Logf("Evaluation result '%v' does not match expected: '%v'", result, evaluationTest.Expected)
 			test.Fail()
+			continue
+		}
+
+		// compiled expressions should always agree with the expressions they're compiled from.
+		result, err = expression.Compile().Evaluate(parameters)
+
+		if err != nil || result != evaluationTest.Expected {
+
+			test.Logf("Test '%s' failed", evaluationTest.Name)
+			test.Logf("Compiled evaluation result '%v' (error %v) does not match expected: '%v'", result, err, evaluationTest.Expected)
+			test.Fail()
 		}
 	}
 }
@@ -0,0 +1,387 @@
+package govaluate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+/*
+	Represents a test of the type and location of an error.
+	Tests which expect a parsing error leave [Parameters] nil.
+*/
+type ExpressionErrorTest struct {
+	Name       string
+	Input      string
+	Functions  map[string]ExpressionFunction
+	Parameters map[string]interface{}
+	Expected   error
+	Position   Position
+}
+
+func TestParsingErrorPositions(test *testing.T) {
+
+	errorTests := []ExpressionErrorTest{
+
+		ExpressionErrorTest{
+
+			Name:     "Unclosed string",
+			Input:    "foo == 'bar",
+			Expected: ParseError{},
+			Position: Position{Offset: 7, Line: 1, Column: 8},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Invalid token",
+			Input:    "1 + 2 @ 3",
+			Expected: ParseError{},
+			Position: Position{Offset: 6, Line: 1, Column: 7},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Invalid regex",
+			Input:    "foo =~ '[a-'",
+			Expected: ParseError{},
+			Position: Position{Offset: 7, Line: 1, Column: 8},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Invalid token on second line",
+			Input:    "1 +\n  2 @ 3",
+			Expected: ParseError{},
+			Position: Position{Offset: 8, Line: 2, Column: 5},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Position counts characters, not bytes",
+			Input:    "'héllo' == 'x' @",
+			Expected: ParseError{},
+			Position: Position{Offset: 16, Line: 1, Column: 16},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Invalid transition",
+			Input:    "1 + > 2",
+			Expected: SyntaxError{},
+			Position: Position{Offset: 4, Line: 1, Column: 5},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Unexpected end",
+			Input:    "1 + 2 >",
+			Expected: SyntaxError{},
+			Position: Position{Offset: 6, Line: 1, Column: 7},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Undefined function",
+			Input:    "1 + foo(2)",
+			Expected: SyntaxError{},
+			Position: Position{Offset: 4, Line: 1, Column: 5},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Unclosed parenthesis",
+			Input:    "(1 + (2 * 3)",
+			Expected: SyntaxError{},
+			Position: Position{Offset: 0, Line: 1, Column: 1},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Unopened parenthesis",
+			Input:    "1 + 2) * 3",
+			Expected: SyntaxError{},
+			Position: Position{Offset: 5, Line: 1, Column: 6},
+		},
+	}
+
+	runExpressionErrorTests(errorTests, test)
+}
+
+func TestEvaluationErrorPositions(test *testing.T) {
+
+	functionError := errors.New("Function failed")
+	functions := map[string]ExpressionFunction{
+		"fail": func(arguments ...interface{}) (interface{}, error) {
+			return nil, functionError
+		},
+	}
+
+	errorTests := []ExpressionErrorTest{
+
+		ExpressionErrorTest{
+
+			Name:       "Missing parameter",
+			Input:      "1 + foo",
+			Parameters: map[string]interface{}{},
+			Expected:   MissingParameterError{},
+			Position:   Position{Offset: 4, Line: 1, Column: 5},
+		},
+		ExpressionErrorTest{
+
+			Name:       "Missing accessor parameter",
+			Input:      "1 + foo.Bar",
+			Parameters: map[string]interface{}{},
+			Expected:   MissingParameterError{},
+			Position:   Position{Offset: 4, Line: 1, Column: 5},
+		},
+		ExpressionErrorTest{
+
+			Name:       "Type mismatch",
+			Input:      "foo > 1 &&\n foo * 'bar' > 0",
+			Parameters: map[string]interface{}{"foo": 2},
+			Expected:   TypeError{},
+			Position:   Position{Offset: 16, Line: 2, Column: 6},
+		},
+		ExpressionErrorTest{
+
+			Name:       "Combined type mismatch",
+			Input:      "true && (1 + true > 0)",
+			Parameters: map[string]interface{}{},
+			Expected:   TypeError{},
+			Position:   Position{Offset: 11, Line: 1, Column: 12},
+		},
+		ExpressionErrorTest{
+
+			Name:       "Function failure",
+			Input:      "1 + fail()",
+			Functions:  functions,
+			Parameters: map[string]interface{}{},
+			Expected:   FunctionError{},
+			Position:   Position{Offset: 4, Line: 1, Column: 5},
+		},
+		ExpressionErrorTest{
+
+			Name:       "Accessor failure",
+			Input:      "foo.AlwaysFail()",
+			Parameters: map[string]interface{}{"foo": dummyParameter{}},
+			Expected:   FunctionError{},
+			Position:   Position{Offset: 0, Line: 1, Column: 1},
+		},
+	}
+
+	runExpressionErrorTests(errorTests, test)
+}
+
+func TestErrorDetails(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("1 + foo")
+	_, err := expression.Evaluate(nil)
+
+	var missingError MissingParameterError
+	if !errors.As(err, &missingError) || missingError.Name != "foo" {
+		test.Errorf("Expected missing parameter 'foo', got: %v", err)
+	}
+
+	expression, _ = NewEvaluableExpression("1 - 'bar'")
+	_, err = expression.Evaluate(nil)
+
+	var typeError TypeError
+	if !errors.As(err, &typeError) {
+		test.Fatalf("Expected type error, got: %v", err)
+	}
+	if typeError.Symbol != MINUS || typeError.Token.Kind != MODIFIER || typeError.Value != 1.0 {
+		test.Errorf("Unexpected type error details: %+v", typeError)
+	}
+
+	functionError := errors.New("Function failed")
+	functions := map[string]ExpressionFunction{
+		"fail": func(arguments ...interface{}) (interface{}, error) {
+			return nil, functionError
+		},
+	}
+
+	expression, _ = NewEvaluableExpressionWithFunctions("fail(1)", functions)
+	_, err = expression.Evaluate(nil)
+
+	var failure FunctionError
+	if !errors.As(err, &failure) || failure.Name != "fail" {
+		test.Errorf("Expected function error from 'fail', got: %v", err)
+	}
+	if !errors.Is(err, functionError) || err.Error() != functionError.Error() {
+		test.Errorf("Expected function error to wrap the original error, got: %v", err)
+	}
+}
+
+func TestPositionsArentInTokens(test *testing.T) {
+
+	// positions are kept apart from tokens, so tokens can still be compared with (and made by) unkeyed literals.
+	expression, _ := NewEvaluableExpression("foo +\n 1")
+	expected := []ExpressionToken{
+		ExpressionToken{VARIABLE, "foo"},
+		ExpressionToken{MODIFIER, "+"},
+		ExpressionToken{NUMERIC, 1.0},
+	}
+
+	if !reflect.DeepEqual(expression.Tokens(), expected) {
+		test.Errorf("Expected tokens %v, got %v", expected, expression.Tokens())
+	}
+
+	_, err := expression.Evaluate(map[string]interface{}{"foo": true})
+
+	var typeError TypeError
+	if !errors.As(err, &typeError) || typeError.Position != (Position{Offset: 4, Line: 1, Column: 5}) {
+		test.Errorf("Expected a type error at line 1, column 5, got: %v", err)
+	}
+}
+
+func runExpressionErrorTests(errorTests []ExpressionErrorTest, test *testing.T) {
+
+	var expression *EvaluableExpression
+	var err error
+
+	for _, errorTest := range errorTests {
+
+		expression, err = NewEvaluableExpressionWithFunctions(errorTest.Input, errorTest.Functions)
+
+		if errorTest.Parameters != nil {
+
+			if err != nil {
+				test.Errorf("Test '%s' failed to parse: %v", errorTest.Name, err)
+				continue
+			}
+			_, err = expression.Evaluate(errorTest.Parameters)
+		}
+
+		if err == nil {
+			test.Errorf("Test '%s' expected an error, got none", errorTest.Name)
+			continue
+		}
+
+		position, matches := findErrorPosition(err, errorTest.Expected)
+		if !matches {
+			test.Errorf("Test '%s' expected a %T, got %T: %v", errorTest.Name, errorTest.Expected, err, err)
+			continue
+		}
+
+		if position != errorTest.Position {
+			test.Errorf("Test '%s' expected error at %+v, got %+v", errorTest.Name, errorTest.Position, position)
+		}
+	}
+}
+
+/*
+	Returns the position of [err], and whether or not it's the same type as [expected].
+*/
+func findErrorPosition(err error, expected error) (Position, bool) {
+
+	if reflect.TypeOf(err) != reflect.TypeOf(expected) {
+		return Position{}, false
+	}
+
+	switch err.(type) {
+	case ParseError:
+		return err.(ParseError).Position, true
+	case SyntaxError:
+		return err.(SyntaxError).Position, true
+	case TypeError:
+		return err.(TypeError).Position, true
+	case MissingParameterError:
+		return err.(MissingParameterError).Position, true
+	case FunctionError:
+		return err.(FunctionError).Position, true
+	}
+	return Position{}, false
+}
+
+func TestErrorOperators(test *testing.T) {
+
+	errorTests := []ExpressionErrorTest{
+
+		ExpressionErrorTest{
+
+			Name:     "Unclosed string",
+			Input:    "foo == 'bar",
+			Expected: ParseError{Token: ExpressionToken{STRING, "bar"}},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Invalid token",
+			Input:    "1 + 2 @ 3",
+			Expected: ParseError{Token: ExpressionToken{UNKNOWN, "@"}},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Invalid regex",
+			Input:    "foo =~ '[a-'",
+			Expected: ParseError{Token: ExpressionToken{STRING, "[a-"}},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Hanging accessor",
+			Input:    "foo.",
+			Expected: ParseError{Token: ExpressionToken{ACCESSOR, "foo."}},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Invalid transition",
+			Input:    "1 + > 2",
+			Expected: SyntaxError{Symbol: GT, Token: ExpressionToken{COMPARATOR, ">"}},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Unexpected end",
+			Input:    "1 + 2 -",
+			Expected: SyntaxError{Symbol: MINUS, Token: ExpressionToken{MODIFIER, "-"}},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Unclosed parenthesis",
+			Input:    "(1 + (2 * 3)",
+			Expected: SyntaxError{Symbol: NOOP, Token: ExpressionToken{CLAUSE, '('}},
+		},
+		ExpressionErrorTest{
+
+			Name:     "Unexpected literal",
+			Input:    "1 2",
+			Expected: SyntaxError{Symbol: LITERAL, Token: ExpressionToken{NUMERIC, 2.0}},
+		},
+		ExpressionErrorTest{
+
+			Name:       "Missing parameter",
+			Input:      "1 + foo",
+			Parameters: map[string]interface{}{},
+			Expected:   MissingParameterError{Name: "foo", Symbol: VALUE, Token: ExpressionToken{VARIABLE, "foo"}},
+		},
+		ExpressionErrorTest{
+
+			Name:       "Missing accessor parameter",
+			Input:      "1 + foo.Bar",
+			Parameters: map[string]interface{}{},
+			Expected:   MissingParameterError{Name: "foo", Symbol: ACCESS, Token: ExpressionToken{ACCESSOR, []string{"foo", "Bar"}}},
+		},
+	}
+
+	for _, errorTest := range errorTests {
+
+		expression, err := NewEvaluableExpressionWithFunctions(errorTest.Input, errorTest.Functions)
+		if errorTest.Parameters != nil && err == nil {
+			_, err = expression.Evaluate(errorTest.Parameters)
+		}
+
+		var actual error
+		switch errorTest.Expected.(type) {
+		case ParseError:
+			var parseError ParseError
+			if errors.As(err, &parseError) {
+				actual = ParseError{Token: parseError.Token}
+			}
+		case SyntaxError:
+			var syntaxError SyntaxError
+			if errors.As(err, &syntaxError) {
+				actual = SyntaxError{Symbol: syntaxError.Symbol, Token: syntaxError.Token}
+			}
+		case MissingParameterError:
+			var missingError MissingParameterError
+			if errors.As(err, &missingError) {
+				actual = MissingParameterError{Name: missingError.Name, Symbol: missingError.Symbol, Token: missingError.Token}
+			}
+		}
+
+		if !reflect.DeepEqual(actual, errorTest.Expected) {
+			test.Logf("Test '%s' failed", errorTest.Name)
+			test.Logf("Expected error details %#v, got %#v (%v)", errorTest.Expected, actual, err)
+			test.Fail()
+		}
+	}
+}
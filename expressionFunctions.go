@@ -1,8 +1,19 @@
 package govaluate
 
+import (
+	"context"
+)
+
 /*
 	Represents a function that can be called from within an expression.
 	This method must return an error if, for any reason, it is unable to produce exactly one unambiguous result.
 	An error returned will halt execution of the expression.
 */
 type ExpressionFunction func(arguments ...interface{}) (interface{}, error)
+
+/*
+	Similar to [ExpressionFunction], except that the function also receives the context given to `EvalContext`
+	(or `context.Background()` when evaluated through `Eval` or `Evaluate`).
+	Long-running functions should watch that context, and return its error once it is done.
+*/
+type ContextExpressionFunction func(ctx context.Context, arguments ...interface{}) (interface{}, error)
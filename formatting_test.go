@@ -0,0 +1,241 @@
+package govaluate
+
+import (
+	"testing"
+	"time"
+)
+
+/*
+	Represents a test of formatting an expression.
+	[Expected] is the canonical form of [Input].
+*/
+type FormattingTest struct {
+	Name      string
+	Input     string
+	Functions map[string]ExpressionFunction
+	Options   ParsingOptions
+	Expected  string
+}
+
+func TestFormatting(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"max": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	formattingTests := []FormattingTest{
+
+		FormattingTest{
+
+			Name:     "Spacing",
+			Input:    "foo>1&&  bar  ==   'x'",
+			Expected: "foo > 1 && bar == 'x'",
+		},
+		FormattingTest{
+
+			Name:     "Redundant parenthesis",
+			Input:    "((a + (b * c))) > (1)",
+			Expected: "a + b * c > 1",
+		},
+		FormattingTest{
+
+			Name:     "Required parenthesis",
+			Input:    "(a + b) * c - (d - e)",
+			Expected: "(a + b) * c - (d - e)",
+		},
+		FormattingTest{
+
+			Name:     "Logical grouping",
+			Input:    "(a || b) && !(c || d)",
+			Expected: "(a || b) && !(c || d)",
+		},
+		FormattingTest{
+
+			Name:     "Ternaries",
+			Input:    "a ? (b ? 1 : 2) : c ?? 3",
+			Expected: "a ? (b ? 1 : 2) : c ?? 3",
+		},
+		FormattingTest{
+
+			Name:     "Double quotes",
+			Input:    "name == \"it\\'s\"",
+			Expected: "name == 'it\\'s'",
+		},
+		FormattingTest{
+
+			Name:     "Escaped characters",
+			Input:    "'a\\\\b\\\"c'",
+			Expected: "'a\\\\b\\\"c'",
+		},
+		FormattingTest{
+
+			Name:     "Numbers",
+			Input:    "0x10 + 1.50 + 2.0 + .25",
+			Expected: "16 + 1.5 + 2 + 0.25",
+		},
+		FormattingTest{
+
+			Name:     "Negative numbers",
+			Input:    "-1 - -2.5 * -(3)",
+			Expected: "-1 - -2.5 * -3",
+		},
+		FormattingTest{
+
+			Name:     "Booleans and arrays",
+			Input:    "foo IN (true,false , ())",
+			Expected: "foo in (true, false, ())",
+		},
+		FormattingTest{
+
+			Name:      "Functions and accessors",
+			Input:     "max( 1,2 ) + foo.Bar+foo.Baz( 'x' )",
+			Functions: functions,
+			Expected:  "max(1, 2) + foo.Bar + foo.Baz('x')",
+		},
+		FormattingTest{
+
+			Name:     "Escaped variables",
+			Input:    "[foo bar] + [true] + [a\\]b] + [plain]",
+			Expected: "[foo bar] + [true] + [a\\]b] + plain",
+		},
+		FormattingTest{
+
+			Name:     "Patterns",
+			Input:    "foo =~ \"^a\\'b\"",
+			Expected: "foo =~ '^a\\'b'",
+		},
+		FormattingTest{
+
+			Name:     "Durations",
+			Input:    "elapsed > 90m && timeout < 1.5s",
+			Expected: "elapsed > 1h30m0s && timeout < 1.5s",
+		},
+		FormattingTest{
+
+			Name:     "Times",
+			Input:    "created > '2014-01-02T14:12:22.5Z'",
+			Expected: "created > '2014-01-02T14:12:22.5Z'",
+		},
+		FormattingTest{
+
+			Name:     "Integer mode",
+			Input:    "9007199254740993 + 1.5",
+			Options:  ParsingOptions{NumericMode: INTEGER_NUMERICS},
+			Expected: "9007199254740993 + 1.5",
+		},
+		FormattingTest{
+
+			Name:     "Decimal mode keeps digits",
+			Input:    "0.10 + 1000.00",
+			Options:  ParsingOptions{NumericMode: DECIMAL_NUMERICS},
+			Expected: "0.10 + 1000.00",
+		},
+	}
+
+	runFormattingTests(formattingTests, test)
+}
+
+func TestFormattingFromTokens(test *testing.T) {
+
+	tokens := []ExpressionToken{
+		ExpressionToken{Kind: VARIABLE, Value: "foo"},
+		ExpressionToken{Kind: MODIFIER, Value: "*"},
+		ExpressionToken{Kind: CLAUSE, Value: '('},
+		ExpressionToken{Kind: NUMERIC, Value: 1.0},
+		ExpressionToken{Kind: MODIFIER, Value: "+"},
+		ExpressionToken{Kind: STRING, Value: "bar"},
+		ExpressionToken{Kind: CLAUSE_CLOSE, Value: ')'},
+	}
+
+	expression, err := NewEvaluableExpressionFromTokens(tokens)
+	if err != nil {
+		test.Fatalf("Unable to make expression: %v", err)
+	}
+
+	if expression.String() != "foo * (1 + 'bar')" {
+		test.Errorf("Expected formatted string, got '%s'", expression.String())
+	}
+
+	// functions made from tokens have no names, so can't be written out.
+	tokens = []ExpressionToken{
+		ExpressionToken{Kind: FUNCTION, Value: ExpressionFunction(func(arguments ...interface{}) (interface{}, error) { return nil, nil })},
+		ExpressionToken{Kind: CLAUSE, Value: '('},
+		ExpressionToken{Kind: CLAUSE_CLOSE, Value: ')'},
+	}
+
+	expression, err = NewEvaluableExpressionFromTokens(tokens)
+	if err != nil {
+		test.Fatalf("Unable to make expression: %v", err)
+	}
+
+	_, err = expression.Formatted()
+	if err == nil {
+		test.Errorf("Expected an error formatting an unnamed function")
+	}
+}
+
+func TestFormattingAST(test *testing.T) {
+
+	node := &BinaryNode{
+		Operator: AND,
+		Left:     &BinaryNode{Operator: OR, Left: &VariableNode{Name: "a"}, Right: &VariableNode{Name: "b"}},
+		Right: &BinaryNode{
+			Operator: GT,
+			Left:     &VariableNode{Name: "when"},
+			Right:    &LiteralNode{Value: time.Date(2020, time.March, 4, 5, 6, 7, 0, time.UTC)},
+		},
+	}
+
+	text, err := FormatAST(node)
+	if err != nil {
+		test.Fatalf("Unable to format AST: %v", err)
+	}
+
+	if text != "(a || b) && when > '2020-03-04T05:06:07Z'" {
+		test.Errorf("Unexpected formatted AST: %s", text)
+	}
+}
+
+func runFormattingTests(formattingTests []FormattingTest, test *testing.T) {
+
+	for _, formattingTest := range formattingTests {
+
+		options := formattingTest.Options
+		options.Functions = formattingTest.Functions
+
+		expression, err := NewEvaluableExpressionWithOptions(formattingTest.Input, options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", formattingTest.Name, err)
+			continue
+		}
+
+		formatted, err := expression.Formatted()
+		if err != nil {
+			test.Errorf("Test '%s' failed to format: %v", formattingTest.Name, err)
+			continue
+		}
+
+		if formatted != formattingTest.Expected {
+			test.Errorf("Test '%s' expected '%s', got '%s'", formattingTest.Name, formattingTest.Expected, formatted)
+			continue
+		}
+
+		// formatting should round-trip, and be stable.
+		reparsed, err := NewEvaluableExpressionWithOptions(formatted, options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse its formatted text '%s': %v", formattingTest.Name, formatted, err)
+			continue
+		}
+
+		if describeNode(reparsed.AST()) != describeNode(expression.AST()) {
+			test.Errorf("Test '%s' formatted text has AST '%s', expected '%s'", formattingTest.Name, describeNode(reparsed.AST()), describeNode(expression.AST()))
+		}
+
+		reformatted, _ := reparsed.Formatted()
+		if reformatted != formatted {
+			test.Errorf("Test '%s' reformatted to '%s', expected '%s'", formattingTest.Name, reformatted, formatted)
+		}
+	}
+}
@@ -24,6 +24,7 @@ var validLexerStates = []lexerState{
 
 			PREFIX,
 			NUMERIC,
+			DURATION,
 			BOOLEAN,
 			VARIABLE,
 			PATTERN,
@@ -44,6 +45,7 @@ var validLexerStates = []lexerState{
 
 			PREFIX,
 			NUMERIC,
+			DURATION,
 			BOOLEAN,
 			VARIABLE,
 			PATTERN,
@@ -66,6 +68,7 @@ var validLexerStates = []lexerState{
 			COMPARATOR,
 			MODIFIER,
 			NUMERIC,
+			DURATION,
 			BOOLEAN,
 			VARIABLE,
 			STRING,
@@ -94,6 +97,21 @@ var validLexerStates = []lexerState{
 			SEPARATOR,
 		},
 	},
+	lexerState{
+
+		kind:       DURATION,
+		isEOF:      true,
+		isNullable: false,
+		validNextKinds: []TokenKind{
+
+			MODIFIER,
+			COMPARATOR,
+			LOGICALOP,
+			CLAUSE_CLOSE,
+			TERNARY,
+			SEPARATOR,
+		},
+	},
 	lexerState{
 
 		kind:       BOOLEAN,
@@ -135,6 +153,7 @@ var validLexerStates = []lexerState{
 			COMPARATOR,
 			LOGICALOP,
 			CLAUSE_CLOSE,
+			TERNARY,
 			SEPARATOR,
 		},
 	},
@@ -149,6 +168,7 @@ var validLexerStates = []lexerState{
 			COMPARATOR,
 			LOGICALOP,
 			CLAUSE_CLOSE,
+			TERNARY,
 			SEPARATOR,
 		},
 	},
@@ -176,10 +196,12 @@ var validLexerStates = []lexerState{
 
 			PREFIX,
 			NUMERIC,
+			DURATION,
 			VARIABLE,
 			FUNCTION,
 			ACCESSOR,
 			STRING,
+			TIME,
 			BOOLEAN,
 			CLAUSE,
 			CLAUSE_CLOSE,
@@ -194,6 +216,7 @@ var validLexerStates = []lexerState{
 
 			PREFIX,
 			NUMERIC,
+			DURATION,
 			BOOLEAN,
 			VARIABLE,
 			FUNCTION,
@@ -214,6 +237,7 @@ var validLexerStates = []lexerState{
 
 			PREFIX,
 			NUMERIC,
+			DURATION,
 			BOOLEAN,
 			VARIABLE,
 			FUNCTION,
@@ -232,6 +256,7 @@ var validLexerStates = []lexerState{
 		validNextKinds: []TokenKind{
 
 			NUMERIC,
+			DURATION,
 			BOOLEAN,
 			VARIABLE,
 			FUNCTION,
@@ -250,6 +275,7 @@ var validLexerStates = []lexerState{
 
 			PREFIX,
 			NUMERIC,
+			DURATION,
 			BOOLEAN,
 			STRING,
 			TIME,
@@ -293,6 +319,7 @@ var validLexerStates = []lexerState{
 
 			PREFIX,
 			NUMERIC,
+			DURATION,
 			BOOLEAN,
 			STRING,
 			TIME,
@@ -316,47 +343,92 @@ func (this lexerState) canTransitionTo(kind TokenKind) bool {
 	return false
 }
 
-func checkExpressionSyntax(tokens []ExpressionToken) error {
+func checkExpressionSyntax(tokens []ExpressionToken, sources []tokenSource) error {
+
+	errs := findSyntaxErrors(tokens, sources, nil, false)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+/*
+	Checks that each of the given [tokens] can follow the one before it, using their [sources] to say where errors are.
+	If [recovers] is false, this stops at the first error. Otherwise, each token which can't follow the one before it is reported,
+	and then checking resumes as though it was valid, so that every error can be found.
+	Tokens whose indices are in [resumed] follow a token which couldn't be read; they're checked as though they started the expression.
+*/
+func findSyntaxErrors(tokens []ExpressionToken, sources []tokenSource, resumed map[int]bool, recovers bool) []error {
 
 	var state lexerState
 	var lastToken ExpressionToken
+	var lastSource, source tokenSource
+	var errs []error
 	var err error
 
 	state = validLexerStates[0]
 
-	for _, token := range tokens {
+	for index, token := range tokens {
+
+		source = sources[index]
+
+		if resumed[index] {
+			state = validLexerStates[0]
+			lastToken = ExpressionToken{}
+			lastSource = tokenSource{}
+		}
 
 		if !state.canTransitionTo(token.Kind) {
 
 			// call out a specific error for tokens looking like they want to be functions.
 			if lastToken.Kind == VARIABLE && token.Kind == CLAUSE {
-				return errors.New("Undefined function " + lastToken.Value.(string))
-			}
+				err = SyntaxError{"Undefined function " + lastToken.Value.(string), lastSource.start, findTokenSymbol(lastToken), lastToken}
+			} else {
 
-			firstStateName := fmt.Sprintf("%s [%v]", state.kind.String(), lastToken.Value)
-			nextStateName := fmt.Sprintf("%s [%v]", token.Kind.String(), token.Value)
+				firstStateName := fmt.Sprintf("%s [%v]", state.kind.String(), lastToken.Value)
+				nextStateName := fmt.Sprintf("%s [%v]", token.Kind.String(), token.Value)
 
-			return errors.New("Cannot transition token types from " + firstStateName + " to " + nextStateName)
+				err = SyntaxError{"Cannot transition token types from " + firstStateName + " to " + nextStateName, source.start, findTokenSymbol(token), token}
+			}
+
+			errs = append(errs, err)
+			if !recovers {
+				return errs
+			}
 		}
 
 		state, err = getLexerStateForToken(token.Kind)
 		if err != nil {
-			return err
+
+			errs = append(errs, SyntaxError{err.Error(), source.start, findTokenSymbol(token), token})
+			if !recovers {
+				return errs
+			}
 		}
 
 		if !state.isNullable && token.Value == nil {
 
 			errorMsg := fmt.Sprintf("Token kind '%v' cannot have a nil value", token.Kind.String())
-			return errors.New(errorMsg)
+			errs = append(errs, SyntaxError{errorMsg, source.start, findTokenSymbol(token), token})
+			if !recovers {
+				return errs
+			}
 		}
 
 		lastToken = token
+		lastSource = source
+	}
+
+	// if the expression ended with a token which couldn't be read, that's already been reported.
+	if resumed[len(tokens)] {
+		return errs
 	}
 
+	// there's no token at the end of the expression, so the error points to the last one (which is missing whatever follows it).
 	if !state.isEOF {
-		return errors.New("Unexpected end of expression")
+		errs = append(errs, SyntaxError{"Unexpected end of expression", lastSource.start, findTokenSymbol(lastToken), lastToken})
 	}
-	return nil
+	return errs
 }
 
 func getLexerStateForToken(kind TokenKind) (lexerState, error) {
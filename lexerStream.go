@@ -1,9 +1,18 @@
 package govaluate
 
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
 type lexerStream struct {
 	source   []rune
 	position int
 	length   int
+
+	// the last location found by `positionOf`, so that finding the location of every token doesn't rescan the whole source.
+	lastIndex    int
+	lastPosition Position
 }
 
 func newLexerStream(source string) *lexerStream {
@@ -18,6 +27,7 @@ func newLexerStream(source string) *lexerStream {
 	ret = new(lexerStream)
 	ret.source = runes
 	ret.length = len(runes)
+	ret.lastPosition = Position{Line: 1, Column: 1}
 	return ret
 }
 
@@ -37,3 +47,41 @@ func (this *lexerStream) rewind(amount int) {
 func (this lexerStream) canRead() bool {
 	return this.position < this.length
 }
+
+/*
+	Returns the location in the original expression of the character at the given (rune) [index].
+*/
+func (this *lexerStream) positionOf(index int) Position {
+
+	if index < this.lastIndex {
+		this.lastIndex = 0
+		this.lastPosition = Position{Line: 1, Column: 1}
+	}
+
+	for ; this.lastIndex < index && this.lastIndex < this.length; this.lastIndex++ {
+
+		character := this.source[this.lastIndex]
+		this.lastPosition.Offset += utf8.RuneLen(character)
+
+		if character == '\n' {
+			this.lastPosition.Line++
+			this.lastPosition.Column = 1
+		} else {
+			this.lastPosition.Column++
+		}
+	}
+	return this.lastPosition
+}
+
+/*
+	Returns the index just past the end of the token which started at [start], and was just read.
+	Reading a token may have consumed the whitespace which follows it, which isn't part of the token.
+*/
+func (this lexerStream) tokenEnd(start int) int {
+
+	end := this.position
+	for end > start+1 && unicode.IsSpace(this.source[end-1]) {
+		end--
+	}
+	return end
+}
@@ -0,0 +1,267 @@
+package govaluate
+
+import (
+	"encoding"
+	"encoding/json"
+	"testing"
+)
+
+type marshalledRule struct {
+	Name      string
+	Condition *EvaluableExpression
+	Fallback  EvaluableExpression
+}
+
+func TestJSONMarshalling(test *testing.T) {
+
+	var rule marshalledRule
+
+	err := json.Unmarshal([]byte(`{"Name": "adults", "Condition": "age >= 18 && name != 'x'", "Fallback": "false"}`), &rule)
+	if err != nil {
+		test.Fatalf("Unable to unmarshal rule: %v", err)
+	}
+
+	result, err := rule.Condition.Evaluate(map[string]interface{}{"age": 21, "name": "bob"})
+	if err != nil || result != true {
+		test.Errorf("Expected unmarshalled condition to give true, got %v (%v)", result, err)
+	}
+
+	result, err = rule.Fallback.Evaluate(nil)
+	if err != nil || result != false {
+		test.Errorf("Expected unmarshalled fallback to give false, got %v (%v)", result, err)
+	}
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		test.Fatalf("Unable to marshal rule: %v", err)
+	}
+
+	// encoding/json escapes <, > and & in all strings.
+	expected := `{"Name":"adults","Condition":"age \u003e= 18 \u0026\u0026 name != 'x'","Fallback":"false"}`
+	if string(data) != expected {
+		test.Errorf("Expected '%s', got '%s'", expected, string(data))
+	}
+
+	// null leaves the expression as it was.
+	rule = marshalledRule{}
+	err = json.Unmarshal([]byte(`{"Condition": null}`), &rule)
+	if err != nil || rule.Condition != nil {
+		test.Errorf("Expected null to leave the condition nil, got %v (%v)", rule.Condition, err)
+	}
+
+	err = json.Unmarshal([]byte(`{"Condition": "1 +"}`), &rule)
+	if err == nil {
+		test.Errorf("Expected an invalid expression to fail to unmarshal")
+	}
+
+	err = json.Unmarshal([]byte(`{"Condition": 5}`), &rule)
+	if err == nil {
+		test.Errorf("Expected a number to fail to unmarshal as an expression")
+	}
+}
+
+func TestTextMarshalling(test *testing.T) {
+
+	var marshaller encoding.TextMarshaler
+	var unmarshaller encoding.TextUnmarshaler
+
+	expression, _ := NewEvaluableExpression("foo  +  1")
+	marshaller = expression
+
+	text, err := marshaller.MarshalText()
+	if err != nil || string(text) != "foo  +  1" {
+		test.Errorf("Expected the original text, got '%s' (%v)", string(text), err)
+	}
+
+	// expressions without text are formatted.
+	tokens := []ExpressionToken{
+		ExpressionToken{Kind: VARIABLE, Value: "foo"},
+		ExpressionToken{Kind: MODIFIER, Value: "*"},
+		ExpressionToken{Kind: CLAUSE, Value: '('},
+		ExpressionToken{Kind: NUMERIC, Value: 2.0},
+		ExpressionToken{Kind: MODIFIER, Value: "+"},
+		ExpressionToken{Kind: NUMERIC, Value: 1.5},
+		ExpressionToken{Kind: CLAUSE_CLOSE, Value: ')'},
+	}
+
+	expression, err = NewEvaluableExpressionFromTokens(tokens)
+	if err != nil {
+		test.Fatalf("Unable to create expression from tokens: %v", err)
+	}
+
+	text, err = expression.MarshalText()
+	if err != nil || string(text) != "foo * (2 + 1.5)" {
+		test.Errorf("Expected formatted text, got '%s' (%v)", string(text), err)
+	}
+
+	unmarshalled := new(EvaluableExpression)
+	unmarshaller = unmarshalled
+
+	err = unmarshaller.UnmarshalText(text)
+	if err != nil {
+		test.Fatalf("Unable to unmarshal text: %v", err)
+	}
+
+	result, err := unmarshalled.Evaluate(map[string]interface{}{"foo": 2})
+	if err != nil || result != 7.0 {
+		test.Errorf("Expected 7, got %v (%v)", result, err)
+	}
+}
+
+func TestUnmarshallingFunctions(test *testing.T) {
+
+	defer SetUnmarshalOptions(ParsingOptions{})
+
+	var expression EvaluableExpression
+
+	err := json.Unmarshal([]byte(`"double(foo) > 10"`), &expression)
+	if err == nil {
+		test.Errorf("Expected an unregistered function to fail to unmarshal")
+	}
+
+	RegisterFunction("double", func(arguments ...interface{}) (interface{}, error) {
+		return arguments[0].(float64) * 2, nil
+	})
+
+	err = json.Unmarshal([]byte(`"double(foo) > 10"`), &expression)
+	if err != nil {
+		test.Fatalf("Unable to unmarshal expression with a registered function: %v", err)
+	}
+
+	result, err := expression.Evaluate(map[string]interface{}{"foo": 6})
+	if err != nil || result != true {
+		test.Errorf("Expected true, got %v (%v)", result, err)
+	}
+
+	data, err := json.Marshal(expression)
+	if err != nil || string(data) != `"double(foo) \u003e 10"` {
+		test.Errorf("Unexpected marshalled function call '%s' (%v)", string(data), err)
+	}
+
+	// other options replace the registered functions.
+	SetUnmarshalOptions(ParsingOptions{NumericMode: DECIMAL_NUMERICS})
+
+	err = json.Unmarshal([]byte(`"double(foo) > 10"`), &expression)
+	if err == nil {
+		test.Errorf("Expected functions to be replaced by new options")
+	}
+
+	err = json.Unmarshal([]byte(`"0.1 + 0.2 == 0.3"`), &expression)
+	if err != nil {
+		test.Fatalf("Unable to unmarshal decimal expression: %v", err)
+	}
+
+	result, err = expression.Evaluate(nil)
+	if err != nil || result != true {
+		test.Errorf("Expected exact decimals to give true, got %v (%v)", result, err)
+	}
+}
+
+func TestMarshallingSettings(test *testing.T) {
+
+	expression, _ := NewEvaluableExpressionWithOptions("0.1 + 0.2 == 0.3", ParsingOptions{NumericMode: DECIMAL_NUMERICS, DecimalPrecision: 4})
+	expression.ChecksTypes = false
+	expression.Options = EvaluationOptions{MaxSteps: 10}
+
+	data, err := json.Marshal(expression)
+	if err != nil {
+		test.Fatalf("Unable to marshal expression: %v", err)
+	}
+
+	expected := `{"Expression":"0.1 + 0.2 == 0.3","NumericMode":"DECIMAL_NUMERICS","DecimalPrecision":4,"ChecksTypes":false,"Options":{"MaxSteps":10,"MaxStringLength":0,"MaxArrayLength":0}}`
+	if string(data) != expected {
+		test.Errorf("Expected '%s', got '%s'", expected, string(data))
+	}
+
+	var unmarshalled EvaluableExpression
+
+	err = json.Unmarshal(data, &unmarshalled)
+	if err != nil {
+		test.Fatalf("Unable to unmarshal expression: %v", err)
+	}
+
+	if unmarshalled.parsingOptions.NumericMode != DECIMAL_NUMERICS || unmarshalled.parsingOptions.DecimalPrecision != 4 {
+		test.Errorf("Expected decimal numerics with a precision of 4, got %v", unmarshalled.parsingOptions)
+	}
+	if unmarshalled.ChecksTypes || unmarshalled.Options != expression.Options {
+		test.Errorf("Expected unchecked types and limits to be kept, got %v and %v", unmarshalled.ChecksTypes, unmarshalled.Options)
+	}
+
+	result, err := unmarshalled.Evaluate(nil)
+	if err != nil || result != true {
+		test.Errorf("Expected exact decimals to give true, got %v (%v)", result, err)
+	}
+
+	// text holds the same object, so that YAML and TOML keep the settings too.
+	text, err := expression.MarshalText()
+	if err != nil || string(text) != expected {
+		test.Errorf("Expected '%s', got '%s' (%v)", expected, string(text), err)
+	}
+
+	unmarshalled = EvaluableExpression{}
+	err = unmarshalled.UnmarshalText(text)
+	if err != nil || unmarshalled.parsingOptions.NumericMode != DECIMAL_NUMERICS || unmarshalled.ChecksTypes {
+		test.Errorf("Expected settings to be unmarshalled from text, got %v (%v)", unmarshalled.parsingOptions, err)
+	}
+
+	// a JSON string of the object is the same as the object.
+	data, _ = json.Marshal(string(text))
+	unmarshalled = EvaluableExpression{}
+
+	err = json.Unmarshal(data, &unmarshalled)
+	if err != nil || unmarshalled.parsingOptions.NumericMode != DECIMAL_NUMERICS {
+		test.Errorf("Expected settings to be unmarshalled from a JSON string, got %v (%v)", unmarshalled.parsingOptions, err)
+	}
+}
+
+func TestUnmarshallingSettingsFailure(test *testing.T) {
+
+	inputs := []string{
+		`{"Expression": "1 + 1", "NumericMode": "COMPLEX_NUMERICS"}`,
+		`{"Expression": "1 + 1", "NumericMod": "DECIMAL_NUMERICS"}`,
+		`{"Expression": "1 +"}`,
+		`{"Expression": "1 + 1"`,
+	}
+
+	for _, input := range inputs {
+
+		var expression EvaluableExpression
+
+		err := expression.UnmarshalText([]byte(input))
+		if err == nil {
+			test.Errorf("Expected '%s' to fail to unmarshal", input)
+		}
+	}
+
+	_, err := NewEvaluableExpression("{")
+	if err == nil {
+		test.Errorf("Expected an expression starting with a brace to fail to parse")
+	}
+}
+
+func TestUnmarshallingScopedFunctions(test *testing.T) {
+
+	options := ParsingOptions{
+		Functions: map[string]ExpressionFunction{
+			"triple": func(arguments ...interface{}) (interface{}, error) {
+				return arguments[0].(float64) * 3, nil
+			},
+		},
+	}
+
+	expression, err := NewEvaluableExpressionFromText([]byte(`{"Expression": "triple(foo)", "ChecksTypes": true, "Options": {"MaxSteps": 5}}`), options)
+	if err != nil {
+		test.Fatalf("Unable to unmarshal expression with scoped functions: %v", err)
+	}
+
+	result, err := expression.Evaluate(map[string]interface{}{"foo": 2})
+	if err != nil || result != 6.0 {
+		test.Errorf("Expected 6, got %v (%v)", result, err)
+	}
+
+	// scoped functions aren't registered for everyone else.
+	err = new(EvaluableExpression).UnmarshalText([]byte("triple(foo)"))
+	if err == nil {
+		test.Errorf("Expected a scoped function to be unavailable to UnmarshalText")
+	}
+}
@@ -0,0 +1,338 @@
+package govaluate
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+/*
+	Returns a new map of common math functions, to be given to `NewEvaluableExpressionWithFunctions`
+	(or ParsingOptions.Functions). None of these are available to an expression unless they're given to it.
+	Each call returns a new map, so the caller is free to add their own functions to it, or remove any they don't want.
+
+	The functions are:
+
+		abs(x), floor(x), ceil(x), sqrt(x), exp(x)
+		round(x), or round(x, places)
+		log(x), or log(x, base)
+		pow(x, y)
+		min(x, ...), max(x, ...)
+
+	Every function accepts numbers in any of the representations used by the NumericMode of an expression.
+	abs, floor, ceil, round, min and max keep the representation of their arguments (so integers stay integers, and decimals stay exact).
+	sqrt, log and exp are computed as float64, and give a Decimal when any of their arguments is a Decimal.
+	pow behaves exactly like the `**` operator.
+*/
+func MathFunctions() map[string]ExpressionFunction {
+
+	return map[string]ExpressionFunction{
+		"abs":   absFunction,
+		"min":   minFunction,
+		"max":   maxFunction,
+		"floor": floorFunction,
+		"ceil":  ceilFunction,
+		"round": roundFunction,
+		"sqrt":  sqrtFunction,
+		"log":   logFunction,
+		"exp":   expFunction,
+		"pow":   powFunction,
+	}
+}
+
+func absFunction(arguments ...interface{}) (interface{}, error) {
+
+	arguments, err := numericArguments("abs", arguments, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch arguments[0].(type) {
+	case int64:
+		value := arguments[0].(int64)
+		if value == math.MinInt64 {
+			return nil, fmt.Errorf("Function 'abs' can't take the absolute value of %d, it would overflow", value)
+		}
+		if value < 0 {
+			return -value, nil
+		}
+		return value, nil
+	case Decimal:
+		value := arguments[0].(Decimal)
+		if value.Sign() < 0 {
+			return value.Neg(), nil
+		}
+		return value, nil
+	}
+	return math.Abs(arguments[0].(float64)), nil
+}
+
+func minFunction(arguments ...interface{}) (interface{}, error) {
+	return extremeArgument("min", arguments, ltStage)
+}
+
+func maxFunction(arguments ...interface{}) (interface{}, error) {
+	return extremeArgument("max", arguments, gtStage)
+}
+
+/*
+	Returns whichever of the given [arguments] [comparator] prefers over all of the others.
+	The first of several equal arguments is returned.
+*/
+func extremeArgument(name string, arguments []interface{}, comparator evaluationOperator) (interface{}, error) {
+
+	arguments, err := numericArguments(name, arguments, 1, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := arguments[0]
+
+	for _, argument := range arguments[1:] {
+
+		preferred, err := comparator(argument, ret, nil)
+		if err != nil {
+			return nil, err
+		}
+		if preferred.(bool) {
+			ret = argument
+		}
+	}
+	return ret, nil
+}
+
+func floorFunction(arguments ...interface{}) (interface{}, error) {
+
+	arguments, err := numericArguments("floor", arguments, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch arguments[0].(type) {
+	case int64:
+		return arguments[0], nil
+	case Decimal:
+		return arguments[0].(Decimal).Round(0, big.ToNegativeInf), nil
+	}
+	return math.Floor(arguments[0].(float64)), nil
+}
+
+func ceilFunction(arguments ...interface{}) (interface{}, error) {
+
+	arguments, err := numericArguments("ceil", arguments, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch arguments[0].(type) {
+	case int64:
+		return arguments[0], nil
+	case Decimal:
+		return arguments[0].(Decimal).Round(0, big.ToPositiveInf), nil
+	}
+	return math.Ceil(arguments[0].(float64)), nil
+}
+
+/*
+	Rounds to the nearest whole number, or to the given number of places after the decimal point.
+	Halves are rounded away from zero.
+*/
+func roundFunction(arguments ...interface{}) (interface{}, error) {
+
+	var places int64
+
+	arguments, err := numericArguments("round", arguments, 1, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(arguments) > 1 {
+
+		places, err = wholeArgument("round", arguments[1])
+		if err != nil {
+			return nil, err
+		}
+		if places < 0 {
+			return nil, fmt.Errorf("Function 'round' can't round to %d places, it must not be negative", places)
+		}
+	}
+
+	switch arguments[0].(type) {
+	case int64:
+		return arguments[0], nil
+	case Decimal:
+		return arguments[0].(Decimal).Round(int(places), big.ToNearestAway), nil
+	}
+
+	value := arguments[0].(float64)
+	scale := math.Pow10(int(places))
+
+	// a float has no digits that far after the decimal point, so there's nothing to round.
+	if math.IsInf(value*scale, 0) {
+		return value, nil
+	}
+	return math.Round(value*scale) / scale, nil
+}
+
+func sqrtFunction(arguments ...interface{}) (interface{}, error) {
+
+	arguments, err := numericArguments("sqrt", arguments, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	value := toFloat64(arguments[0])
+	if value < 0 {
+		return nil, fmt.Errorf("Function 'sqrt' can't take the square root of %v, it is negative", arguments[0])
+	}
+	return floatResult(math.Sqrt(value), arguments)
+}
+
+/*
+	The natural logarithm, or the logarithm in the given base.
+*/
+func logFunction(arguments ...interface{}) (interface{}, error) {
+
+	var ret float64
+
+	arguments, err := numericArguments("log", arguments, 1, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	value := toFloat64(arguments[0])
+	if value <= 0 {
+		return nil, fmt.Errorf("Function 'log' can't take the logarithm of %v, it is not positive", arguments[0])
+	}
+
+	if len(arguments) == 1 {
+		return floatResult(math.Log(value), arguments)
+	}
+
+	base := toFloat64(arguments[1])
+	if base <= 0 || base == 1 {
+		return nil, fmt.Errorf("Function 'log' can't use %v as a base, it must be positive and not 1", arguments[1])
+	}
+
+	// these are more accurate than dividing natural logarithms.
+	switch base {
+	case 2:
+		ret = math.Log2(value)
+	case 10:
+		ret = math.Log10(value)
+	default:
+		ret = math.Log(value) / math.Log(base)
+	}
+	return floatResult(ret, arguments)
+}
+
+func expFunction(arguments ...interface{}) (interface{}, error) {
+
+	arguments, err := numericArguments("exp", arguments, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	return floatResult(math.Exp(toFloat64(arguments[0])), arguments)
+}
+
+func powFunction(arguments ...interface{}) (interface{}, error) {
+
+	arguments, err := numericArguments("pow", arguments, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+	return exponentStage(arguments[0], arguments[1], nil)
+}
+
+/*
+	Checks that there are between [minimum] and [maximum] [arguments] (or at least [minimum], if [maximum] is negative),
+	and that every one of them is a number.
+	Returns the arguments as float64, int64, or Decimal; other integer and float types (such as those returned by other functions) are widened.
+*/
+func numericArguments(name string, arguments []interface{}, minimum int, maximum int) ([]interface{}, error) {
+
+	err := checkArgumentCount(name, arguments, minimum, maximum)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]interface{}, len(arguments))
+
+	for i, argument := range arguments {
+
+		ret[i] = castToInt64(argument)
+		if !isNumber(ret[i]) {
+			return nil, fmt.Errorf("Function '%s' can't use '%v' (%T) as argument %d, it is not a number", name, argument, argument, i+1)
+		}
+	}
+	return ret, nil
+}
+
+/*
+	Checks that there are between [minimum] and [maximum] [arguments] (or at least [minimum], if [maximum] is negative).
+*/
+func checkArgumentCount(name string, arguments []interface{}, minimum int, maximum int) error {
+
+	var expected string
+
+	if len(arguments) >= minimum && (maximum < 0 || len(arguments) <= maximum) {
+		return nil
+	}
+
+	switch {
+	case maximum < 0:
+		expected = fmt.Sprintf("at least %d", minimum)
+	case minimum == maximum:
+		expected = fmt.Sprintf("%d", minimum)
+	default:
+		expected = fmt.Sprintf("%d or %d", minimum, maximum)
+	}
+
+	plural := "s"
+	if expected == "1" || expected == "at least 1" {
+		plural = ""
+	}
+	return fmt.Errorf("Function '%s' expects %s argument%s, got %d", name, expected, plural, len(arguments))
+}
+
+/*
+	Returns the given numeric [argument] as an int64, if it's a whole number which fits in one.
+*/
+func wholeArgument(name string, argument interface{}) (int64, error) {
+
+	var ret int64
+	var whole bool
+
+	argument = castToInt64(argument)
+
+	switch argument.(type) {
+	case int64:
+		return argument.(int64), nil
+	case Decimal:
+		ret, whole = argument.(Decimal).Int64()
+		whole = whole && argument.(Decimal).IsInteger()
+	case float64:
+		value := argument.(float64)
+		ret = int64(value)
+		whole = value == math.Trunc(value) && math.Abs(value) < math.MaxInt32
+	}
+
+	if !whole {
+		return 0, fmt.Errorf("Function '%s' can't use '%v', it is not a whole number", name, argument)
+	}
+	return ret, nil
+}
+
+/*
+	Gives the [result] of a function computed as a float64 as a Decimal, if any of the [arguments] it was computed from are Decimals.
+*/
+func floatResult(result float64, arguments []interface{}) (interface{}, error) {
+
+	for _, argument := range arguments {
+
+		if isDecimal(argument) {
+			return decimalResult(NewDecimalFromFloat(result))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,335 @@
+package govaluate
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of the functions returned by MathFunctions, under a specific NumericMode.
+	If [Expected] is a string, it's compared to the text of a Decimal result.
+*/
+type MathFunctionTest struct {
+	Name       string
+	Input      string
+	Mode       NumericMode
+	Parameters map[string]interface{}
+	Expected   interface{}
+}
+
+func TestMathFunctions(test *testing.T) {
+
+	mathTests := []MathFunctionTest{
+
+		MathFunctionTest{
+			Name:     "abs",
+			Input:    "abs(-1.5) + abs(2)",
+			Expected: 3.5,
+		},
+		MathFunctionTest{
+			Name:     "abs of integer",
+			Input:    "abs(-7)",
+			Mode:     INTEGER_NUMERICS,
+			Expected: int64(7),
+		},
+		MathFunctionTest{
+			Name:     "abs of decimal",
+			Input:    "abs(-1.50)",
+			Mode:     DECIMAL_NUMERICS,
+			Expected: "1.50",
+		},
+		MathFunctionTest{
+			Name:     "min and max",
+			Input:    "min(3, 1, 2) + max(3, 1, 5, 2)",
+			Expected: 6.0,
+		},
+		MathFunctionTest{
+			Name:     "min of one",
+			Input:    "min(4)",
+			Expected: 4.0,
+		},
+		MathFunctionTest{
+			Name:       "min of an array parameter",
+			Input:      "min(foo)",
+			Parameters: map[string]interface{}{"foo": []interface{}{4.0, -2.0, 9.0}},
+			Expected:   -2.0,
+		},
+		MathFunctionTest{
+			Name:     "max of integers",
+			Input:    "max(-4, 9, 2)",
+			Mode:     INTEGER_NUMERICS,
+			Expected: int64(9),
+		},
+		MathFunctionTest{
+			Name:     "max of decimals",
+			Input:    "max(0.10, 0.2, 0.15)",
+			Mode:     DECIMAL_NUMERICS,
+			Expected: "0.2",
+		},
+		MathFunctionTest{
+			Name:     "floor and ceil",
+			Input:    "floor(-1.5) + ceil(2.1)",
+			Expected: 1.0,
+		},
+		MathFunctionTest{
+			Name:     "floor of integer",
+			Input:    "floor(7 / 2)",
+			Mode:     INTEGER_NUMERICS,
+			Expected: int64(3),
+		},
+		MathFunctionTest{
+			Name:     "floor of decimal",
+			Input:    "floor(-1.25)",
+			Mode:     DECIMAL_NUMERICS,
+			Expected: "-2",
+		},
+		MathFunctionTest{
+			Name:     "ceil of decimal",
+			Input:    "ceil(1.25)",
+			Mode:     DECIMAL_NUMERICS,
+			Expected: "2",
+		},
+		MathFunctionTest{
+			Name:     "round",
+			Input:    "round(2.5) + round(-2.5)",
+			Expected: 0.0,
+		},
+		MathFunctionTest{
+			Name:     "round to places",
+			Input:    "round(3.14159, 2)",
+			Expected: 3.14,
+		},
+		MathFunctionTest{
+			Name:       "round to more places than a float has",
+			Input:      "round(foo, 20)",
+			Parameters: map[string]interface{}{"foo": 1e300},
+			Expected:   1e300,
+		},
+		MathFunctionTest{
+			Name:     "round of decimal",
+			Input:    "round(2.345, 2)",
+			Mode:     DECIMAL_NUMERICS,
+			Expected: "2.35",
+		},
+		MathFunctionTest{
+			Name:     "round of integer",
+			Input:    "round(5, 2)",
+			Mode:     INTEGER_NUMERICS,
+			Expected: int64(5),
+		},
+		MathFunctionTest{
+			Name:     "sqrt",
+			Input:    "sqrt(16)",
+			Expected: 4.0,
+		},
+		MathFunctionTest{
+			Name:     "sqrt of integer",
+			Input:    "sqrt(2) > 1",
+			Mode:     INTEGER_NUMERICS,
+			Expected: true,
+		},
+		MathFunctionTest{
+			Name:     "sqrt of decimal",
+			Input:    "sqrt(2.25)",
+			Mode:     DECIMAL_NUMERICS,
+			Expected: "1.5",
+		},
+		MathFunctionTest{
+			Name:     "log",
+			Input:    "log(1)",
+			Expected: 0.0,
+		},
+		MathFunctionTest{
+			Name:     "log in base",
+			Input:    "log(1000, 10) + log(8, 2)",
+			Expected: 6.0,
+		},
+		MathFunctionTest{
+			Name:     "exp",
+			Input:    "exp(0)",
+			Expected: 1.0,
+		},
+		MathFunctionTest{
+			Name:     "exp of decimal",
+			Input:    "exp(0)",
+			Mode:     DECIMAL_NUMERICS,
+			Expected: "1",
+		},
+		MathFunctionTest{
+			Name:     "pow",
+			Input:    "pow(2, 10)",
+			Expected: 1024.0,
+		},
+		MathFunctionTest{
+			Name:     "pow of integers",
+			Input:    "pow(3, 4)",
+			Mode:     INTEGER_NUMERICS,
+			Expected: int64(81),
+		},
+		MathFunctionTest{
+			Name:     "pow of decimals",
+			Input:    "pow(1.5, 2)",
+			Mode:     DECIMAL_NUMERICS,
+			Expected: "2.25",
+		},
+		MathFunctionTest{
+			Name:       "Nested with parameters",
+			Input:      "round(sqrt(pow(x, 2) + pow(y, 2)), 1)",
+			Parameters: map[string]interface{}{"x": 1, "y": int32(2)},
+			Expected:   2.2,
+		},
+	}
+
+	for _, mathTest := range mathTests {
+
+		options := ParsingOptions{
+			NumericMode: mathTest.Mode,
+			Functions:   MathFunctions(),
+		}
+
+		expression, err := NewEvaluableExpressionWithOptions(mathTest.Input, options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", mathTest.Name, err)
+			continue
+		}
+
+		result, err := expression.Evaluate(mathTest.Parameters)
+		if err != nil {
+			test.Errorf("Test '%s' failed: %v", mathTest.Name, err)
+			continue
+		}
+
+		if decimal, ok := result.(Decimal); ok {
+			result = decimal.String()
+		}
+
+		if result != mathTest.Expected {
+			test.Errorf("Test '%s' gave '%v' (%T), expected '%v' (%T)", mathTest.Name, result, result, mathTest.Expected, mathTest.Expected)
+		}
+	}
+}
+
+func TestMathFunctionErrors(test *testing.T) {
+
+	mathTests := []MathFunctionTest{
+
+		MathFunctionTest{
+			Name:     "Too many arguments",
+			Input:    "sqrt(1, 2)",
+			Expected: "Function 'sqrt' expects 1 argument, got 2",
+		},
+		MathFunctionTest{
+			Name:     "Too few arguments",
+			Input:    "pow(1)",
+			Expected: "Function 'pow' expects 2 arguments, got 1",
+		},
+		MathFunctionTest{
+			Name:     "Optional arguments",
+			Input:    "round(1, 2, 3)",
+			Expected: "Function 'round' expects 1 or 2 arguments, got 3",
+		},
+		MathFunctionTest{
+			Name:     "No arguments",
+			Input:    "max()",
+			Expected: "Function 'max' expects at least 1 argument, got 0",
+		},
+		MathFunctionTest{
+			Name:     "Not a number",
+			Input:    "abs('foo')",
+			Expected: "Function 'abs' can't use 'foo' (string) as argument 1, it is not a number",
+		},
+		MathFunctionTest{
+			Name:       "Later argument not a number",
+			Input:      "min(1, foo)",
+			Parameters: map[string]interface{}{"foo": true},
+			Expected:   "Function 'min' can't use 'true' (bool) as argument 2, it is not a number",
+		},
+		MathFunctionTest{
+			Name:     "Square root of a negative",
+			Input:    "sqrt(-4)",
+			Expected: "Function 'sqrt' can't take the square root of -4, it is negative",
+		},
+		MathFunctionTest{
+			Name:     "Logarithm of zero",
+			Input:    "log(0)",
+			Expected: "Function 'log' can't take the logarithm of 0, it is not positive",
+		},
+		MathFunctionTest{
+			Name:     "Logarithm in base one",
+			Input:    "log(5, 1)",
+			Expected: "Function 'log' can't use 1 as a base, it must be positive and not 1",
+		},
+		MathFunctionTest{
+			Name:     "Fractional places",
+			Input:    "round(1.5, 0.5)",
+			Expected: "Function 'round' can't use '0.5', it is not a whole number",
+		},
+		MathFunctionTest{
+			Name:     "Negative places",
+			Input:    "round(1.5, -1)",
+			Expected: "Function 'round' can't round to -1 places, it must not be negative",
+		},
+		MathFunctionTest{
+			Name:     "Overflowing absolute value",
+			Input:    "abs(-9223372036854775807 - 1)",
+			Mode:     INTEGER_NUMERICS,
+			Expected: "Function 'abs' can't take the absolute value of -9223372036854775808, it would overflow",
+		},
+		MathFunctionTest{
+			Name:     "Decimal overflow",
+			Input:    "exp(1000)",
+			Mode:     DECIMAL_NUMERICS,
+			Expected: "Unable to represent '+Inf' as a decimal",
+		},
+	}
+
+	for _, mathTest := range mathTests {
+
+		options := ParsingOptions{
+			NumericMode: mathTest.Mode,
+			Functions:   MathFunctions(),
+		}
+
+		expression, err := NewEvaluableExpressionWithOptions(mathTest.Input, options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", mathTest.Name, err)
+			continue
+		}
+
+		_, err = expression.Evaluate(mathTest.Parameters)
+
+		var functionError FunctionError
+		if !errors.As(err, &functionError) {
+			test.Errorf("Test '%s' expected a function error, got %v", mathTest.Name, err)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), mathTest.Expected.(string)) {
+			test.Errorf("Test '%s' gave error '%v', expected '%v'", mathTest.Name, err, mathTest.Expected)
+		}
+	}
+}
+
+func TestMathFunctionsCalledDirectly(test *testing.T) {
+
+	functions := MathFunctions()
+
+	// values which haven't been through an expression, such as those returned by other functions, are widened.
+	result, err := functions["max"](int32(3), uint8(9), float32(1.5))
+	if err != nil || result != int64(9) {
+		test.Errorf("Expected 9, got %v (%v)", result, err)
+	}
+
+	result, err = functions["abs"](math.Inf(-1))
+	if err != nil || result != math.Inf(1) {
+		test.Errorf("Expected +Inf, got %v (%v)", result, err)
+	}
+
+	// each call gives a separate map.
+	delete(functions, "pow")
+	if MathFunctions()["pow"] == nil {
+		test.Errorf("Expected a new map of functions from each call")
+	}
+}
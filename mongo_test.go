@@ -0,0 +1,236 @@
+package govaluate
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+/*
+	Represents a test of creating a Mongo query filter from an expression.
+*/
+type MongoQueryTest struct {
+	Name     string
+	Input    string
+	Expected map[string]interface{}
+}
+
+type document map[string]interface{}
+type list []interface{}
+
+func TestMongoQueries(test *testing.T) {
+
+	testCases := []MongoQueryTest{
+
+		MongoQueryTest{
+
+			Name:     "Equality",
+			Input:    "name == 'bob'",
+			Expected: document{"name": document{"$eq": "bob"}},
+		},
+		MongoQueryTest{
+
+			Name:     "Comparators",
+			Input:    "age >= 18 && age < 65 && name != 'x'",
+			Expected: document{"$and": list{document{"age": document{"$gte": 18.0}}, document{"age": document{"$lt": 65.0}}, document{"name": document{"$ne": "x"}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Constant on the left",
+			Input:    "18 < age",
+			Expected: document{"age": document{"$gt": 18.0}},
+		},
+		MongoQueryTest{
+
+			Name:     "Negative constant",
+			Input:    "balance > -10",
+			Expected: document{"balance": document{"$gt": -10.0}},
+		},
+		MongoQueryTest{
+
+			Name:     "Or within and",
+			Input:    "active && (role == 'admin' || role == 'owner')",
+			Expected: document{"$and": list{document{"active": document{"$eq": true}}, document{"$or": list{document{"role": document{"$eq": "admin"}}, document{"role": document{"$eq": "owner"}}}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Regex",
+			Input:    "name =~ '^a' && name !~ 'z$'",
+			Expected: document{"$and": list{document{"name": document{"$regex": "^a"}}, document{"name": document{"$not": document{"$regex": "z$"}}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Membership",
+			Input:    "status IN ('new', 'open')",
+			Expected: document{"status": document{"$in": list{"new", "open"}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Not on a field",
+			Input:    "!(age > 18)",
+			Expected: document{"age": document{"$not": document{"$gt": 18.0}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Not on several fields",
+			Input:    "!(age > 18 && active)",
+			Expected: document{"$nor": list{document{"$and": list{document{"age": document{"$gt": 18.0}}, document{"active": document{"$eq": true}}}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Accessors",
+			Input:    "user.Address.City == 'Paris'",
+			Expected: document{"user.Address.City": document{"$eq": "Paris"}},
+		},
+		MongoQueryTest{
+
+			Name:     "Times",
+			Input:    "created > '2014-07-04T00:00:00Z'",
+			Expected: document{"created": document{"$gt": time.Date(2014, time.July, 4, 0, 0, 0, 0, time.UTC)}},
+		},
+		MongoQueryTest{
+
+			Name:     "Arithmetic",
+			Input:    "price * quantity > 100",
+			Expected: document{"$expr": document{"$gt": list{document{"$multiply": list{"$price", "$quantity"}}, 100.0}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Fields compared",
+			Input:    "spent <= budget",
+			Expected: document{"$expr": document{"$lte": list{"$spent", "$budget"}}},
+		},
+		MongoQueryTest{
+
+			Name:  "Expression operators",
+			Input: "(a - b) % 2 == 0 || a ** 2 / -b != 1",
+			Expected: document{"$or": list{
+				document{"$expr": document{"$eq": list{document{"$mod": list{document{"$subtract": list{"$a", "$b"}}, 2.0}}, 0.0}}},
+				document{"$expr": document{"$ne": list{document{"$divide": list{document{"$pow": list{"$a", 2.0}}, document{"$multiply": list{-1, "$b"}}}}, 1.0}}},
+			}},
+		},
+		MongoQueryTest{
+
+			Name:     "Concatenation and literals",
+			Input:    "first + ' ' + last == '$name'",
+			Expected: document{"$expr": document{"$eq": list{document{"$concat": list{document{"$concat": list{"$first", " "}}, "$last"}}, document{"$literal": "$name"}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Ternary and coalescence",
+			Input:    "(vip ? 0 : (fee ?? 5)) < 10",
+			Expected: document{"$expr": document{"$lt": list{document{"$cond": list{"$vip", 0.0, document{"$ifNull": list{"$fee", 5.0}}}}, 10.0}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Membership in a field",
+			Input:    "tag in tags",
+			Expected: document{"$expr": document{"$in": list{"$tag", "$tags"}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Regex on a field",
+			Input:    "name =~ pattern",
+			Expected: document{"$expr": document{"$regexMatch": document{"input": "$name", "regex": "$pattern"}}},
+		},
+	}
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpression(testCase.Input)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", testCase.Name, err)
+			continue
+		}
+
+		actual, err := expression.ToMongoQuery()
+		if err != nil {
+			test.Errorf("Test '%s' failed to create query: %v", testCase.Name, err)
+			continue
+		}
+
+		expected := normalizeDocument(testCase.Expected)
+		if !reflect.DeepEqual(actual, expected) {
+			test.Errorf("Test '%s' expected %v, got %v", testCase.Name, expected, actual)
+		}
+	}
+}
+
+func TestMongoQueryFailures(test *testing.T) {
+
+	testCases := []QueryTest{
+
+		QueryTest{
+
+			Name:  "Function",
+			Input: "lower(name) == 'x'",
+			Functions: map[string]ExpressionFunction{
+				"lower": func(arguments ...interface{}) (interface{}, error) {
+					return nil, nil
+				},
+			},
+			Expected: "Unable to write function 'lower' in a Mongo query",
+		},
+		QueryTest{
+
+			Name:     "Duration",
+			Input:    "elapsed > 1h",
+			Expected: "Durations can't be written in a Mongo query",
+		},
+		QueryTest{
+
+			Name:     "Bit shift",
+			Input:    "flags << 2 > 8",
+			Expected: "Bit shifts can't be written in a Mongo query",
+		},
+		QueryTest{
+
+			Name:     "Method call",
+			Input:    "user.Name() == 'x'",
+			Expected: "Unable to write method call 'user.Name' in a Mongo query",
+		},
+	}
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpressionWithFunctions(testCase.Input, testCase.Functions)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", testCase.Name, err)
+			continue
+		}
+
+		_, err = expression.ToMongoQuery()
+		if err == nil || err.Error() != testCase.Expected {
+			test.Errorf("Test '%s' expected error '%s', got '%v'", testCase.Name, testCase.Expected, err)
+		}
+	}
+}
+
+/*
+	Turns the shorthand types used to write expected documents back into the plain maps and slices that queries are made of.
+*/
+func normalizeDocument(value map[string]interface{}) map[string]interface{} {
+	return normalizeDocumentValue(document(value)).(map[string]interface{})
+}
+
+func normalizeDocumentValue(value interface{}) interface{} {
+
+	switch value.(type) {
+	case document:
+
+		ret := make(map[string]interface{})
+		for key, element := range value.(document) {
+			ret[key] = normalizeDocumentValue(element)
+		}
+		return ret
+
+	case list:
+
+		ret := make([]interface{}, len(value.(list)))
+		for i, element := range value.(list) {
+			ret[i] = normalizeDocumentValue(element)
+		}
+		return ret
+	}
+	return value
+}
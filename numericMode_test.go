@@ -0,0 +1,367 @@
+package govaluate
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"testing"
+)
+
+/*
+	Represents a test of expression evaluation under a specific NumericMode.
+*/
+type NumericModeTest struct {
+	Name       string
+	Input      string
+	Mode       NumericMode
+	Parameters map[string]interface{}
+	Expected   interface{}
+}
+
+func TestIntegerEvaluation(test *testing.T) {
+
+	evaluationTests := []NumericModeTest{
+
+		NumericModeTest{
+
+			Name:     "Integer literal",
+			Input:    "9007199254740993",
+			Expected: int64(9007199254740993),
+		},
+		NumericModeTest{
+
+			Name:     "Integer PLUS",
+			Input:    "51 + 49",
+			Expected: int64(100),
+		},
+		NumericModeTest{
+
+			Name:     "Integer DIVIDE truncates",
+			Input:    "7 / 2",
+			Expected: int64(3),
+		},
+		NumericModeTest{
+
+			Name:     "Integer MODULUS",
+			Input:    "-7 % 3",
+			Expected: int64(-1),
+		},
+		NumericModeTest{
+
+			Name:     "Integer EXPONENT",
+			Input:    "3 ** 4",
+			Expected: int64(81),
+		},
+		NumericModeTest{
+
+			Name:     "Negative integer EXPONENT promotes",
+			Input:    "2 ** -1",
+			Expected: 0.5,
+		},
+		NumericModeTest{
+
+			Name:     "Mixed PLUS promotes",
+			Input:    "1 + 0.5",
+			Expected: 1.5,
+		},
+		NumericModeTest{
+
+			Name:     "Float literal",
+			Input:    "1.0",
+			Expected: 1.0,
+		},
+		NumericModeTest{
+
+			Name:     "Hex literal",
+			Input:    "0xFF",
+			Expected: int64(255),
+		},
+		NumericModeTest{
+
+			Name:     "Large hex literal wraps",
+			Input:    "0xFFFFFFFFFFFFFFFF",
+			Expected: int64(-1),
+		},
+		NumericModeTest{
+
+			Name:       "Large integer parameter",
+			Input:      "foo + 1",
+			Parameters: map[string]interface{}{"foo": int64(math.MaxInt64 - 1)},
+			Expected:   int64(math.MaxInt64),
+		},
+		NumericModeTest{
+
+			Name:       "Narrow integer parameters",
+			Input:      "foo + bar",
+			Parameters: map[string]interface{}{"foo": int8(1), "bar": uint32(2)},
+			Expected:   int64(3),
+		},
+		NumericModeTest{
+
+			Name:       "Oversized unsigned parameter",
+			Input:      "foo",
+			Parameters: map[string]interface{}{"foo": uint64(math.MaxUint64)},
+			Expected:   float64(math.MaxUint64),
+		},
+		NumericModeTest{
+
+			Name:       "Exact bitwise AND",
+			Input:      "foo & 0xFF",
+			Parameters: map[string]interface{}{"foo": int64(9007199254740993)},
+			Expected:   int64(1),
+		},
+		NumericModeTest{
+
+			Name:       "Exact left shift",
+			Input:      "foo << 1",
+			Parameters: map[string]interface{}{"foo": int64(1) << 60},
+			Expected:   int64(1) << 61,
+		},
+		NumericModeTest{
+
+			Name:     "Arithmetic right shift",
+			Input:    "-8 >> 1",
+			Expected: int64(-4),
+		},
+		NumericModeTest{
+
+			Name:     "Integer BITWISE NOT",
+			Input:    "~10",
+			Expected: int64(-11),
+		},
+		NumericModeTest{
+
+			Name:       "Large integer comparison",
+			Input:      "foo > 9007199254740992",
+			Parameters: map[string]interface{}{"foo": int64(9007199254740993)},
+			Expected:   true,
+		},
+		NumericModeTest{
+
+			Name:     "Mixed comparison",
+			Input:    "2 > 1.5",
+			Expected: true,
+		},
+		NumericModeTest{
+
+			Name:       "Mixed equality",
+			Input:      "foo == 1",
+			Parameters: map[string]interface{}{"foo": 1.0},
+			Expected:   true,
+		},
+		NumericModeTest{
+
+			Name:       "Mixed membership",
+			Input:      "foo in (1, 2, 3)",
+			Parameters: map[string]interface{}{"foo": 2.0},
+			Expected:   true,
+		},
+		NumericModeTest{
+
+			Name:       "Integer accessor",
+			Input:      "foo.Int + 1",
+			Parameters: map[string]interface{}{"foo": dummyParameterInstance},
+			Expected:   int64(102),
+		},
+		NumericModeTest{
+
+			Name:     "Integer string concatenation",
+			Input:    "'foo' + 1",
+			Expected: "foo1",
+		},
+	}
+
+	for i := range evaluationTests {
+		evaluationTests[i].Mode = INTEGER_NUMERICS
+	}
+	runNumericModeTests(evaluationTests, test)
+}
+
+func TestIntegerDivisionByZero(test *testing.T) {
+
+	for _, input := range []string{"foo / 0", "foo % 0"} {
+
+		expression, err := NewEvaluableExpressionWithOptions(input, ParsingOptions{NumericMode: INTEGER_NUMERICS})
+		if err != nil {
+			test.Fatalf("Unable to parse '%s': %v", input, err)
+		}
+
+		_, err = expression.Evaluate(map[string]interface{}{"foo": 1})
+		if err == nil {
+			test.Errorf("Expected division by zero error from '%s'", input)
+		}
+	}
+}
+
+func TestDecimalEvaluation(test *testing.T) {
+
+	evaluationTests := []NumericModeTest{
+
+		NumericModeTest{
+
+			Name:     "Exact addition",
+			Input:    "0.1 + 0.2",
+			Expected: "0.3",
+		},
+		NumericModeTest{
+
+			Name:     "Exact equality",
+			Input:    "0.1 + 0.2 == 0.3",
+			Expected: true,
+		},
+		NumericModeTest{
+
+			Name:     "Scale is kept",
+			Input:    "1.50 * 2",
+			Expected: "3.00",
+		},
+		NumericModeTest{
+
+			Name:       "Float parameter",
+			Input:      "price * 3",
+			Parameters: map[string]interface{}{"price": 0.1},
+			Expected:   "0.3",
+		},
+		NumericModeTest{
+
+			Name:       "Integer parameter",
+			Input:      "quantity * 1.25",
+			Parameters: map[string]interface{}{"quantity": 3},
+			Expected:   "3.75",
+		},
+		NumericModeTest{
+
+			Name:     "Division precision",
+			Input:    "10 / 3",
+			Expected: "3.3333333333333333",
+		},
+		NumericModeTest{
+
+			Name:     "Modulus",
+			Input:    "7.5 % 2",
+			Expected: "1.5",
+		},
+		NumericModeTest{
+
+			Name:     "Negative exponent",
+			Input:    "2 ** -2",
+			Expected: "0.2500000000000000",
+		},
+		NumericModeTest{
+
+			Name:     "Negation",
+			Input:    "-(1.5 - 2)",
+			Expected: "0.5",
+		},
+		NumericModeTest{
+
+			Name:     "Bitwise AND",
+			Input:    "0xFF & 15",
+			Expected: "15",
+		},
+		NumericModeTest{
+
+			Name:     "Comparator",
+			Input:    "19.99 < 20",
+			Expected: true,
+		},
+		NumericModeTest{
+
+			Name:       "Membership",
+			Input:      "total in (1.5, 2.50)",
+			Parameters: map[string]interface{}{"total": 2.5},
+			Expected:   true,
+		},
+		NumericModeTest{
+
+			Name:     "Concatenation",
+			Input:    "'$' + 1.50",
+			Expected: "$1.50",
+		},
+		NumericModeTest{
+
+			Name:       "Decimal accessor",
+			Input:      "foo.Int / 4",
+			Parameters: map[string]interface{}{"foo": dummyParameterInstance},
+			Expected:   "25.2500000000000000",
+		},
+	}
+
+	for i := range evaluationTests {
+		evaluationTests[i].Mode = DECIMAL_NUMERICS
+	}
+	runNumericModeTests(evaluationTests, test)
+}
+
+func TestDecimalDivisionOptions(test *testing.T) {
+
+	options := ParsingOptions{
+		NumericMode:      DECIMAL_NUMERICS,
+		DecimalPrecision: 2,
+		DecimalRounding:  big.ToNearestAway,
+	}
+
+	expression, err := NewEvaluableExpressionWithOptions("total / 8", options)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	result, err := expression.Evaluate(map[string]interface{}{"total": 1})
+	if err != nil {
+		test.Fatalf("Unexpected evaluation error: %v", err)
+	}
+	if fmt.Sprintf("%v", result) != "0.13" {
+		test.Errorf("Expected 0.13, got %v", result)
+	}
+
+	// constant divisions are computed while parsing, and must use the same options.
+	expression, _ = NewEvaluableExpressionWithOptions("1 / 8", options)
+	result, _ = expression.Evaluate(nil)
+	if fmt.Sprintf("%v", result) != "0.13" {
+		test.Errorf("Expected 0.13 from constant division, got %v", result)
+	}
+
+	expression, _ = NewEvaluableExpressionWithOptions("total / 0", options)
+	_, err = expression.Evaluate(map[string]interface{}{"total": 1})
+	if err == nil {
+		test.Errorf("Expected division by zero error")
+	}
+}
+
+func runNumericModeTests(evaluationTests []NumericModeTest, test *testing.T) {
+
+	fmt.Printf("Running %d numeric mode test cases...\n", len(evaluationTests))
+
+	for _, evaluationTest := range evaluationTests {
+
+		options := ParsingOptions{
+			NumericMode: evaluationTest.Mode,
+		}
+
+		expression, err := NewEvaluableExpressionWithOptions(evaluationTest.Input, options)
+		if err != nil {
+			test.Logf("Test '%s' failed to parse: '%s'", evaluationTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		result, err := expression.Evaluate(evaluationTest.Parameters)
+		if err != nil {
+			test.Logf("Test '%s' failed", evaluationTest.Name)
+			test.Logf("Encountered error: %s", err.Error())
+			test.Fail()
+			continue
+		}
+
+		// decimals aren't comparable with ==, so are compared by their exact text.
+		if decimal, ok := result.(Decimal); ok {
+			result = decimal.String()
+		}
+
+		if result != evaluationTest.Expected {
+			test.Logf("Test '%s' failed", evaluationTest.Name)
+			test.Logf("Evaluation result '%v' (%T) does not match expected: '%v' (%T)", result, result, evaluationTest.Expected, evaluationTest.Expected)
+			test.Fail()
+		}
+	}
+}
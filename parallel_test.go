@@ -0,0 +1,280 @@
+package govaluate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentEvaluation(test *testing.T) {
+
+	var group sync.WaitGroup
+
+	functions := map[string]ExpressionFunction{
+		"double": func(arguments ...interface{}) (interface{}, error) {
+			return arguments[0].(float64) * 2, nil
+		},
+	}
+
+	// one expression, with a precompiled regex, a function and an accessor, shared by every goroutine.
+	expression, err := NewEvaluableExpressionWithFunctions(
+		"name =~ '^row[0-9]+$' && double(foo) == foo * 2 && foostruct.Int == foo ? name + foostruct.Func() : 'wrong'", functions)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+	compiled := expression.Compile()
+
+	failures := make(chan string, 100)
+
+	for worker := 0; worker < 8; worker++ {
+
+		group.Add(1)
+		go func(worker int) {
+
+			defer group.Done()
+
+			for i := 0; i < 200; i++ {
+
+				row := worker*1000 + i
+				parameters := map[string]interface{}{
+					"foo":       row,
+					"name":      fmt.Sprintf("row%d", row),
+					"foostruct": dummyParameter{Int: row},
+				}
+				expected := fmt.Sprintf("row%dfunk", row)
+
+				result, err := expression.Evaluate(parameters)
+				if err != nil || result != expected {
+					failures <- fmt.Sprintf("expected '%s', got '%v' (%v)", expected, result, err)
+					return
+				}
+
+				result, err = compiled.Evaluate(parameters)
+				if err != nil || result != expected {
+					failures <- fmt.Sprintf("expected compiled '%s', got '%v' (%v)", expected, result, err)
+					return
+				}
+			}
+		}(worker)
+	}
+
+	group.Wait()
+	close(failures)
+
+	for failure := range failures {
+		test.Errorf("Concurrent evaluation failed: %s", failure)
+	}
+}
+
+func TestParallelEvaluation(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo * 2")
+
+	rows := make([]Parameters, 1000)
+	for i := range rows {
+		rows[i] = MapParameters{"foo": i}
+	}
+	rows[500] = MapParameters{}
+
+	results, failures := expression.EvalParallel(context.Background(), rows, ParallelOptions{Workers: 7})
+
+	for i := range rows {
+
+		if i == 500 {
+
+			var missingError MissingParameterError
+			if !errors.As(failures[i], &missingError) {
+				test.Errorf("Expected row 500 to be missing its parameter, got %v", failures[i])
+			}
+			continue
+		}
+
+		if failures[i] != nil || results[i] != float64(i*2) {
+			test.Errorf("Expected row %d to be %d, got %v (%v)", i, i*2, results[i], failures[i])
+			break
+		}
+	}
+}
+
+func TestParallelStopOnError(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo * 2")
+
+	rows := make([]Parameters, 1000)
+	for i := range rows {
+		rows[i] = MapParameters{"foo": i}
+	}
+	rows[10] = MapParameters{"foo": "bar"}
+
+	results, failures := expression.EvalParallel(context.Background(), rows, ParallelOptions{Workers: 4, StopOnError: true})
+
+	for i := 0; i < 10; i++ {
+		if failures[i] != nil || results[i] != float64(i*2) {
+			test.Errorf("Expected row %d before the failure to be evaluated, got %v (%v)", i, results[i], failures[i])
+		}
+	}
+
+	var typeError TypeError
+	if !errors.As(failures[10], &typeError) {
+		test.Errorf("Expected row 10 to fail with a type error, got %v", failures[10])
+	}
+
+	if failures[len(rows)-1] != EVALUATION_STOPPED {
+		test.Errorf("Expected the last row to be stopped, got %v (%v)", results[len(rows)-1], failures[len(rows)-1])
+	}
+}
+
+func TestParallelStopOrder(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo * 2")
+
+	rows := make([]Parameters, 200)
+	for i := range rows {
+
+		// every tenth row fails, so failures race with each other, and with rows being claimed.
+		if i%10 == 9 {
+			rows[i] = MapParameters{"foo": "bar"}
+		} else {
+			rows[i] = MapParameters{"foo": i}
+		}
+	}
+
+	for attempt := 0; attempt < 50; attempt++ {
+
+		_, failures := expression.EvalParallel(context.Background(), rows, ParallelOptions{Workers: 8, StopOnError: true})
+
+		for i, failure := range failures {
+
+			if failure == EVALUATION_STOPPED {
+				test.Fatalf("Expected the first error to be a failure, but row %d was stopped", i)
+			}
+			if failure != nil {
+				break
+			}
+		}
+	}
+}
+
+func TestParallelCancellation(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo * 2")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, failures := expression.EvalParallel(ctx, []Parameters{MapParameters{"foo": 1}}, ParallelOptions{})
+	if failures[0] != context.Canceled {
+		test.Errorf("Expected cancellation error, got %v", failures[0])
+	}
+
+	results, failures := expression.EvalParallel(ctx, nil, ParallelOptions{})
+	if len(results) != 0 || len(failures) != 0 {
+		test.Errorf("Expected no results for no rows")
+	}
+}
+
+func TestStreamEvaluation(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"wait": func(arguments ...interface{}) (interface{}, error) {
+
+			// later rows finish first, so that results have to be put back in order.
+			time.Sleep(time.Duration(arguments[0].(float64)) * time.Microsecond)
+			return arguments[0], nil
+		},
+	}
+
+	expression, _ := NewEvaluableExpressionWithFunctions("wait(delay)", functions)
+	rows := make(chan Parameters)
+
+	go func() {
+		for i := 0; i < 200; i++ {
+			rows <- MapParameters{"delay": 200 - i}
+		}
+		close(rows)
+	}()
+
+	count := 0
+	for result := range expression.EvalStream(context.Background(), rows, ParallelOptions{Workers: 8}) {
+
+		if result.Index != count || result.Error != nil || result.Value != float64(200-count) {
+			test.Errorf("Expected result %d to be %d, got %d: %v (%v)", count, 200-count, result.Index, result.Value, result.Error)
+			break
+		}
+		count++
+	}
+
+	if count != 200 {
+		test.Errorf("Expected 200 results, got %d", count)
+	}
+}
+
+func TestStreamStopOnError(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("10 / foo")
+	rows := make(chan Parameters)
+	done := make(chan struct{})
+
+	go func() {
+
+		defer close(done)
+
+		for i := 0; i < 100; i++ {
+
+			parameters := MapParameters{"foo": i + 1}
+			if i == 20 {
+				parameters = MapParameters{}
+			}
+
+			// once evaluation stops, no more rows are received.
+			select {
+			case rows <- parameters:
+			case <-time.After(50 * time.Millisecond):
+				return
+			}
+		}
+		close(rows)
+	}()
+
+	var results []EvaluationResult
+	for result := range expression.EvalStream(context.Background(), rows, ParallelOptions{Workers: 3, StopOnError: true}) {
+		results = append(results, result)
+	}
+
+	if len(results) != 21 || results[20].Error == nil {
+		test.Errorf("Expected 21 results ending with an error, got %d", len(results))
+	}
+
+	<-done
+}
+
+func TestStreamCancellation(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo")
+	rows := make(chan Parameters)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := expression.EvalStream(ctx, rows, ParallelOptions{Workers: 2})
+
+	rows <- MapParameters{"foo": 1}
+
+	result := <-results
+	if result.Value != 1.0 {
+		test.Errorf("Expected 1, got %v (%v)", result.Value, result.Error)
+	}
+
+	// the stream is closed even though the rows never are.
+	cancel()
+
+	select {
+	case _, open := <-results:
+		for open {
+			_, open = <-results
+		}
+	case <-time.After(time.Second):
+		test.Errorf("Expected the stream to be closed after cancellation")
+	}
+}
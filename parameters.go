@@ -1,9 +1,5 @@
 package govaluate
 
-import (
-	"errors"
-)
-
 /*
 	Parameters is a collection of named parameters that can be used by an EvaluableExpression to retrieve parameters
 	when an expression tries to use them.
@@ -24,8 +20,7 @@ func (p MapParameters) Get(name string) (interface{}, error) {
 	value, found := p[name]
 
 	if !found {
-		errorMessage := "No parameter '" + name + "' found."
-		return nil, errors.New(errorMessage)
+		return nil, MissingParameterError{Name: name}
 	}
 
 	return value, nil
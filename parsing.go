@@ -2,8 +2,8 @@ package govaluate
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
+	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
@@ -11,24 +11,64 @@ import (
 	"unicode"
 )
 
-func parseTokens(expression string, functions map[string]ExpressionFunction) ([]ExpressionToken, error) {
+func parseTokens(expression string, options ParsingOptions) ([]ExpressionToken, []tokenSource, error) {
 
 	var ret []ExpressionToken
+	var sources []tokenSource
+	var errs []error
+	var err error
+
+	ret, sources, _, errs = readTokens(expression, options, false)
+	if len(errs) > 0 {
+		return ret, sources, errs[0]
+	}
+
+	err = checkBalance(ret, sources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ret, sources, nil
+}
+
+/*
+	Reads every token from the given [expression], along with the source of each one.
+	If [recovers] is false, this stops at the first token which can't be read, and returns only that error.
+	Otherwise, such tokens are skipped, and reading carries on after them so that every error can be found.
+	The indices of tokens which directly follow a skipped token are returned in the map,
+	since their place in the expression's syntax can't be known.
+*/
+func readTokens(expression string, options ParsingOptions, recovers bool) ([]ExpressionToken, []tokenSource, map[int]bool, []error) {
+
+	var ret []ExpressionToken
+	var sources []tokenSource
+	var resumed map[int]bool
+	var errs []error
 	var token ExpressionToken
+	var source tokenSource
 	var stream *lexerStream
 	var state lexerState
 	var err error
 	var found bool
 
+	resumed = make(map[int]bool)
 	stream = newLexerStream(expression)
 	state = validLexerStates[0]
 
 	for stream.canRead() {
 
-		token, err, found = readToken(stream, state, functions)
+		token, source, err, found = readToken(stream, state, options)
 
 		if err != nil {
-			return ret, err
+
+			errs = append(errs, err)
+			if !recovers {
+				return ret, sources, resumed, errs
+			}
+
+			resumed[len(ret)] = true
+			state = validLexerStates[0]
+			continue
 		}
 
 		if !found {
@@ -37,32 +77,36 @@ func parseTokens(expression string, functions map[string]ExpressionFunction) ([]
 
 		state, err = getLexerStateForToken(token.Kind)
 		if err != nil {
-			return ret, err
+
+			errs = append(errs, SyntaxError{err.Error(), source.start, findTokenSymbol(token), token})
+			if !recovers {
+				return ret, sources, resumed, errs
+			}
 		}
 
 		// append this valid token
 		ret = append(ret, token)
+		sources = append(sources, source)
 	}
 
-	err = checkBalance(ret)
-	if err != nil {
-		return nil, err
-	}
-
-	return ret, nil
+	return ret, sources, resumed, errs
 }
 
-func readToken(stream *lexerStream, state lexerState, functions map[string]ExpressionFunction) (ExpressionToken, error, bool) {
+func readToken(stream *lexerStream, state lexerState, options ParsingOptions) (ExpressionToken, tokenSource, error, bool) {
 
 	var function ExpressionFunction
+	var contextFunction ContextExpressionFunction
 	var ret ExpressionToken
+	var source tokenSource
 	var tokenValue interface{}
 	var tokenTime time.Time
+	var tokenDuration time.Duration
 	var tokenString string
 	var kind TokenKind
 	var character rune
 	var found bool
 	var completed bool
+	var start int
 	var err error
 
 	// numeric is 0-9, or . or 0x followed by digits
@@ -80,6 +124,7 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 		}
 
 		kind = UNKNOWN
+		start = stream.position - 1
 
 		// numeric constant
 		if isNumeric(character) {
@@ -93,11 +138,19 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 
 					if err != nil {
 						errorMsg := fmt.Sprintf("Unable to parse hex value '%v' to uint64\n", tokenString)
-						return ExpressionToken{}, errors.New(errorMsg), false
+						return ExpressionToken{}, tokenSource{}, ParseError{errorMsg, stream.positionOf(start), ExpressionToken{Kind: NUMERIC, Value: tokenString}}, false
 					}
 
 					kind = NUMERIC
-					tokenValue = float64(tokenValueInt)
+					switch options.NumericMode {
+					case INTEGER_NUMERICS:
+						// hex literals are usually masks, so values above MaxInt64 are kept as their two's complement.
+						tokenValue = int64(tokenValueInt)
+					case DECIMAL_NUMERICS:
+						tokenValue = Decimal{new(big.Int).SetUint64(tokenValueInt), 0}
+					default:
+						tokenValue = float64(tokenValueInt)
+					}
 					break
 				} else {
 					stream.rewind(1)
@@ -105,11 +158,42 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 			}
 
 			tokenString = readTokenUntilFalse(stream, isNumeric)
+
+			// a number immediately followed by a unit (such as "15m" or "1h30m") is a duration.
+			tokenDuration, found = tryReadDuration(stream, tokenString)
+			if found {
+				kind = DURATION
+				tokenValue = tokenDuration
+				break
+			}
+
+			if options.NumericMode == DECIMAL_NUMERICS {
+
+				tokenValue, err = ParseDecimal(tokenString)
+				if err != nil {
+					errorMsg := fmt.Sprintf("Unable to parse numeric value '%v' to decimal\n", tokenString)
+					return ExpressionToken{}, tokenSource{}, ParseError{errorMsg, stream.positionOf(start), ExpressionToken{Kind: NUMERIC, Value: tokenString}}, false
+				}
+				kind = NUMERIC
+				break
+			}
+
+			if options.NumericMode == INTEGER_NUMERICS && !strings.Contains(tokenString, ".") {
+
+				tokenValue, err = strconv.ParseInt(tokenString, 10, 64)
+				if err != nil {
+					errorMsg := fmt.Sprintf("Unable to parse numeric value '%v' to int64\n", tokenString)
+					return ExpressionToken{}, tokenSource{}, ParseError{errorMsg, stream.positionOf(start), ExpressionToken{Kind: NUMERIC, Value: tokenString}}, false
+				}
+				kind = NUMERIC
+				break
+			}
+
 			tokenValue, err = strconv.ParseFloat(tokenString, 64)
 
 			if err != nil {
 				errorMsg := fmt.Sprintf("Unable to parse numeric value '%v' to float64\n", tokenString)
-				return ExpressionToken{}, errors.New(errorMsg), false
+				return ExpressionToken{}, tokenSource{}, ParseError{errorMsg, stream.positionOf(start), ExpressionToken{Kind: NUMERIC, Value: tokenString}}, false
 			}
 			kind = NUMERIC
 			break
@@ -130,7 +214,7 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 			kind = VARIABLE
 
 			if !completed {
-				return ExpressionToken{}, errors.New("Unclosed parameter bracket"), false
+				return ExpressionToken{}, tokenSource{}, ParseError{"Unclosed parameter bracket", stream.positionOf(start), ExpressionToken{Kind: VARIABLE, Value: tokenValue}}, false
 			}
 
 			// above method normally rewinds us to the closing bracket, which we want to skip.
@@ -169,12 +253,18 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 			}
 
 			// function?
-			function, found = functions[tokenString]
+			function, found = options.Functions[tokenString]
 			if found {
 				kind = FUNCTION
 				tokenValue = function
 			}
 
+			contextFunction, found = options.ContextFunctions[tokenString]
+			if found {
+				kind = FUNCTION
+				tokenValue = contextFunction
+			}
+
 			// accessor?
 			accessorIndex := strings.Index(tokenString, ".")
 			if accessorIndex > 0 {
@@ -182,7 +272,7 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 				// check that it doesn't end with a hanging period
 				if tokenString[len(tokenString)-1] == '.' {
 					errorMsg := fmt.Sprintf("Hanging accessor on token '%s'", tokenString)
-					return ExpressionToken{}, errors.New(errorMsg), false
+					return ExpressionToken{}, tokenSource{}, ParseError{errorMsg, stream.positionOf(start), ExpressionToken{Kind: ACCESSOR, Value: tokenString}}, false
 				}
 
 				kind = ACCESSOR
@@ -196,7 +286,7 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 
 					if unicode.ToUpper(firstCharacter) != firstCharacter {
 						errorMsg := fmt.Sprintf("Unable to access unexported field '%s' in token '%s'", splits[i], tokenString)
-						return ExpressionToken{}, errors.New(errorMsg), false
+						return ExpressionToken{}, tokenSource{}, ParseError{errorMsg, stream.positionOf(start), ExpressionToken{Kind: ACCESSOR, Value: tokenString}}, false
 					}
 				}
 			}
@@ -207,7 +297,7 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 			tokenValue, completed = readUntilFalse(stream, true, false, true, isNotQuote)
 
 			if !completed {
-				return ExpressionToken{}, errors.New("Unclosed string literal"), false
+				return ExpressionToken{}, tokenSource{}, ParseError{"Unclosed string literal", stream.positionOf(start), ExpressionToken{Kind: STRING, Value: tokenValue}}, false
 			}
 
 			// advance the stream one position, since reading until false assumes the terminator is a real token
@@ -279,13 +369,19 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 		}
 
 		errorMessage := fmt.Sprintf("Invalid token: '%s'", tokenString)
-		return ret, errors.New(errorMessage), false
+		return ret, source, ParseError{errorMessage, stream.positionOf(start), ExpressionToken{Kind: UNKNOWN, Value: tokenString}}, false
 	}
 
 	ret.Kind = kind
 	ret.Value = tokenValue
+	source.start = stream.positionOf(start)
+	source.end = stream.positionOf(stream.tokenEnd(start))
 
-	return ret, nil, (kind != UNKNOWN)
+	if kind == FUNCTION {
+		source.name = tokenString
+	}
+
+	return ret, source, nil, (kind != UNKNOWN)
 }
 
 func readTokenUntilFalse(stream *lexerStream, condition func(rune) bool) string {
@@ -348,10 +444,24 @@ func readUntilFalse(stream *lexerStream, includeWhitespace bool, breakWhitespace
 	Checks to see if any optimizations can be performed on the given [tokens], which form a complete, valid expression.
 	The returns slice will represent the optimized (or unmodified) list of tokens to use.
 */
-func optimizeTokens(tokens []ExpressionToken) ([]ExpressionToken, error) {
+func optimizeTokens(tokens []ExpressionToken, sources []tokenSource) ([]ExpressionToken, error) {
+
+	errs := compilePatterns(tokens, sources, false)
+	if len(errs) > 0 {
+		return tokens, errs[0]
+	}
+	return tokens, nil
+}
+
+/*
+	Precompiles every constant regex pattern in [tokens], replacing their tokens with PATTERN tokens.
+	If [recovers] is false, stops at (and returns) the first pattern which doesn't compile. Otherwise, returns errors for all of them.
+*/
+func compilePatterns(tokens []ExpressionToken, sources []tokenSource, recovers bool) []error {
 
 	var token ExpressionToken
 	var symbol OperatorSymbol
+	var errs []error
 	var err error
 	var index int
 
@@ -367,6 +477,11 @@ func optimizeTokens(tokens []ExpressionToken) ([]ExpressionToken, error) {
 			continue
 		}
 
+		// only possible in expressions with syntax errors, which are being validated.
+		if index+1 >= len(tokens) {
+			break
+		}
+
 		index++
 		token = tokens[index]
 		if token.Kind == STRING {
@@ -375,43 +490,65 @@ func optimizeTokens(tokens []ExpressionToken) ([]ExpressionToken, error) {
 			token.Value, err = regexp.Compile(token.Value.(string))
 
 			if err != nil {
-				return tokens, err
+
+				errs = append(errs, ParseError{err.Error(), sources[index].start, tokens[index]})
+				if !recovers {
+					return errs
+				}
+				continue
 			}
 
 			tokens[index] = token
 		}
 	}
-	return tokens, nil
+	return errs
 }
 
 /*
 	Checks the balance of tokens which have multiple parts, such as parenthesis.
 */
-func checkBalance(tokens []ExpressionToken) error {
+func checkBalance(tokens []ExpressionToken, sources []tokenSource) error {
 
-	var stream *tokenStream
-	var token ExpressionToken
-	var parens int
+	var openings, closings []int
+	var index int
+
+	openings, closings = findUnbalancedParens(tokens)
+
+	if len(openings) > len(closings) {
+		index = openings[len(openings)-1]
+		return SyntaxError{"Unbalanced parenthesis", sources[index].start, findTokenSymbol(tokens[index]), tokens[index]}
+	}
+	if len(closings) > len(openings) {
+		index = closings[0]
+		return SyntaxError{"Unbalanced parenthesis", sources[index].start, findTokenSymbol(tokens[index]), tokens[index]}
+	}
+	return nil
+}
+
+/*
+	Returns the indices of every opening paren which is never closed, and every closing paren which was never opened.
+*/
+func findUnbalancedParens(tokens []ExpressionToken) ([]int, []int) {
 
-	stream = newTokenStream(tokens)
+	var openings, closings []int
 
-	for stream.hasNext() {
+	for index, token := range tokens {
 
-		token = stream.next()
 		if token.Kind == CLAUSE {
-			parens++
+			openings = append(openings, index)
 			continue
 		}
 		if token.Kind == CLAUSE_CLOSE {
-			parens--
+
+			if len(openings) > 0 {
+				openings = openings[:len(openings)-1]
+			} else {
+				closings = append(closings, index)
+			}
 			continue
 		}
 	}
-
-	if parens != 0 {
-		return errors.New("Unbalanced parenthesis")
-	}
-	return nil
+	return openings, closings
 }
 
 func isDigit(character rune) bool {
@@ -465,6 +602,44 @@ func isNotClosingBracket(character rune) bool {
 	return character != ']'
 }
 
+func isDurationCharacter(character rune) bool {
+
+	return unicode.IsLetter(character) ||
+		unicode.IsDigit(character) ||
+		character == '.'
+}
+
+/*
+	Checks to see if the number [numberString] which was just read is immediately followed by a duration unit.
+	If so, reads the rest of the duration (e.g., "m" or "h30m") and returns it.
+	Otherwise, the stream is left where it was, and false is returned through the second return.
+*/
+func tryReadDuration(stream *lexerStream, numberString string) (time.Duration, bool) {
+
+	var suffix string
+	var start int
+
+	// reading the number may have consumed a trailing space, in which case no unit follows it.
+	if !stream.canRead() || unicode.IsSpace(stream.source[stream.position-1]) {
+		return 0, false
+	}
+
+	start = stream.position
+	if !unicode.IsLetter(stream.readCharacter()) {
+		stream.position = start
+		return 0, false
+	}
+
+	suffix = readTokenUntilFalse(stream, isDurationCharacter)
+
+	duration, err := time.ParseDuration(numberString + suffix)
+	if err != nil {
+		stream.position = start
+		return 0, false
+	}
+	return duration, true
+}
+
 /*
 	Attempts to parse the [candidate] as a Time.
 	Tries a series of standardized date formats, returns the Time if one applies,
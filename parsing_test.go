@@ -140,6 +140,64 @@ func TestConstantParsing(test *testing.T) {
 				},
 			},
 		},
+		TokenParsingTest{
+
+			Name:  "Single duration",
+			Input: "15m",
+			Expected: []ExpressionToken{
+				ExpressionToken{
+					Kind:  DURATION,
+					Value: 15 * time.Minute,
+				},
+			},
+		},
+		TokenParsingTest{
+
+			Name:  "Single compound duration",
+			Input: "1h30m",
+			Expected: []ExpressionToken{
+				ExpressionToken{
+					Kind:  DURATION,
+					Value: 90 * time.Minute,
+				},
+			},
+		},
+		TokenParsingTest{
+
+			Name:  "Single fractional duration",
+			Input: "1.5s",
+			Expected: []ExpressionToken{
+				ExpressionToken{
+					Kind:  DURATION,
+					Value: 1500 * time.Millisecond,
+				},
+			},
+		},
+		TokenParsingTest{
+
+			Name:  "Numeric followed by comparator",
+			Input: "5 in (5)",
+			Expected: []ExpressionToken{
+				ExpressionToken{
+					Kind:  NUMERIC,
+					Value: 5.0,
+				},
+				ExpressionToken{
+					Kind:  COMPARATOR,
+					Value: "in",
+				},
+				ExpressionToken{
+					Kind: CLAUSE,
+				},
+				ExpressionToken{
+					Kind:  NUMERIC,
+					Value: 5.0,
+				},
+				ExpressionToken{
+					Kind: CLAUSE_CLOSE,
+				},
+			},
+		},
 		TokenParsingTest{
 
 			Name:  "Single boolean",
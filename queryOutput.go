@@ -0,0 +1,125 @@
+package govaluate
+
+import (
+	"regexp"
+	"strings"
+)
+
+/*
+	Returns the field path of a parameter or accessor, or false if the stage is anything else.
+*/
+func findFieldPath(stage *evaluationStage) (string, bool) {
+
+	switch stage.symbol {
+	case VALUE:
+		return stage.token.Value.(string), true
+	case ACCESS:
+
+		// accessors with arguments are method calls, not fields.
+		if stage.rightStage != nil {
+			return "", false
+		}
+		return strings.Join(stage.token.Value.([]string), "."), true
+	}
+	return "", false
+}
+
+/*
+	Returns the value of the given stage, if it's a constant which can be written into a query filter.
+*/
+func findConstant(stage *evaluationStage, makeValue func(ExpressionToken) (interface{}, error)) (interface{}, bool) {
+
+	switch stage.symbol {
+	case LITERAL:
+
+		if stage.token.Kind == PATTERN {
+			return nil, false
+		}
+
+		value, err := makeValue(stage.token)
+		return value, err == nil
+
+	case NOOP:
+
+		if stage.rightStage == nil {
+			return nil, false
+		}
+		return findConstant(stage.rightStage, makeValue)
+
+	case NEGATE:
+
+		value, isValue := findConstant(stage.rightStage, makeValue)
+		if !isValue {
+			return nil, false
+		}
+
+		switch value.(type) {
+		case float64:
+			return -value.(float64), true
+		case int64:
+			return -value.(int64), true
+		}
+	}
+	return nil, false
+}
+
+/*
+	Returns the values of a parenthesized list of constants, such as the right side of `foo in (1, 2, 3)`.
+*/
+func findConstantList(stage *evaluationStage, makeValue func(ExpressionToken) (interface{}, error)) ([]interface{}, bool) {
+
+	if stage.symbol != NOOP {
+		return nil, false
+	}
+
+	ret := []interface{}{}
+	if stage.rightStage == nil {
+		return ret, true
+	}
+
+	for _, element := range findListElements(stage.rightStage) {
+
+		value, isValue := findConstant(element, makeValue)
+		if !isValue {
+			return nil, false
+		}
+		ret = append(ret, value)
+	}
+	return ret, true
+}
+
+/*
+	Returns the text of a constant pattern (or string used as one), such as the right side of `foo =~ '^a'`.
+*/
+func findConstantPattern(stage *evaluationStage) (string, bool) {
+
+	if stage.symbol != LITERAL {
+		return "", false
+	}
+
+	switch stage.token.Kind {
+	case PATTERN:
+		return stage.token.Value.(*regexp.Regexp).String(), true
+	case STRING:
+		return stage.token.Value.(string), true
+	}
+	return "", false
+}
+
+/*
+	Returns the comparator which means the same thing with its sides swapped.
+*/
+func flipComparator(symbol OperatorSymbol) OperatorSymbol {
+
+	switch symbol {
+	case GT:
+		return LT
+	case LT:
+		return GT
+	case GTE:
+		return LTE
+	case LTE:
+		return GTE
+	}
+	return symbol
+}
@@ -1,9 +1,16 @@
 package govaluate
 
+import (
+	"math"
+	"math/big"
+	"strconv"
+)
+
 // sanitizedParameters is a wrapper for Parameters that does sanitization as
 // parameters are accessed.
 type sanitizedParameters struct {
-	orig Parameters
+	orig        Parameters
+	numericMode NumericMode
 }
 
 func (p sanitizedParameters) Get(key string) (interface{}, error) {
@@ -12,7 +19,22 @@ func (p sanitizedParameters) Get(key string) (interface{}, error) {
 		return nil, err
 	}
 
-	return castToFloat64(value), nil
+	return sanitizeNumeric(value, p.numericMode), nil
+}
+
+/*
+	Converts the given [value] to the representation that [mode] uses for numbers.
+	Non-numeric values are returned unmodified.
+*/
+func sanitizeNumeric(value interface{}, mode NumericMode) interface{} {
+
+	switch mode {
+	case INTEGER_NUMERICS:
+		return castToInt64(value)
+	case DECIMAL_NUMERICS:
+		return castToDecimal(value)
+	}
+	return castToFloat64(value)
 }
 
 func castToFloat64(value interface{}) interface{} {
@@ -41,3 +63,74 @@ func castToFloat64(value interface{}) interface{} {
 
 	return value
 }
+
+/*
+	Converts all integers to int64, and all floats to float64.
+	Unsigned integers too large to be an int64 are converted to float64 instead of overflowing.
+*/
+func castToInt64(value interface{}) interface{} {
+	switch value.(type) {
+	case uint8:
+		return int64(value.(uint8))
+	case uint16:
+		return int64(value.(uint16))
+	case uint32:
+		return int64(value.(uint32))
+	case uint64:
+		if value.(uint64) > math.MaxInt64 {
+			return float64(value.(uint64))
+		}
+		return int64(value.(uint64))
+	case uint:
+		if uint64(value.(uint)) > math.MaxInt64 {
+			return float64(value.(uint))
+		}
+		return int64(value.(uint))
+	case int8:
+		return int64(value.(int8))
+	case int16:
+		return int64(value.(int16))
+	case int32:
+		return int64(value.(int32))
+	case int:
+		return int64(value.(int))
+	case float32:
+		return float64(value.(float32))
+	}
+
+	return value
+}
+
+/*
+	Converts all integers and floats to Decimal.
+	Floats which have no decimal representation (NaN and infinities) are left as float64.
+*/
+func castToDecimal(value interface{}) interface{} {
+	switch value.(type) {
+	case uint64:
+		return Decimal{new(big.Int).SetUint64(value.(uint64)), 0}
+	case uint:
+		return Decimal{new(big.Int).SetUint64(uint64(value.(uint))), 0}
+	case *big.Int:
+		return Decimal{new(big.Int).Set(value.(*big.Int)), 0}
+	case float32:
+		// parsed at 32 bits, so that the shortest representation of the float32 (not its float64 conversion) is used.
+		ret, err := ParseDecimal(strconv.FormatFloat(float64(value.(float32)), 'g', -1, 32))
+		if err != nil {
+			return float64(value.(float32))
+		}
+		return ret
+	case float64:
+		ret, err := NewDecimalFromFloat(value.(float64))
+		if err != nil {
+			return value
+		}
+		return ret
+	}
+
+	value = castToInt64(value)
+	if isInt64(value) {
+		return NewDecimalFromInt(value.(int64))
+	}
+	return value
+}
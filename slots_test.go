@@ -0,0 +1,220 @@
+package govaluate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+/*
+	Represents a test of evaluating a compiled expression by slot, which should give the same result as evaluating it by name.
+*/
+type SlotTest struct {
+	Name       string
+	Input      string
+	Options    ParsingOptions
+	Parameters map[string]interface{}
+	Variables  []string
+}
+
+func TestSlotEvaluation(test *testing.T) {
+
+	slotTests := []SlotTest{
+
+		SlotTest{
+			Name:       "Single variable",
+			Input:      "foo > 1",
+			Parameters: map[string]interface{}{"foo": 2},
+			Variables:  []string{"foo"},
+		},
+		SlotTest{
+			Name:       "Repeated variables",
+			Input:      "foo + bar * foo - [baz qux]",
+			Parameters: map[string]interface{}{"foo": 2, "bar": 3.5, "baz qux": int8(1)},
+			Variables:  []string{"foo", "bar", "baz qux"},
+		},
+		SlotTest{
+			Name:       "Nil values",
+			Input:      "foo ?? bar",
+			Parameters: map[string]interface{}{"foo": nil, "bar": "default"},
+			Variables:  []string{"foo", "bar"},
+		},
+		SlotTest{
+			Name:       "Accessors",
+			Input:      "foostruct.Int + foo > 100 && foostruct.Func() == 'funk'",
+			Parameters: map[string]interface{}{"foo": 2, "foostruct": dummyParameter{Int: 101}},
+			Variables:  []string{"foostruct", "foo"},
+		},
+		SlotTest{
+			Name:       "Skipped variables",
+			Input:      "foo || bar > 1",
+			Parameters: map[string]interface{}{"foo": true, "bar": 0},
+			Variables:  []string{"foo", "bar"},
+		},
+		SlotTest{
+			Name:       "Integers",
+			Input:      "foo / bar",
+			Options:    ParsingOptions{NumericMode: INTEGER_NUMERICS},
+			Parameters: map[string]interface{}{"foo": 7, "bar": uint8(2)},
+			Variables:  []string{"foo", "bar"},
+		},
+		SlotTest{
+			Name:       "Decimals",
+			Input:      "foo + 0.2 == bar",
+			Options:    ParsingOptions{NumericMode: DECIMAL_NUMERICS},
+			Parameters: map[string]interface{}{"foo": 0.1, "bar": 0.3},
+			Variables:  []string{"foo", "bar"},
+		},
+		SlotTest{
+			Name:      "No variables",
+			Input:     "1 + 2",
+			Variables: []string{},
+		},
+	}
+
+	for _, slotTest := range slotTests {
+
+		expression, err := NewEvaluableExpressionWithOptions(slotTest.Input, slotTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", slotTest.Name, err)
+			continue
+		}
+
+		compiled := expression.Compile()
+
+		if !reflect.DeepEqual(compiled.Variables(), slotTest.Variables) {
+			test.Errorf("Test '%s' has variables %v, expected %v", slotTest.Name, compiled.Variables(), slotTest.Variables)
+			continue
+		}
+
+		expected, err := expression.Evaluate(slotTest.Parameters)
+		if err != nil {
+			test.Errorf("Test '%s' failed to evaluate: %v", slotTest.Name, err)
+			continue
+		}
+
+		values := make([]interface{}, len(slotTest.Variables))
+		binding := compiled.NewBinding()
+
+		for _, variable := range slotTest.Variables {
+
+			slot, found := compiled.Slot(variable)
+			if !found {
+				test.Errorf("Test '%s' has no slot for '%s'", slotTest.Name, variable)
+				continue
+			}
+
+			values[slot] = slotTest.Parameters[variable]
+			binding.SetSlot(slot, slotTest.Parameters[variable])
+		}
+
+		result, err := compiled.EvalSlots(values)
+		if err != nil || !reflect.DeepEqual(result, expected) {
+			test.Errorf("Test '%s' gave %v (%v) by slot, expected %v", slotTest.Name, result, err, expected)
+		}
+
+		result, err = binding.Eval()
+		if err != nil || !reflect.DeepEqual(result, expected) {
+			test.Errorf("Test '%s' gave %v (%v) by binding, expected %v", slotTest.Name, result, err, expected)
+		}
+	}
+}
+
+func TestBinding(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo > 1 &&\n bar == 'x'")
+	binding := expression.Compile().NewBinding()
+
+	if binding.Set("missing", 1) {
+		test.Errorf("Expected a variable the expression doesn't use not to be set")
+	}
+
+	binding.Set("foo", 2)
+
+	_, err := binding.Eval()
+
+	var missingError MissingParameterError
+	if !errors.As(err, &missingError) || missingError.Name != "bar" || missingError.Position.Line != 2 {
+		test.Errorf("Expected a located error for missing 'bar', got %v", err)
+	}
+
+	binding.Set("bar", "x")
+
+	result, err := binding.Eval()
+	if err != nil || result != true {
+		test.Errorf("Expected true, got %v (%v)", result, err)
+	}
+
+	// values stay set between evaluations, until they're reset.
+	binding.Set("foo", 0)
+
+	result, err = binding.Eval()
+	if err != nil || result != false {
+		test.Errorf("Expected false, got %v (%v)", result, err)
+	}
+
+	binding.Reset()
+
+	_, err = binding.Eval()
+	if !errors.As(err, &missingError) || missingError.Name != "foo" {
+		test.Errorf("Expected reset binding to be missing 'foo', got %v", err)
+	}
+}
+
+func TestSlotErrors(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo + bar")
+	compiled := expression.Compile()
+
+	_, err := compiled.EvalSlots([]interface{}{1})
+	if err == nil {
+		test.Errorf("Expected too few values to fail")
+	}
+
+	_, found := compiled.Slot("baz")
+	if found {
+		test.Errorf("Expected no slot for a variable the expression doesn't use")
+	}
+
+	_, err = compiled.EvalSlots([]interface{}{1, "a", "ignored"})
+	if err != nil {
+		test.Errorf("Expected extra values to be ignored, got %v", err)
+	}
+}
+
+func TestSlotAllocations(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo > 1 && bar == 'x' || foo < -5")
+	compiled := expression.Compile()
+	binding := compiled.NewBinding()
+
+	values := []interface{}{2.0, "x"}
+	binding.SetSlot(0, 2.0)
+	binding.SetSlot(1, "x")
+
+	allocations := testing.AllocsPerRun(100, func() {
+		compiled.EvalSlots(values)
+	})
+	if allocations > 0 {
+		test.Errorf("Expected evaluation by slot not to allocate, got %v allocations", allocations)
+	}
+
+	allocations = testing.AllocsPerRun(100, func() {
+		binding.Eval()
+	})
+	if allocations > 0 {
+		test.Errorf("Expected evaluation by binding not to allocate, got %v allocations", allocations)
+	}
+
+	// the results of arithmetic aren't boxed when they're only given to more arithmetic, or to comparisons.
+	expression, _ = NewEvaluableExpression("(foo * bar / 100) + foo ** 2 - foo % 3 >= 90 || foo * 2 == bar")
+	compiled = expression.Compile()
+	values = []interface{}{99.0, 90.0}
+
+	allocations = testing.AllocsPerRun(100, func() {
+		compiled.EvalSlots(values)
+	})
+	if allocations > 0 {
+		test.Errorf("Expected arithmetic by slot not to allocate, got %v allocations", allocations)
+	}
+}
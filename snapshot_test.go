@@ -0,0 +1,353 @@
+package govaluate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type SnapshotTest struct {
+	Name       string
+	Input      string
+	Options    ParsingOptions
+	Parameters map[string]interface{}
+}
+
+func TestSnapshotEvaluation(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"sum": func(arguments ...interface{}) (interface{}, error) {
+
+			total := 0.0
+			for _, argument := range arguments {
+				total += argument.(float64)
+			}
+			return total, nil
+		},
+	}
+
+	contextFunctions := map[string]ContextExpressionFunction{
+		"deadline": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+			_, found := ctx.Deadline()
+			return found, nil
+		},
+	}
+
+	parameters := map[string]interface{}{
+		"foo":    2,
+		"bar":    "abc",
+		"flag":   true,
+		"none":   nil,
+		"list":   []interface{}{1.0, 2.0},
+		"date":   time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		"window": 5 * time.Minute,
+		"foostruct": dummyParameter{
+			String: "string!",
+			Int:    101,
+		},
+	}
+
+	snapshotTests := []SnapshotTest{
+
+		SnapshotTest{
+			Name:  "Arithmetic",
+			Input: "(foo + 1) * 3 - 4 / 2 ** 2 % 3",
+		},
+		SnapshotTest{
+			Name:  "Folded constants",
+			Input: "foo + (1 + 2) * 3",
+		},
+		SnapshotTest{
+			Name:  "Bitwise",
+			Input: "foo | 8 & 12 ^ 3 << 2 >> 1",
+		},
+		SnapshotTest{
+			Name:  "Prefixes",
+			Input: "-foo + ~foo",
+		},
+		SnapshotTest{
+			Name:  "Logic",
+			Input: "!flag || foo > 1 && bar != 'x'",
+		},
+		SnapshotTest{
+			Name:  "Strings",
+			Input: "bar + 'def' + foo",
+		},
+		SnapshotTest{
+			Name:  "Precompiled regex",
+			Input: "bar =~ '^a.c$' && bar !~ 'z+'",
+		},
+		SnapshotTest{
+			Name:  "Runtime regex",
+			Input: "'abc' =~ bar",
+		},
+		SnapshotTest{
+			Name:  "Ternaries",
+			Input: "flag ? foo : 'no'",
+		},
+		SnapshotTest{
+			Name:  "Coalescence",
+			Input: "none ?? foo",
+		},
+		SnapshotTest{
+			Name:  "Membership",
+			Input: "foo IN (1, 2, 3) && 2.0 IN list",
+		},
+		SnapshotTest{
+			Name:  "Times",
+			Input: "date > '2019-06-01' && date <= '2020-01-02 03:04:05'",
+		},
+		SnapshotTest{
+			Name:  "Durations",
+			Input: "window + 1h30m",
+		},
+		SnapshotTest{
+			Name:  "Accessors",
+			Input: "foostruct.String + ' ' + foostruct.Func() + foostruct.Int",
+		},
+		SnapshotTest{
+			Name:    "Functions",
+			Input:   "sum(foo, 1, 2) + sum()",
+			Options: ParsingOptions{Functions: functions},
+		},
+		SnapshotTest{
+			Name:    "Context functions",
+			Input:   "deadline()",
+			Options: ParsingOptions{ContextFunctions: contextFunctions},
+		},
+		SnapshotTest{
+			Name:    "Integers",
+			Input:   "foo * 7 / 2",
+			Options: ParsingOptions{NumericMode: INTEGER_NUMERICS},
+		},
+		SnapshotTest{
+			Name:    "Decimals",
+			Input:   "0.1 + 0.2 == 0.3 && foo / 3 > 0.6",
+			Options: ParsingOptions{NumericMode: DECIMAL_NUMERICS},
+		},
+		SnapshotTest{
+			Name:    "Decimal division",
+			Input:   "foo / 3",
+			Options: ParsingOptions{NumericMode: DECIMAL_NUMERICS, DecimalPrecision: 4},
+		},
+	}
+
+	for _, snapshotTest := range snapshotTests {
+
+		expression, err := NewEvaluableExpressionWithOptions(snapshotTest.Input, snapshotTest.Options)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", snapshotTest.Name, err)
+			continue
+		}
+
+		snapshot, err := expression.Snapshot()
+		if err != nil {
+			test.Errorf("Test '%s' failed to snapshot: %v", snapshotTest.Name, err)
+			continue
+		}
+
+		loaded, err := NewEvaluableExpressionFromSnapshot(snapshot, ParsingOptions{
+			Functions:        functions,
+			ContextFunctions: contextFunctions,
+		})
+		if err != nil {
+			test.Errorf("Test '%s' failed to load: %v", snapshotTest.Name, err)
+			continue
+		}
+
+		expected, expectedErr := expression.Evaluate(parameters)
+		actual, err := loaded.Evaluate(parameters)
+
+		if expectedErr != nil || err != nil {
+			test.Errorf("Test '%s' failed to evaluate: %v, %v", snapshotTest.Name, expectedErr, err)
+			continue
+		}
+
+		if fmt.Sprintf("%T %v", expected, expected) != fmt.Sprintf("%T %v", actual, actual) {
+			test.Errorf("Test '%s' gave %v (%T) after loading, expected %v (%T)", snapshotTest.Name, actual, actual, expected, expected)
+		}
+
+		if loaded.String() != snapshotTest.Input {
+			test.Errorf("Test '%s' loaded with text '%s'", snapshotTest.Name, loaded.String())
+		}
+
+		if len(loaded.Tokens()) != len(expression.Tokens()) {
+			test.Errorf("Test '%s' loaded with %d tokens, expected %d", snapshotTest.Name, len(loaded.Tokens()), len(expression.Tokens()))
+		}
+	}
+}
+
+func TestSnapshotSettings(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo + 1")
+	expression.QueryDateFormat = "2006-01-02"
+	expression.ChecksTypes = false
+	expression.Options = EvaluationOptions{MaxSteps: 10, MaxStringLength: 20, MaxArrayLength: 30}
+
+	snapshot, err := expression.Snapshot()
+	if err != nil {
+		test.Fatalf("Unable to snapshot expression: %v", err)
+	}
+
+	loaded, err := NewEvaluableExpressionFromSnapshot(snapshot, ParsingOptions{})
+	if err != nil {
+		test.Fatalf("Unable to load snapshot: %v", err)
+	}
+
+	if loaded.QueryDateFormat != "2006-01-02" || loaded.ChecksTypes || loaded.Options != expression.Options {
+		test.Errorf("Expected settings to be kept, got %v, %v, %v", loaded.QueryDateFormat, loaded.ChecksTypes, loaded.Options)
+	}
+}
+
+func TestSnapshotErrors(test *testing.T) {
+
+	// type errors keep their positions.
+	expression, _ := NewEvaluableExpression("1 +\n  foo > 'a'")
+	snapshot, _ := expression.Snapshot()
+
+	loaded, err := NewEvaluableExpressionFromSnapshot(snapshot, ParsingOptions{})
+	if err != nil {
+		test.Fatalf("Unable to load snapshot: %v", err)
+	}
+
+	_, err = loaded.Evaluate(map[string]interface{}{"foo": 1})
+
+	var typeError TypeError
+	if !errors.As(err, &typeError) || typeError.Position.Line != 2 || !strings.Contains(err.Error(), "comparator") {
+		test.Errorf("Expected a located comparator type error, got %v", err)
+	}
+
+	// functions have to be given again.
+	functions := map[string]ExpressionFunction{
+		"one": func(arguments ...interface{}) (interface{}, error) {
+			return 1.0, nil
+		},
+	}
+
+	expression, _ = NewEvaluableExpressionWithFunctions("one() + 1", functions)
+	snapshot, _ = expression.Snapshot()
+
+	_, err = NewEvaluableExpressionFromSnapshot(snapshot, ParsingOptions{})
+	if err == nil || !strings.Contains(err.Error(), "'one'") {
+		test.Errorf("Expected a missing function to fail to load, got %v", err)
+	}
+
+	// functions from tokens have no names.
+	expression, _ = NewEvaluableExpressionFromTokens([]ExpressionToken{
+		ExpressionToken{Kind: FUNCTION, Value: functions["one"]},
+		ExpressionToken{Kind: CLAUSE, Value: '('},
+		ExpressionToken{Kind: CLAUSE_CLOSE, Value: ')'},
+	})
+
+	_, err = expression.Snapshot()
+	if err == nil {
+		test.Errorf("Expected a function without a name to fail to snapshot")
+	}
+
+	// every truncation of a snapshot fails cleanly.
+	expression, _ = NewEvaluableExpression("foo =~ '^a' ? [bar] * 2 : date > '2020-01-01'")
+	snapshot, _ = expression.Snapshot()
+
+	for i := 0; i < len(snapshot); i++ {
+
+		_, err = NewEvaluableExpressionFromSnapshot(snapshot[:i], ParsingOptions{})
+		if err == nil {
+			test.Errorf("Expected snapshot truncated to %d bytes to fail to load", i)
+		}
+	}
+
+	_, err = NewEvaluableExpressionFromSnapshot(append(snapshot, 0), ParsingOptions{})
+	if err == nil {
+		test.Errorf("Expected snapshot with trailing data to fail to load")
+	}
+
+	_, err = NewEvaluableExpressionFromSnapshot([]byte("gvs\x09"), ParsingOptions{})
+	if err == nil || !strings.Contains(err.Error(), "version 9") {
+		test.Errorf("Expected snapshot of an unknown version to fail to load, got %v", err)
+	}
+
+	// stages nested too deeply are refused, whether they're written or read.
+	stage := &evaluationStage{symbol: LITERAL, operator: makeLiteralStage(1.0)}
+	for i := 0; i < maxSnapshotDepth; i++ {
+		stage = &evaluationStage{symbol: NOOP, operator: noopStageRight, rightStage: stage}
+	}
+
+	_, err = (&EvaluableExpression{evaluationStages: stage}).Snapshot()
+	if err == nil || !strings.Contains(err.Error(), "deep") {
+		test.Errorf("Expected deeply nested stages to fail to snapshot, got %v", err)
+	}
+
+	// a snapshot without stages ends with a flag saying so, which is replaced by a deep chain of stages.
+	snapshot, _ = (&EvaluableExpression{}).Snapshot()
+
+	writer := new(snapshotWriter)
+	writer.buffer.Write(snapshot[:len(snapshot)-1])
+	writer.writeBool(true)
+
+	for i := 0; i < maxSnapshotDepth; i++ {
+		writer.buffer.WriteByte(snapshotHasRight)
+		writer.writeUvarint(uint64(NOOP))
+	}
+	writer.buffer.WriteByte(0)
+	writer.writeUvarint(uint64(LITERAL))
+	writer.buffer.WriteByte(snapshotNil)
+
+	_, err = NewEvaluableExpressionFromSnapshot(writer.buffer.Bytes(), ParsingOptions{})
+	if err == nil || !strings.Contains(err.Error(), "deep") {
+		test.Errorf("Expected a deeply nested snapshot to fail to load, got %v", err)
+	}
+
+	// stages refer to tokens by index, which has to be one of the snapshot's tokens.
+	writer = new(snapshotWriter)
+	writer.buffer.Write(snapshot[:len(snapshot)-1])
+	writer.writeBool(true)
+	writer.buffer.WriteByte(snapshotHasToken)
+	writer.writeUvarint(uint64(VALUE))
+	writer.writeUvarint(0)
+
+	_, err = NewEvaluableExpressionFromSnapshot(writer.buffer.Bytes(), ParsingOptions{})
+	if err == nil || !strings.Contains(err.Error(), "token") {
+		test.Errorf("Expected a snapshot with a stage of a missing token to fail to load, got %v", err)
+	}
+}
+
+func TestSnapshotStagesArePlanned(test *testing.T) {
+
+	// loaded stages have the same type checks and error formats as planned ones.
+	expression, _ := NewEvaluableExpression("-a + b ** c > d && !(e =~ 'f') || g ? h IN (i, j) : k ?? l << m & ~n")
+	snapshot, _ := expression.Snapshot()
+
+	loaded, err := NewEvaluableExpressionFromSnapshot(snapshot, ParsingOptions{})
+	if err != nil {
+		test.Fatalf("Unable to load snapshot: %v", err)
+	}
+
+	compareSnapshotStages(expression.evaluationStages, loaded.evaluationStages, test)
+}
+
+func compareSnapshotStages(planned *evaluationStage, loaded *evaluationStage, test *testing.T) {
+
+	if planned == nil || loaded == nil {
+		if planned != loaded {
+			test.Errorf("Expected loaded stages to have the same shape as planned ones")
+		}
+		return
+	}
+
+	if planned.symbol != loaded.symbol ||
+		planned.tokenIndex != loaded.tokenIndex ||
+		planned.closingIndex != loaded.closingIndex ||
+		planned.typeErrorFormat != loaded.typeErrorFormat ||
+		(planned.leftTypeCheck == nil) != (loaded.leftTypeCheck == nil) ||
+		(planned.rightTypeCheck == nil) != (loaded.rightTypeCheck == nil) ||
+		(planned.typeCheck == nil) != (loaded.typeCheck == nil) {
+
+		test.Errorf("Stage '%v' was loaded differently than it was planned", planned.symbol)
+	}
+
+	compareSnapshotStages(planned.leftStage, loaded.leftStage, test)
+	compareSnapshotStages(planned.rightStage, loaded.rightStage, test)
+}
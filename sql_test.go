@@ -1,16 +1,34 @@
 package govaluate
 
 import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 /*
 	Represents a test of correctly creating a SQL query string from an expression.
 */
 type QueryTest struct {
-	Name     string
-	Input    string
-	Expected string
+	Name      string
+	Input     string
+	Functions map[string]ExpressionFunction
+	Dialect   SQLDialect
+	Expected  string
+}
+
+/*
+	Represents a test of creating a SQL query with placeholders, and the [Arguments] for those placeholders.
+*/
+type ParameterizedQueryTest struct {
+	Name      string
+	Input     string
+	Style     SQLPlaceholderStyle
+	Expected  string
+	Arguments []interface{}
 }
 
 func TestSQLSerialization(test *testing.T) {
@@ -66,6 +84,12 @@ func TestSQLSerialization(test *testing.T) {
 			Input:    "'foo'",
 			Expected: "'foo'",
 		},
+		QueryTest{
+
+			Name:     "Strings with quotes",
+			Input:    "foo == \"it\\'s\"",
+			Expected: "[foo] = 'it''s'",
+		},
 		QueryTest{
 
 			Name:     "Date format",
@@ -162,32 +186,78 @@ func TestSQLSerialization(test *testing.T) {
 			Input:    "foo ?? bar",
 			Expected: "COALESCE([foo], [bar])",
 		},
-		/*
-			// Ternaries don't work yet, because the outputter is not yet sophisticated enough to produce them.
-			QueryTest{
+		QueryTest{
+
+			Name:     "Full ternary",
+			Input:    "[foo] == 5 ? 1 : 2",
+			Expected: "CASE WHEN [foo] = 5 THEN 1 ELSE 2 END",
+		},
+		QueryTest{
+
+			Name:     "Half ternary",
+			Input:    "[foo] == 5 ? 1",
+			Expected: "CASE WHEN [foo] = 5 THEN 1 END",
+		},
+		QueryTest{
+
+			Name:     "Full ternary with implicit bool",
+			Input:    "[foo] ? 1 : 2",
+			Expected: "CASE WHEN [foo] THEN 1 ELSE 2 END",
+		},
+		QueryTest{
+
+			Name:     "Nested ternary",
+			Input:    "foo ? bar ? 1 : 2 : 3",
+			Expected: "CASE WHEN [foo] THEN CASE WHEN [bar] THEN 1 ELSE 2 END ELSE 3 END",
+		},
+		QueryTest{
+
+			Name:     "Nested half ternary",
+			Input:    "foo ? bar ? 1 : 2",
+			Expected: "CASE WHEN [foo] THEN CASE WHEN [bar] THEN 1 ELSE 2 END END",
+		},
+		QueryTest{
+
+			Name:     "Doubly nested ternary",
+			Input:    "foo ? bar ? baz ? 1 : 2 : 3 : 4",
+			Expected: "CASE WHEN [foo] THEN CASE WHEN [bar] THEN CASE WHEN [baz] THEN 1 ELSE 2 END ELSE 3 END ELSE 4 END",
+		},
+		QueryTest{
+
+			Name:     "Ternary as a condition",
+			Input:    "foo ? true : bar ? 1 : 2",
+			Expected: "CASE WHEN CASE WHEN [foo] THEN 1 ELSE [bar] END THEN 1 ELSE 2 END",
+		},
+		QueryTest{
 
-				Name:     "Full ternary",
-				Input:    "[foo] == 5 ? 1 : 2",
-				Expected: "IF([foo] = 5, 1, 2)",
-			},
-			QueryTest{
+			Name:     "Ternary within an operation",
+			Input:    "(foo > 1 ? 'big' : 'small') == size",
+			Expected: "( CASE WHEN [foo] > 1 THEN 'big' ELSE 'small' END ) = [size]",
+		},
+		QueryTest{
 
-				Name:     "Half ternary",
-				Input:    "[foo] == 5 ? 1",
-				Expected: "IF([foo] = 5, 1)",
-			},
-			QueryTest{
+			Name:     "String concatenation",
+			Input:    "first + ' ' + last == 'Jo Bo'",
+			Expected: "CONCAT(CONCAT([first], ' '), [last]) = 'Jo Bo'",
+		},
+		QueryTest{
 
-				Name:     "Full ternary with implicit bool",
-				Input:    "[foo] ? 1 : 2",
-				Expected: "IF([foo] = 0, 1, 2)",
-			},
-			QueryTest{
+			Name:     "Addition of parameters",
+			Input:    "first + last",
+			Expected: "[first] + [last]",
+		},
+		QueryTest{
 
-				Name:     "Half ternary with implicit bool",
-				Input:    "[foo] ? 1",
-				Expected: "IF([foo] = 0, 1)",
-			},*/
+			Name:     "Bitwise operators",
+			Input:    "flags & 4 == 4 && ~mask | 1 > 0",
+			Expected: "[flags] & 4 = 4 AND ~[mask] | 1 > 0",
+		},
+		QueryTest{
+
+			Name:     "Bitwise precedence",
+			Input:    "a | b & c ^ d << 2",
+			Expected: "(([a] | [b]) & [c]) ^ ([d] << 2)",
+		},
 		QueryTest{
 
 			Name:     "Regex equals",
@@ -200,9 +270,543 @@ func TestSQLSerialization(test *testing.T) {
 			Input:    "'foo' !~ '[fF][oO]+'",
 			Expected: "'foo' NOT RLIKE '[fF][oO]+'",
 		},
+		QueryTest{
+
+			Name:     "Duration",
+			Input:    "elapsed > 1h30m",
+			Expected: "[elapsed] > INTERVAL 5400 SECOND",
+		},
+	}
+
+	runQueryTests(testCases, test)
+}
+
+func TestSQLParameterization(test *testing.T) {
+
+	testCases := []ParameterizedQueryTest{
+
+		ParameterizedQueryTest{
+
+			Name:      "Question placeholders",
+			Input:     "foo == 'bar' && baz > 5",
+			Style:     QUESTION_PLACEHOLDERS,
+			Expected:  "[foo] = ? AND [baz] > ?",
+			Arguments: []interface{}{"bar", 5.0},
+		},
+		ParameterizedQueryTest{
+
+			Name:      "Dollar placeholders",
+			Input:     "foo IN ('a', 'b', 'c')",
+			Style:     DOLLAR_PLACEHOLDERS,
+			Expected:  "[foo] in ( $1 , $2 , $3 )",
+			Arguments: []interface{}{"a", "b", "c"},
+		},
+		ParameterizedQueryTest{
+
+			Name:      "At placeholders",
+			Input:     "foo ?? 'default' == bar",
+			Style:     AT_PLACEHOLDERS,
+			Expected:  "COALESCE([foo], @p1 = [bar])",
+			Arguments: []interface{}{"default"},
+		},
+		ParameterizedQueryTest{
+
+			Name:      "Named placeholders",
+			Input:     "foo % 2 == 1",
+			Style:     NAMED_PLACEHOLDERS,
+			Expected:  "MOD([foo], :p1) = :p2",
+			Arguments: []interface{}{sql.Named("p1", 2.0), sql.Named("p2", 1.0)},
+		},
+		ParameterizedQueryTest{
+
+			Name:      "Injection",
+			Input:     "name == \"x\\' OR 1=1 --\"",
+			Style:     QUESTION_PLACEHOLDERS,
+			Expected:  "[name] = ?",
+			Arguments: []interface{}{"x' OR 1=1 --"},
+		},
+		ParameterizedQueryTest{
+
+			Name:      "Times and patterns",
+			Input:     "created > '2014-07-04T00:00:00Z' && name =~ '^a'",
+			Style:     QUESTION_PLACEHOLDERS,
+			Expected:  "[created] > ? AND [name] RLIKE ?",
+			Arguments: []interface{}{time.Date(2014, time.July, 4, 0, 0, 0, 0, time.UTC), "^a"},
+		},
+		ParameterizedQueryTest{
+
+			Name:      "Ternary",
+			Input:     "foo > 1 ? 'x' : 'y'",
+			Style:     QUESTION_PLACEHOLDERS,
+			Expected:  "CASE WHEN [foo] > ? THEN ? ELSE ? END",
+			Arguments: []interface{}{1.0, "x", "y"},
+		},
+		ParameterizedQueryTest{
+
+			Name:      "Numbered ternary",
+			Input:     "foo > 1 ? 'x' : 'y'",
+			Style:     DOLLAR_PLACEHOLDERS,
+			Expected:  "CASE WHEN [foo] > $1 THEN $2 ELSE $3 END",
+			Arguments: []interface{}{1.0, "x", "y"},
+		},
+		ParameterizedQueryTest{
+
+			Name:      "Nested ternary",
+			Input:     "foo > 1 ? bar > 2 ? 'x' : 'y' : 'z'",
+			Style:     DOLLAR_PLACEHOLDERS,
+			Expected:  "CASE WHEN [foo] > $1 THEN CASE WHEN [bar] > $2 THEN $3 ELSE $4 END ELSE $5 END",
+			Arguments: []interface{}{1.0, 2.0, "x", "y", "z"},
+		},
+		ParameterizedQueryTest{
+
+			Name:     "Booleans and durations are not arguments",
+			Input:    "active == true && elapsed > 1m",
+			Style:    DOLLAR_PLACEHOLDERS,
+			Expected: "[active] = 1 AND [elapsed] > INTERVAL 60 SECOND",
+		},
+	}
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpression(testCase.Input)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", testCase.Name, err)
+			continue
+		}
+
+		query, arguments, err := expression.ToSQLParameterized(testCase.Style)
+		if err != nil {
+			test.Errorf("Test '%s' failed to create query: %v", testCase.Name, err)
+			continue
+		}
+
+		if query != testCase.Expected {
+			test.Errorf("Test '%s' expected query '%s', got '%s'", testCase.Name, testCase.Expected, query)
+		}
+
+		if len(arguments) != len(testCase.Arguments) {
+			test.Errorf("Test '%s' expected arguments %v, got %v", testCase.Name, testCase.Arguments, arguments)
+			continue
+		}
+
+		for i, argument := range arguments {
+
+			expected := testCase.Arguments[i]
+
+			expectedTime, isTime := expected.(time.Time)
+			if isTime && expectedTime.Equal(argument.(time.Time)) {
+				continue
+			}
+
+			if !reflect.DeepEqual(argument, expected) {
+				test.Errorf("Test '%s' expected argument %d to be %v, got %v", testCase.Name, i, expected, argument)
+			}
+		}
+	}
+}
+
+func TestSQLDialects(test *testing.T) {
+
+	testCases := []QueryTest{
+
+		QueryTest{
+
+			Name:     "Grouped exponent",
+			Input:    "(foo + 1) ** 2 > 10",
+			Expected: "POW(( [foo] + 1 ), 2) > 10",
+		},
+		QueryTest{
+
+			Name:     "Grouped modulus",
+			Input:    "foo * 2 % 3",
+			Expected: "MOD([foo] * 2, 3)",
+		},
+		QueryTest{
+
+			Name:     "Default membership in parameter",
+			Input:    "foo in bar",
+			Expected: "[foo] in [bar]",
+		},
+		QueryTest{
+
+			Name:     "Empty membership",
+			Input:    "foo in ()",
+			Expected: "1 = 0",
+		},
+		QueryTest{
+
+			Name:     "PostgreSQL",
+			Input:    "name =~ '^a' && active == true && score ** 2 > 10",
+			Dialect:  POSTGRES_SQL_DIALECT,
+			Expected: "\"name\" ~ '^a' AND \"active\" = TRUE AND POWER(\"score\", 2) > 10",
+		},
+		QueryTest{
+
+			Name:     "PostgreSQL membership",
+			Input:    "foo IN ('a', 'b') || foo in bar",
+			Dialect:  POSTGRES_SQL_DIALECT,
+			Expected: "\"foo\" IN ( 'a' , 'b' ) OR \"foo\" = ANY(\"bar\")",
+		},
+		QueryTest{
+
+			Name:     "PostgreSQL times and durations",
+			Input:    "created > '2014-07-04T12:30:00Z' && elapsed < 90s",
+			Dialect:  POSTGRES_SQL_DIALECT,
+			Expected: "\"created\" > '2014-07-04 12:30:00+00:00' AND \"elapsed\" < INTERVAL '90 seconds'",
+		},
+		QueryTest{
+
+			Name:     "PostgreSQL quoted identifier",
+			Input:    "[my \"column\"] != false",
+			Dialect:  POSTGRES_SQL_DIALECT,
+			Expected: "\"my \"\"column\"\"\" <> FALSE",
+		},
+		QueryTest{
+
+			Name:     "PostgreSQL concatenation and XOR",
+			Input:    "'id-' + foo == bar && flags ^ 2 > 0",
+			Dialect:  POSTGRES_SQL_DIALECT,
+			Expected: "'id-' || \"foo\" = \"bar\" AND \"flags\" # 2 > 0",
+		},
+		QueryTest{
+
+			Name:     "PostgreSQL ternary",
+			Input:    "active ? score * 2 : 0",
+			Dialect:  POSTGRES_SQL_DIALECT,
+			Expected: "CASE WHEN \"active\" THEN \"score\" * 2 ELSE 0 END",
+		},
+		QueryTest{
+
+			Name:     "MySQL",
+			Input:    "name !~ '^a' || foo % 2 == 1",
+			Dialect:  MYSQL_SQL_DIALECT,
+			Expected: "`name` NOT REGEXP '^a' OR MOD(`foo`, 2) = 1",
+		},
+		QueryTest{
+
+			Name:     "MySQL times",
+			Input:    "created > '2014-07-04T12:30:00Z'",
+			Dialect:  MYSQL_SQL_DIALECT,
+			Expected: "`created` > '2014-07-04 12:30:00'",
+		},
+		QueryTest{
+
+			Name:     "SQLite",
+			Input:    "foo % 2 == 1 && name =~ '^a' && active",
+			Dialect:  SQLITE_SQL_DIALECT,
+			Expected: "\"foo\" % 2 = 1 AND \"name\" REGEXP '^a' AND \"active\"",
+		},
+		QueryTest{
+
+			Name:     "SQLite concatenation",
+			Input:    "name + '!'",
+			Dialect:  SQLITE_SQL_DIALECT,
+			Expected: "\"name\" || '!'",
+		},
+		QueryTest{
+
+			Name:     "SQL Server concatenation",
+			Input:    "name + '!'",
+			Dialect:  SQL_SERVER_SQL_DIALECT,
+			Expected: "[name] + '!'",
+		},
+		QueryTest{
+
+			Name:     "SQL Server",
+			Input:    "[foo bar] ** 2 > 4 && baz IN (1, 2) && enabled == true",
+			Dialect:  SQL_SERVER_SQL_DIALECT,
+			Expected: "POWER([foo bar], 2) > 4 AND [baz] IN ( 1 , 2 ) AND [enabled] = 1",
+		},
+	}
+
+	runQueryTests(testCases, test)
+}
+
+func TestUnsupportedSQLDialectFeatures(test *testing.T) {
+
+	testCases := []QueryTest{
+
+		QueryTest{
+
+			Name:     "Regex in SQL Server",
+			Input:    "name =~ '^a'",
+			Dialect:  SQL_SERVER_SQL_DIALECT,
+			Expected: "Regex comparisons are unsupported by the SQL Server SQL dialect",
+		},
+		QueryTest{
+
+			Name:     "Membership in parameter in MySQL",
+			Input:    "foo in bar",
+			Dialect:  MYSQL_SQL_DIALECT,
+			Expected: "Membership in a parameter is unsupported by the MySQL SQL dialect, use a list of values instead",
+		},
+		QueryTest{
+
+			Name:     "Durations in SQLite",
+			Input:    "elapsed > 1h",
+			Dialect:  SQLITE_SQL_DIALECT,
+			Expected: "Durations are unsupported by the SQLite SQL dialect",
+		},
+		QueryTest{
+
+			Name:     "Bitwise XOR in SQLite",
+			Input:    "flags ^ 2",
+			Dialect:  SQLITE_SQL_DIALECT,
+			Expected: "Bitwise XOR is unsupported by the SQLite SQL dialect",
+		},
+	}
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpressionWithFunctions(testCase.Input, testCase.Functions)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", testCase.Name, err)
+			continue
+		}
+
+		_, err = expression.ToSQLQueryWithDialect(testCase.Dialect)
+		if err == nil || err.Error() != testCase.Expected {
+			test.Errorf("Test '%s' expected error '%s', got '%v'", testCase.Name, testCase.Expected, err)
+		}
+	}
+}
+
+func TestSQLFunctions(test *testing.T) {
+
+	// only the names of these matter, they're never called.
+	noopFunction := func(arguments ...interface{}) (interface{}, error) {
+		return nil, nil
+	}
+
+	functions := map[string]ExpressionFunction{
+		"lower":   noopFunction,
+		"between": noopFunction,
+		"now":     noopFunction,
+		"secret":  noopFunction,
+		"broken":  noopFunction,
+	}
+
+	dialect := POSTGRES_SQL_DIALECT
+	dialect.Functions = map[string]SQLFunction{
+		"lower":   SQLFunctionName("LOWER"),
+		"between": SQLFunctionFormat("%s BETWEEN %s AND %s"),
+		"now": func(arguments []string) (string, error) {
+			return "CURRENT_TIMESTAMP", nil
+		},
+		"broken": func(arguments []string) (string, error) {
+			return "", errors.New("always fails")
+		},
+	}
+
+	testCases := []QueryTest{
+
+		QueryTest{
+
+			Name:      "Function name",
+			Input:     "lower(name) == 'x'",
+			Functions: functions,
+			Dialect:   dialect,
+			Expected:  "LOWER(\"name\") = 'x'",
+		},
+		QueryTest{
+
+			Name:      "Function with expression arguments",
+			Input:     "lower(first + ' ' + last)",
+			Functions: functions,
+			Dialect:   dialect,
+			Expected:  "LOWER(\"first\" || ' ' || \"last\")",
+		},
+		QueryTest{
+
+			Name:      "Function format",
+			Input:     "between(age, 18, 65) && created < now()",
+			Functions: functions,
+			Dialect:   dialect,
+			Expected:  "\"age\" BETWEEN 18 AND 65 AND \"created\" < CURRENT_TIMESTAMP",
+		},
+	}
+
+	runQueryTests(testCases, test)
+
+	failures := []QueryTest{
+
+		QueryTest{
+
+			Name:      "Unmapped function",
+			Input:     "secret(name)",
+			Functions: functions,
+			Dialect:   dialect,
+			Expected:  "Function 'secret' has no SQL mapping in the PostgreSQL SQL dialect",
+		},
+		QueryTest{
+
+			Name:      "Wrong number of arguments",
+			Input:     "between(age, 18)",
+			Functions: functions,
+			Dialect:   dialect,
+			Expected:  "Unable to write function 'between' in SQL: 2 arguments don't fit the format '%s BETWEEN %s AND %s'",
+		},
+		QueryTest{
+
+			Name:      "Failing function",
+			Input:     "broken()",
+			Functions: functions,
+			Dialect:   dialect,
+			Expected:  "Unable to write function 'broken' in SQL: always fails",
+		},
+	}
+
+	for _, testCase := range failures {
+
+		expression, err := NewEvaluableExpressionWithFunctions(testCase.Input, testCase.Functions)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", testCase.Name, err)
+			continue
+		}
+
+		_, err = expression.ToSQLQueryWithDialect(testCase.Dialect)
+		if err == nil || err.Error() != testCase.Expected {
+			test.Errorf("Test '%s' expected error '%s', got '%v'", testCase.Name, testCase.Expected, err)
+		}
+	}
+
+	// placeholders in arguments are numbered in the order they're written.
+	expression, err := NewEvaluableExpressionWithFunctions("lower(name) == lower('X') && between(age, 18, 65)", functions)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	query, arguments, err := expression.ToSQLParameterizedWithDialect(dialect, DOLLAR_PLACEHOLDERS)
+	if err != nil {
+		test.Fatalf("Unable to create query: %v", err)
+	}
+
+	expected := "LOWER(\"name\") = LOWER($1) AND \"age\" BETWEEN $2 AND $3"
+	if query != expected || !reflect.DeepEqual(arguments, []interface{}{"X", 18.0, 65.0}) {
+		test.Errorf("Expected '%s', got '%s' with arguments %v", expected, query, arguments)
+	}
+}
+
+func TestSQLIdentifiers(test *testing.T) {
+
+	qualified := POSTGRES_SQL_DIALECT
+	qualified.ResolveIdentifier = SQLQualifiedColumns(qualified.QuoteIdentifier)
+
+	json := POSTGRES_SQL_DIALECT
+	json.ResolveIdentifier = SQLJSONPathColumns(json.QuoteIdentifier)
+
+	custom := MYSQL_SQL_DIALECT
+	custom.ResolveIdentifier = func(path []string) (string, error) {
+
+		if path[0] != "user" {
+			return "", errors.New("unknown table")
+		}
+		return "`u`." + custom.QuoteIdentifier(strings.ToLower(path[len(path)-1])), nil
+	}
+
+	testCases := []QueryTest{
+
+		QueryTest{
+
+			Name:     "Qualified columns",
+			Input:    "users.City == 'Paris' && age > 18",
+			Dialect:  qualified,
+			Expected: "\"users\".\"City\" = 'Paris' AND \"age\" > 18",
+		},
+		QueryTest{
+
+			Name:     "JSON paths",
+			Input:    "data.Address.City == 'Paris' || data.Name != 'x'",
+			Dialect:  json,
+			Expected: "\"data\"->'Address'->>'City' = 'Paris' OR \"data\"->>'Name' <> 'x'",
+		},
+		QueryTest{
+
+			Name:     "JSON path with parameters",
+			Input:    "data.Owner == 'x' && [my column]",
+			Dialect:  json,
+			Expected: "\"data\"->>'Owner' = 'x' AND \"my column\"",
+		},
+		QueryTest{
+
+			Name:     "Custom resolver",
+			Input:    "user.Address.City == 'Paris'",
+			Dialect:  custom,
+			Expected: "`u`.`city` = 'Paris'",
+		},
 	}
 
 	runQueryTests(testCases, test)
+
+	failures := []QueryTest{
+
+		QueryTest{
+
+			Name:     "Accessor without resolver",
+			Input:    "user.Name == 'x'",
+			Dialect:  POSTGRES_SQL_DIALECT,
+			Expected: "Unable to write accessor 'user.Name' in SQL without a ResolveIdentifier in the dialect",
+		},
+		QueryTest{
+
+			Name:     "Method call",
+			Input:    "user.Name() == 'x'",
+			Dialect:  json,
+			Expected: "Unable to write method call 'user.Name' in SQL",
+		},
+		QueryTest{
+
+			Name:     "Resolver error",
+			Input:    "account.Name == 'x'",
+			Dialect:  custom,
+			Expected: "Unable to write 'account.Name' in SQL: unknown table",
+		},
+	}
+
+	for _, testCase := range failures {
+
+		expression, err := NewEvaluableExpression(testCase.Input)
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", testCase.Name, err)
+			continue
+		}
+
+		_, err = expression.ToSQLQueryWithDialect(testCase.Dialect)
+		if err == nil || err.Error() != testCase.Expected {
+			test.Errorf("Test '%s' expected error '%s', got '%v'", testCase.Name, testCase.Expected, err)
+		}
+	}
+}
+
+func TestDecimalSQLSerialization(test *testing.T) {
+
+	options := ParsingOptions{
+		NumericMode: DECIMAL_NUMERICS,
+	}
+
+	expression, err := NewEvaluableExpressionWithOptions("price * 1.0825 > 100.10", options)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	query, err := expression.ToSQLQuery()
+	if err != nil {
+		test.Fatalf("Unable to create query: %v", err)
+	}
+
+	expected := "[price] * 1.0825 > 100.10"
+	if query != expected {
+		test.Errorf("Actual: '%s', expected '%s'", query, expected)
+	}
+
+	query, arguments, err := expression.ToSQLParameterized(QUESTION_PLACEHOLDERS)
+	if err != nil {
+		test.Fatalf("Unable to create query: %v", err)
+	}
+
+	if query != "[price] * ? > ?" || !reflect.DeepEqual(arguments, []interface{}{"1.0825", "100.10"}) {
+		test.Errorf("Unexpected parameterized query '%s' with arguments %v", query, arguments)
+	}
 }
 
 func runQueryTests(testCases []QueryTest, test *testing.T) {
@@ -216,7 +820,7 @@ func runQueryTests(testCases []QueryTest, test *testing.T) {
 	// Run the test cases.
 	for _, testCase := range testCases {
 
-		expression, err = NewEvaluableExpression(testCase.Input)
+		expression, err = NewEvaluableExpressionWithFunctions(testCase.Input, testCase.Functions)
 
 		if err != nil {
 
@@ -226,7 +830,11 @@ func runQueryTests(testCases []QueryTest, test *testing.T) {
 			continue
 		}
 
-		actualQuery, err = expression.ToSQLQuery()
+		if testCase.Dialect.Name == "" {
+			actualQuery, err = expression.ToSQLQuery()
+		} else {
+			actualQuery, err = expression.ToSQLQueryWithDialect(testCase.Dialect)
+		}
 
 		if err != nil {
 
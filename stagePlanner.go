@@ -1,9 +1,8 @@
 package govaluate
 
 import (
-	"errors"
 	"fmt"
-	"time"
+	"math/big"
 )
 
 var stageSymbolMap = map[OperatorSymbol]evaluationOperator{
@@ -38,6 +37,12 @@ var stageSymbolMap = map[OperatorSymbol]evaluationOperator{
 	SEPARATE:       separatorStage,
 }
 
+/*
+	The format of type errors for each operator in stageSymbolMap, given by the planner of its precedence.
+	Filled in by `makePrecedentFromPlanner`.
+*/
+var stageErrorFormatMap = make(map[OperatorSymbol]string)
+
 /*
 	A "precedent" is a function which will recursively parse new evaluateionStages from a given stream of tokens.
 	It's called a `precedent` because it is expected to handle exactly what precedence of operator,
@@ -153,10 +158,13 @@ func makePrecedentFromPlanner(planner *precedencePlanner) precedent {
 	var generated precedent
 	var nextRight precedent
 
+	for _, symbol := range planner.validSymbols {
+		stageErrorFormatMap[symbol] = planner.typeErrorFormat
+	}
+
 	generated = func(stream *tokenStream) (*evaluationStage, error) {
 		return planPrecedenceLevel(
 			stream,
-			planner.typeErrorFormat,
 			planner.validSymbols,
 			planner.validKinds,
 			nextRight,
@@ -178,20 +186,38 @@ func makePrecedentFromPlanner(planner *precedencePlanner) precedent {
 	which is used to completely evaluate a set of tokens at evaluation-time.
 	The three stages of evaluation can be thought of as parsing strings to tokens, then tokens to a stage list, then evaluation with parameters.
 */
-func planStages(tokens []ExpressionToken) (*evaluationStage, error) {
+func planStages(tokens []ExpressionToken, sources []tokenSource, options ParsingOptions) (*evaluationStage, error) {
 
-	stream := newTokenStream(tokens)
+	stage, err := planStageTree(tokens, sources)
+	if err != nil || stage == nil {
+		return nil, err
+	}
+
+	// must happen before literals are elided, so that constant divisions use the same precision as all others.
+	if options.NumericMode == DECIMAL_NUMERICS {
+		planDecimalDivision(stage, options.decimalPrecision(), options.DecimalRounding)
+	}
+
+	stage = elideLiterals(stage)
+	return stage, nil
+}
+
+/*
+	Plans the tree of stages for the given [tokens] (read from the given [sources]), in the order they'll be evaluated, but without any optimizations.
+	Every stage in this tree still has the token it was planned from, which makes it the basis of the expression's AST.
+*/
+func planStageTree(tokens []ExpressionToken, sources []tokenSource) (*evaluationStage, error) {
+
+	stream := newTokenStream(tokens, sources)
 
 	stage, err := planTokens(stream)
-	if err != nil {
+	if err != nil || stage == nil {
 		return nil, err
 	}
 
 	// while we're now fully-planned, we now need to re-order same-precedence operators.
 	// this could probably be avoided with a different planning method
 	reorderStages(stage)
-
-	stage = elideLiterals(stage)
 	return stage, nil
 }
 
@@ -210,16 +236,16 @@ func planTokens(stream *tokenStream) (*evaluationStage, error) {
 */
 func planPrecedenceLevel(
 	stream *tokenStream,
-	typeErrorFormat string,
 	validSymbols map[string]OperatorSymbol,
 	validKinds []TokenKind,
 	rightPrecedent precedent,
 	leftPrecedent precedent) (*evaluationStage, error) {
 
 	var token ExpressionToken
+	var source tokenSource
+	var index int
 	var symbol OperatorSymbol
-	var leftStage, rightStage *evaluationStage
-	var checks typeChecks
+	var ret, leftStage, rightStage *evaluationStage
 	var err error
 	var keyFound bool
 
@@ -234,6 +260,8 @@ func planPrecedenceLevel(
 	for stream.hasNext() {
 
 		token = stream.next()
+		source = stream.source()
+		index = stream.lastIndex()
 
 		if len(validKinds) > 0 {
 
@@ -269,20 +297,14 @@ func planPrecedenceLevel(
 			}
 		}
 
-		checks = findTypeChecks(symbol)
-
-		return &evaluationStage{
-
-			symbol:     symbol,
-			leftStage:  leftStage,
-			rightStage: rightStage,
-			operator:   stageSymbolMap[symbol],
-
-			leftTypeCheck:   checks.left,
-			rightTypeCheck:  checks.right,
-			typeCheck:       checks.combined,
-			typeErrorFormat: typeErrorFormat,
-		}, nil
+		// every symbol a planner accepts is an operator.
+		ret, _ = makeOperatorStage(symbol)
+		ret.token = token
+		ret.source = source
+		ret.tokenIndex = index
+		ret.leftStage = leftStage
+		ret.rightStage = rightStage
+		return ret, nil
 	}
 
 	stream.rewind()
@@ -295,10 +317,14 @@ func planPrecedenceLevel(
 func planFunction(stream *tokenStream) (*evaluationStage, error) {
 
 	var token ExpressionToken
+	var source tokenSource
+	var index int
 	var rightStage *evaluationStage
 	var err error
 
 	token = stream.next()
+	source = stream.source()
+	index = stream.lastIndex()
 
 	if token.Kind != FUNCTION {
 		stream.rewind()
@@ -310,18 +336,22 @@ func planFunction(stream *tokenStream) (*evaluationStage, error) {
 		return nil, err
 	}
 
-	return &evaluationStage{
+	ret, found := makeFunctionCallStage(token)
+	if !found {
+		return nil, makePlanningError(token, source)
+	}
 
-		symbol:          FUNCTIONAL,
-		rightStage:      rightStage,
-		operator:        makeFunctionStage(token.Value.(ExpressionFunction)),
-		typeErrorFormat: "Unable to run function '%v': %v",
-	}, nil
+	ret.source = source
+	ret.tokenIndex = index
+	ret.rightStage = rightStage
+	return ret, nil
 }
 
 func planAccessor(stream *tokenStream) (*evaluationStage, error) {
 
 	var token, otherToken ExpressionToken
+	var source tokenSource
+	var index int
 	var rightStage *evaluationStage
 	var err error
 
@@ -330,6 +360,8 @@ func planAccessor(stream *tokenStream) (*evaluationStage, error) {
 	}
 
 	token = stream.next()
+	source = stream.source()
+	index = stream.lastIndex()
 
 	if token.Kind != ACCESSOR {
 		stream.rewind()
@@ -346,7 +378,8 @@ func planAccessor(stream *tokenStream) (*evaluationStage, error) {
 
 			stream.rewind()
 
-			rightStage, err = planTokens(stream)
+			// only the clause holds the arguments, not anything which follows it.
+			rightStage, err = planValue(stream)
 			if err != nil {
 				return nil, err
 			}
@@ -355,13 +388,91 @@ func planAccessor(stream *tokenStream) (*evaluationStage, error) {
 		}
 	}
 
+	ret, found := makeAccessStage(token)
+	if !found {
+		return nil, makePlanningError(token, source)
+	}
+
+	ret.source = source
+	ret.tokenIndex = index
+	ret.rightStage = rightStage
+	return ret, nil
+}
+
+/*
+	Returns the error for a [token] which can't be planned, such as one given to `NewEvaluableExpressionFromTokens` with the wrong kind of value.
+*/
+func makePlanningError(token ExpressionToken, source tokenSource) error {
+
+	errorMsg := fmt.Sprintf("Unable to plan token kind: '%s', value: '%v'", token.Kind.String(), token.Value)
+	return SyntaxError{errorMsg, source.start, findTokenSymbol(token), token}
+}
+
+/*
+	Makes a stage for the given operator [symbol], with the operator, type checks and type error format it's always planned with.
+	The stage has no token or operands; those are up to the caller. Returns false if [symbol] isn't an operator.
+*/
+func makeOperatorStage(symbol OperatorSymbol) (*evaluationStage, bool) {
+
+	operator, found := stageSymbolMap[symbol]
+	if !found {
+		return nil, false
+	}
+
+	checks := findTypeChecks(symbol)
+
 	return &evaluationStage{
 
+		symbol:   symbol,
+		operator: operator,
+
+		leftTypeCheck:   checks.left,
+		rightTypeCheck:  checks.right,
+		typeCheck:       checks.combined,
+		typeErrorFormat: stageErrorFormatMap[symbol],
+	}, true
+}
+
+/*
+	Makes a stage which calls the function of the given FUNCTION [token]. Its arguments are up to the caller.
+	Returns false if the token's value isn't a function.
+*/
+func makeFunctionCallStage(token ExpressionToken) (*evaluationStage, bool) {
+
+	ret := &evaluationStage{
+		symbol:          FUNCTIONAL,
+		token:           token,
+		typeErrorFormat: functionErrorFormat,
+	}
+
+	switch token.Value.(type) {
+	case ContextExpressionFunction:
+		ret.contextOperator = makeContextFunctionStage(token.Value.(ContextExpressionFunction))
+	case ExpressionFunction:
+		ret.operator = makeFunctionStage(token.Value.(ExpressionFunction))
+	default:
+		return nil, false
+	}
+	return ret, true
+}
+
+/*
+	Makes a stage which accesses the path of the given ACCESSOR [token]. The arguments of a method call are up to the caller.
+	Returns false if the token's value isn't a path.
+*/
+func makeAccessStage(token ExpressionToken) (*evaluationStage, bool) {
+
+	path, isPath := token.Value.([]string)
+	if !isPath {
+		return nil, false
+	}
+
+	return &evaluationStage{
 		symbol:          ACCESS,
-		rightStage:      rightStage,
-		operator:        makeAccessorStage(token.Value.([]string)),
-		typeErrorFormat: "Unable to access parameter field or method '%v': %v",
-	}, nil
+		token:           token,
+		contextOperator: makeAccessorStage(path),
+		typeErrorFormat: accessorErrorFormat,
+	}, true
 }
 
 /*
@@ -371,6 +482,8 @@ func planAccessor(stream *tokenStream) (*evaluationStage, error) {
 func planValue(stream *tokenStream) (*evaluationStage, error) {
 
 	var token ExpressionToken
+	var source tokenSource
+	var index int
 	var symbol OperatorSymbol
 	var ret *evaluationStage
 	var operator evaluationOperator
@@ -381,6 +494,8 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 	}
 
 	token = stream.next()
+	source = stream.source()
+	index = stream.lastIndex()
 
 	switch token.Kind {
 
@@ -392,15 +507,23 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 		}
 
 		// advance past the CLAUSE_CLOSE token. We know that it's a CLAUSE_CLOSE, because at parse-time we check for unbalanced parens.
-		stream.next()
+		closingToken := stream.next()
+		closingSource := stream.source()
+		closingIndex := stream.lastIndex()
 
 		// the stage we got represents all of the logic contained within the parens
 		// but for technical reasons, we need to wrap this stage in a "noop" stage which breaks long chains of precedence.
 		// see github #33.
 		ret = &evaluationStage{
-			rightStage: ret,
-			operator:   noopStageRight,
-			symbol:     NOOP,
+			rightStage:    ret,
+			operator:      noopStageRight,
+			symbol:        NOOP,
+			token:         token,
+			source:        source,
+			closingToken:  closingToken,
+			closingSource: closingSource,
+			tokenIndex:    index,
+			closingIndex:  closingIndex,
 		}
 
 		return ret, nil
@@ -422,11 +545,12 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 	case PATTERN:
 		fallthrough
 	case BOOLEAN:
-		symbol = LITERAL
-		operator = makeLiteralStage(token.Value)
+		fallthrough
 	case TIME:
+		fallthrough
+	case DURATION:
 		symbol = LITERAL
-		operator = makeLiteralStage(float64(token.Value.(time.Time).Unix()))
+		operator = makeLiteralStage(token.Value)
 
 	case PREFIX:
 		stream.rewind()
@@ -434,13 +558,15 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 	}
 
 	if operator == nil {
-		errorMsg := fmt.Sprintf("Unable to plan token kind: '%s', value: '%v'", token.Kind.String(), token.Value)
-		return nil, errors.New(errorMsg)
+		return nil, makePlanningError(token, source)
 	}
 
 	return &evaluationStage{
-		symbol:   symbol,
-		operator: operator,
+		symbol:     symbol,
+		token:      token,
+		source:     source,
+		tokenIndex: index,
+		operator:   operator,
 	}, nil
 }
 
@@ -498,29 +624,35 @@ func findTypeChecks(symbol OperatorSymbol) typeChecks {
 		fallthrough
 	case BITWISE_XOR:
 		return typeChecks{
-			left:  isFloat64,
-			right: isFloat64,
+			left:  isNumber,
+			right: isNumber,
 		}
 	case PLUS:
 		return typeChecks{
 			combined: additionTypeCheck,
 		}
 	case MINUS:
-		fallthrough
+		return typeChecks{
+			combined: subtractionTypeCheck,
+		}
 	case MULTIPLY:
-		fallthrough
+		return typeChecks{
+			combined: multiplicationTypeCheck,
+		}
 	case DIVIDE:
-		fallthrough
+		return typeChecks{
+			combined: divisionTypeCheck,
+		}
 	case MODULUS:
 		fallthrough
 	case EXPONENT:
 		return typeChecks{
-			left:  isFloat64,
-			right: isFloat64,
+			left:  isNumber,
+			right: isNumber,
 		}
 	case NEGATE:
 		return typeChecks{
-			right: isFloat64,
+			right: isNumberOrDuration,
 		}
 	case INVERT:
 		return typeChecks{
@@ -528,18 +660,21 @@ func findTypeChecks(symbol OperatorSymbol) typeChecks {
 		}
 	case BITWISE_NOT:
 		return typeChecks{
-			right: isFloat64,
+			right: isNumber,
 		}
 	case TERNARY_TRUE:
 		return typeChecks{
 			left: isBool,
 		}
 
-	// unchecked cases
 	case EQ:
 		fallthrough
 	case NEQ:
-		return typeChecks{}
+		return typeChecks{
+			combined: equalityTypeCheck,
+		}
+
+	// unchecked cases
 	case TERNARY_FALSE:
 		fallthrough
 	case COALESCE:
@@ -642,6 +777,27 @@ func mirrorStageSubtree(stages []*evaluationStage) {
 	}
 }
 
+/*
+	Replaces the operators of every division (and exponent, which can divide) in the tree
+	with ones that divide decimals using the given [precision] and [rounding].
+*/
+func planDecimalDivision(root *evaluationStage, precision int, rounding big.RoundingMode) {
+
+	if root == nil {
+		return
+	}
+
+	switch root.symbol {
+	case DIVIDE:
+		root.operator = makeDivideStage(precision, rounding)
+	case EXPONENT:
+		root.operator = makeExponentStage(precision, rounding)
+	}
+
+	planDecimalDivision(root.leftStage, precision, rounding)
+	planDecimalDivision(root.rightStage, precision, rounding)
+}
+
 /*
 	Recurses through all operators in the entire tree, eliding operators where both sides are literals.
 */
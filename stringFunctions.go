@@ -0,0 +1,342 @@
+package govaluate
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+	Returns a new map of common string functions, to be given to `NewEvaluableExpressionWithFunctions`
+	(or ParsingOptions.Functions). None of these are available to an expression unless they're given to it.
+	Each call returns a new map, so the caller is free to add their own functions to it, or remove any they don't want.
+
+	The functions are:
+
+		len(s), or len(array)
+		lower(s), upper(s)
+		trim(s), or trim(s, characters)
+		contains(s, substring), startsWith(s, prefix), endsWith(s, suffix), indexOf(s, substring)
+		replace(s, old, new)
+		split(s, separator)
+		join(value, ..., separator)
+		substr(s, start), or substr(s, start, length)
+		format(template, value, ...)
+
+	Lengths and positions count characters (not bytes), from zero, and are given as float64.
+	split gives an array, which can be used with IN. Since arrays are spread into the arguments of a function,
+	len counts its arguments when there's more (or less) than one, and join takes every argument but the last as the values to join,
+	so that `len(split(s, ','))` and `join(split(s, ','), ';')` work as they read.
+*/
+func StringFunctions() map[string]ExpressionFunction {
+
+	return map[string]ExpressionFunction{
+		"len":        lenFunction,
+		"lower":      lowerFunction,
+		"upper":      upperFunction,
+		"trim":       trimFunction,
+		"contains":   containsFunction,
+		"startsWith": startsWithFunction,
+		"endsWith":   endsWithFunction,
+		"replace":    replaceFunction,
+		"split":      splitFunction,
+		"join":       joinFunction,
+		"substr":     substrFunction,
+		"indexOf":    indexOfFunction,
+		"format":     formatFunction,
+	}
+}
+
+/*
+	The number of characters in a string, or the number of elements in an array.
+	Arrays given from an expression are spread into separate arguments, so any number of arguments other than one are counted.
+	That means an array of one string is indistinguishable from the string itself, and gives the length of that string.
+	An array given directly (from Go) isn't spread, and always has its elements counted.
+*/
+func lenFunction(arguments ...interface{}) (interface{}, error) {
+
+	if len(arguments) != 1 {
+		return float64(len(arguments)), nil
+	}
+
+	switch arguments[0].(type) {
+	case []interface{}:
+		return float64(len(arguments[0].([]interface{}))), nil
+	}
+
+	value, err := stringArgument("len", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+	return float64(utf8.RuneCountInString(value)), nil
+}
+
+func lowerFunction(arguments ...interface{}) (interface{}, error) {
+
+	values, err := stringArguments("lower", arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToLower(values[0]), nil
+}
+
+func upperFunction(arguments ...interface{}) (interface{}, error) {
+
+	values, err := stringArguments("upper", arguments, 1)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ToUpper(values[0]), nil
+}
+
+/*
+	Removes whitespace, or any of the given characters, from both ends of a string.
+*/
+func trimFunction(arguments ...interface{}) (interface{}, error) {
+
+	err := checkArgumentCount("trim", arguments, 1, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := stringArguments("trim", arguments, len(arguments))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) == 1 {
+		return strings.TrimSpace(values[0]), nil
+	}
+	return strings.Trim(values[0], values[1]), nil
+}
+
+func containsFunction(arguments ...interface{}) (interface{}, error) {
+
+	values, err := stringArguments("contains", arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+	return boolIface(strings.Contains(values[0], values[1])), nil
+}
+
+func startsWithFunction(arguments ...interface{}) (interface{}, error) {
+
+	values, err := stringArguments("startsWith", arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+	return boolIface(strings.HasPrefix(values[0], values[1])), nil
+}
+
+func endsWithFunction(arguments ...interface{}) (interface{}, error) {
+
+	values, err := stringArguments("endsWith", arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+	return boolIface(strings.HasSuffix(values[0], values[1])), nil
+}
+
+/*
+	Replaces every occurrence of a substring.
+*/
+func replaceFunction(arguments ...interface{}) (interface{}, error) {
+
+	values, err := stringArguments("replace", arguments, 3)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Replace(values[0], values[1], values[2], -1), nil
+}
+
+/*
+	Splits a string around every occurrence of a separator, or into single characters if the separator is empty.
+*/
+func splitFunction(arguments ...interface{}) (interface{}, error) {
+
+	values, err := stringArguments("split", arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(values[0], values[1])
+	ret := make([]interface{}, len(parts))
+
+	for i, part := range parts {
+		ret[i] = part
+	}
+	return ret, nil
+}
+
+/*
+	Joins every argument but the last with the last, which must be a string.
+	Values which aren't strings are formatted the same way as they are by `+` concatenation.
+*/
+func joinFunction(arguments ...interface{}) (interface{}, error) {
+
+	err := checkArgumentCount("join", arguments, 1, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	separator, err := stringArgument("join", arguments, len(arguments)-1)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]string, len(arguments)-1)
+	for i, argument := range arguments[:len(arguments)-1] {
+		parts[i] = fmt.Sprintf("%v", argument)
+	}
+	return strings.Join(parts, separator), nil
+}
+
+/*
+	The characters of a string from [start], up to [length] of them (or to the end of the string, if no length is given).
+	Positions beyond the end of the string give an empty string, rather than an error.
+*/
+func substrFunction(arguments ...interface{}) (interface{}, error) {
+
+	var length int64
+
+	err := checkArgumentCount("substr", arguments, 2, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := stringArgument("substr", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := wholeArgument("substr", arguments[1])
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("Function 'substr' can't start at %d, it must not be negative", start)
+	}
+
+	characters := []rune(value)
+	length = int64(len(characters))
+
+	if len(arguments) > 2 {
+
+		length, err = wholeArgument("substr", arguments[2])
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("Function 'substr' can't take %d characters, it must not be negative", length)
+		}
+	}
+
+	if start >= int64(len(characters)) {
+		return "", nil
+	}
+
+	end := int64(len(characters))
+	if length < end-start {
+		end = start + length
+	}
+	return string(characters[start:end]), nil
+}
+
+/*
+	The position of the first occurrence of a substring, or -1 if there is none.
+*/
+func indexOfFunction(arguments ...interface{}) (interface{}, error) {
+
+	values, err := stringArguments("indexOf", arguments, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	index := strings.Index(values[0], values[1])
+	if index < 0 {
+		return -1.0, nil
+	}
+	return float64(utf8.RuneCountInString(values[0][:index])), nil
+}
+
+/*
+	Replaces each `{}` in the template with the next of the other arguments, formatted the same way as by `+` concatenation.
+	`{{` and `}}` give a literal brace.
+*/
+func formatFunction(arguments ...interface{}) (interface{}, error) {
+
+	var ret strings.Builder
+
+	err := checkArgumentCount("format", arguments, 1, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := stringArgument("format", arguments, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	values := arguments[1:]
+	used := 0
+
+	for i := 0; i < len(template); i++ {
+
+		character := template[i]
+
+		switch {
+		case strings.HasPrefix(template[i:], "{{"), strings.HasPrefix(template[i:], "}}"):
+			ret.WriteByte(character)
+			i++
+
+		case strings.HasPrefix(template[i:], "{}"):
+
+			if used >= len(values) {
+				return nil, fmt.Errorf("Function 'format' has more placeholders than the %d values given", len(values))
+			}
+
+			fmt.Fprintf(&ret, "%v", values[used])
+			used++
+			i++
+
+		default:
+			ret.WriteByte(character)
+		}
+	}
+
+	if used < len(values) {
+		return nil, fmt.Errorf("Function 'format' was given %d values, but only has %d placeholders", len(values), used)
+	}
+	return ret.String(), nil
+}
+
+/*
+	Checks that there are exactly [count] [arguments], and that every one of them is a string.
+*/
+func stringArguments(name string, arguments []interface{}, count int) ([]string, error) {
+
+	err := checkArgumentCount(name, arguments, count, count)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]string, count)
+
+	for i := range arguments {
+
+		ret[i], err = stringArgument(name, arguments, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func stringArgument(name string, arguments []interface{}, index int) (string, error) {
+
+	value, ok := arguments[index].(string)
+	if !ok {
+		return "", fmt.Errorf("Function '%s' can't use '%v' (%T) as argument %d, it is not a string", name, arguments[index], arguments[index], index+1)
+	}
+	return value, nil
+}
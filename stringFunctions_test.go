@@ -0,0 +1,290 @@
+package govaluate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of the functions returned by StringFunctions.
+	For failure tests, [Expected] is the text of the error.
+*/
+type StringFunctionTest struct {
+	Name       string
+	Input      string
+	Parameters map[string]interface{}
+	Expected   interface{}
+}
+
+func TestStringFunctions(test *testing.T) {
+
+	stringTests := []StringFunctionTest{
+
+		StringFunctionTest{
+			Name:     "len",
+			Input:    "len('héllo')",
+			Expected: 5.0,
+		},
+		StringFunctionTest{
+			Name:     "len of empty string",
+			Input:    "len('')",
+			Expected: 0.0,
+		},
+		StringFunctionTest{
+			Name:     "len of split",
+			Input:    "len(split('a,bc,d', ','))",
+			Expected: 3.0,
+		},
+		StringFunctionTest{
+			Name:       "len of an array parameter",
+			Input:      "len(foo) + len(empty)",
+			Parameters: map[string]interface{}{"foo": []interface{}{1, 2}, "empty": []interface{}{}},
+			Expected:   2.0,
+		},
+		StringFunctionTest{
+			Name:     "len of a split with one element is the length of that element",
+			Input:    "len(split('abc', ','))",
+			Expected: 3.0,
+		},
+		StringFunctionTest{
+			Name:     "lower and upper",
+			Input:    "lower('FoO') + upper('bAr')",
+			Expected: "fooBAR",
+		},
+		StringFunctionTest{
+			Name:     "trim",
+			Input:    "trim('  foo \t\n')",
+			Expected: "foo",
+		},
+		StringFunctionTest{
+			Name:     "trim characters",
+			Input:    "trim('--foo-+', '-+')",
+			Expected: "foo",
+		},
+		StringFunctionTest{
+			Name:       "contains",
+			Input:      "contains(name, 'ob') && !contains(name, 'x')",
+			Parameters: map[string]interface{}{"name": "bob"},
+			Expected:   true,
+		},
+		StringFunctionTest{
+			Name:     "startsWith and endsWith",
+			Input:    "startsWith('foobar', 'foo') && endsWith('foobar', 'bar') && !endsWith('foobar', 'foo')",
+			Expected: true,
+		},
+		StringFunctionTest{
+			Name:     "replace",
+			Input:    "replace('a-b-c', '-', '+')",
+			Expected: "a+b+c",
+		},
+		StringFunctionTest{
+			Name:     "split",
+			Input:    "split('a,b,,c', ',')",
+			Expected: []interface{}{"a", "b", "", "c"},
+		},
+		StringFunctionTest{
+			Name:     "split into characters",
+			Input:    "split('añb', '')",
+			Expected: []interface{}{"a", "ñ", "b"},
+		},
+		StringFunctionTest{
+			Name:       "split with IN",
+			Input:      "'admin' IN split(roles, ',') && !('root' IN split(roles, ','))",
+			Parameters: map[string]interface{}{"roles": "user,admin"},
+			Expected:   true,
+		},
+		StringFunctionTest{
+			Name:     "join",
+			Input:    "join('a', 1, true, '-')",
+			Expected: "a-1-true",
+		},
+		StringFunctionTest{
+			Name:     "join of split",
+			Input:    "join(split('a,b,c', ','), '; ')",
+			Expected: "a; b; c",
+		},
+		StringFunctionTest{
+			Name:       "join of an array parameter",
+			Input:      "join(foo, '')",
+			Parameters: map[string]interface{}{"foo": []interface{}{"x", "y"}},
+			Expected:   "xy",
+		},
+		StringFunctionTest{
+			Name:     "join of nothing",
+			Input:    "join(',')",
+			Expected: "",
+		},
+		StringFunctionTest{
+			Name:     "substr",
+			Input:    "substr('héllo', 1, 3)",
+			Expected: "éll",
+		},
+		StringFunctionTest{
+			Name:     "substr to the end",
+			Input:    "substr('héllo', 2)",
+			Expected: "llo",
+		},
+		StringFunctionTest{
+			Name:     "substr beyond the end",
+			Input:    "substr('foo', 2, 10) + substr('foo', 5)",
+			Expected: "o",
+		},
+		StringFunctionTest{
+			Name:     "indexOf",
+			Input:    "indexOf('héllo', 'l')",
+			Expected: 2.0,
+		},
+		StringFunctionTest{
+			Name:     "indexOf missing",
+			Input:    "indexOf('foo', 'x')",
+			Expected: -1.0,
+		},
+		StringFunctionTest{
+			Name:       "format",
+			Input:      "format('{} has {} items, {{literally}}', name, count)",
+			Parameters: map[string]interface{}{"name": "cart", "count": 3},
+			Expected:   "cart has 3 items, {literally}",
+		},
+		StringFunctionTest{
+			Name:       "Positions from numbers",
+			Input:      "substr(name, indexOf(name, '@') + 1)",
+			Parameters: map[string]interface{}{"name": "bob@example.com"},
+			Expected:   "example.com",
+		},
+	}
+
+	for _, stringTest := range stringTests {
+
+		expression, err := NewEvaluableExpressionWithFunctions(stringTest.Input, StringFunctions())
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", stringTest.Name, err)
+			continue
+		}
+
+		result, err := expression.Evaluate(stringTest.Parameters)
+		if err != nil {
+			test.Errorf("Test '%s' failed: %v", stringTest.Name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, stringTest.Expected) {
+			test.Errorf("Test '%s' gave '%v' (%T), expected '%v' (%T)", stringTest.Name, result, result, stringTest.Expected, stringTest.Expected)
+		}
+	}
+}
+
+func TestStringFunctionErrors(test *testing.T) {
+
+	stringTests := []StringFunctionTest{
+
+		StringFunctionTest{
+			Name:     "Too many arguments",
+			Input:    "upper('a', 'b')",
+			Expected: "Function 'upper' expects 1 argument, got 2",
+		},
+		StringFunctionTest{
+			Name:     "Too few arguments",
+			Input:    "replace('a', 'b')",
+			Expected: "Function 'replace' expects 3 arguments, got 2",
+		},
+		StringFunctionTest{
+			Name:     "No arguments",
+			Input:    "join()",
+			Expected: "Function 'join' expects at least 1 argument, got 0",
+		},
+		StringFunctionTest{
+			Name:     "Not a string",
+			Input:    "lower(1)",
+			Expected: "Function 'lower' can't use '1' (float64) as argument 1, it is not a string",
+		},
+		StringFunctionTest{
+			Name:       "Later argument not a string",
+			Input:      "contains('foo', bar)",
+			Parameters: map[string]interface{}{"bar": true},
+			Expected:   "Function 'contains' can't use 'true' (bool) as argument 2, it is not a string",
+		},
+		StringFunctionTest{
+			Name:     "Separator not a string",
+			Input:    "join('a', 'b', 1)",
+			Expected: "Function 'join' can't use '1' (float64) as argument 3, it is not a string",
+		},
+		StringFunctionTest{
+			Name:     "Fractional position",
+			Input:    "substr('foo', 1.5)",
+			Expected: "Function 'substr' can't use '1.5', it is not a whole number",
+		},
+		StringFunctionTest{
+			Name:     "Negative position",
+			Input:    "substr('foo', -1)",
+			Expected: "Function 'substr' can't start at -1, it must not be negative",
+		},
+		StringFunctionTest{
+			Name:     "Negative length",
+			Input:    "substr('foo', 0, -2)",
+			Expected: "Function 'substr' can't take -2 characters, it must not be negative",
+		},
+		StringFunctionTest{
+			Name:     "Too few values to format",
+			Input:    "format('{} and {}', 1)",
+			Expected: "Function 'format' has more placeholders than the 1 values given",
+		},
+		StringFunctionTest{
+			Name:     "Too many values to format",
+			Input:    "format('{}', 1, 2)",
+			Expected: "Function 'format' was given 2 values, but only has 1 placeholders",
+		},
+	}
+
+	for _, stringTest := range stringTests {
+
+		expression, err := NewEvaluableExpressionWithFunctions(stringTest.Input, StringFunctions())
+		if err != nil {
+			test.Errorf("Test '%s' failed to parse: %v", stringTest.Name, err)
+			continue
+		}
+
+		_, err = expression.Evaluate(stringTest.Parameters)
+
+		var functionError FunctionError
+		if !errors.As(err, &functionError) {
+			test.Errorf("Test '%s' expected a function error, got %v", stringTest.Name, err)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), stringTest.Expected.(string)) {
+			test.Errorf("Test '%s' gave error '%v', expected '%v'", stringTest.Name, err, stringTest.Expected)
+		}
+	}
+}
+
+func TestStringFunctionsWithOtherModes(test *testing.T) {
+
+	functions := StringFunctions()
+	for name, function := range MathFunctions() {
+		functions[name] = function
+	}
+
+	options := ParsingOptions{
+		NumericMode: INTEGER_NUMERICS,
+		Functions:   functions,
+	}
+
+	// counts are always float64, so dividing one isn't integer division, even in this mode.
+	expression, err := NewEvaluableExpressionWithOptions("substr(name, 0, max(floor(len(name) / 2), 1)) + (len(name) / 2)", options)
+	if err != nil {
+		test.Fatalf("Unable to parse expression: %v", err)
+	}
+
+	result, err := expression.Evaluate(map[string]interface{}{"name": "abcdefg"})
+	if err != nil || result != "abc3.5" {
+		test.Errorf("Expected 'abc3.5', got %v (%v)", result, err)
+	}
+
+	// arrays given directly, rather than spread from an expression, are counted.
+	result, err = functions["len"]([]interface{}{1, 2, 3})
+	if err != nil || result != 3.0 {
+		test.Errorf("Expected 3, got %v (%v)", result, err)
+	}
+}
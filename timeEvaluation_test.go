@@ -0,0 +1,259 @@
+package govaluate
+
+import (
+	"testing"
+	"time"
+)
+
+/*
+	Tests for evaluating expressions which use times and durations.
+*/
+func TestTimeEvaluation(test *testing.T) {
+
+	lastSeen := time.Date(2014, time.January, 2, 11, 50, 0, 0, time.UTC)
+	now := time.Date(2014, time.January, 2, 12, 10, 0, 0, time.UTC)
+
+	nowFunction := map[string]ExpressionFunction{
+		"now": func(arguments ...interface{}) (interface{}, error) {
+			return now, nil
+		},
+	}
+
+	evaluationTests := []EvaluationTest{
+
+		EvaluationTest{
+
+			Name:      "Time minus time compared to duration",
+			Input:     "now() - lastSeen > 15m",
+			Functions: nowFunction,
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen,
+				},
+			},
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:      "Time minus time",
+			Input:     "now() - lastSeen",
+			Functions: nowFunction,
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen,
+				},
+			},
+			Expected: 20 * time.Minute,
+		},
+		EvaluationTest{
+
+			Name:  "Time plus duration",
+			Input: "lastSeen + 1h30m",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen,
+				},
+			},
+			Expected: time.Date(2014, time.January, 2, 13, 20, 0, 0, time.UTC),
+		},
+		EvaluationTest{
+
+			Name:  "Duration plus time",
+			Input: "10m + lastSeen",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen,
+				},
+			},
+			Expected: time.Date(2014, time.January, 2, 12, 0, 0, 0, time.UTC),
+		},
+		EvaluationTest{
+
+			Name:  "Time minus duration",
+			Input: "lastSeen - 50m",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen,
+				},
+			},
+			Expected: time.Date(2014, time.January, 2, 11, 0, 0, 0, time.UTC),
+		},
+		EvaluationTest{
+
+			Name:  "Time parameter compared to date literal",
+			Input: "lastSeen > '2014-01-02T11:49:59Z'",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen,
+				},
+			},
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:  "Time parameter compared with sub-second precision",
+			Input: "lastSeen < later",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen,
+				},
+				EvaluationParameter{
+					Name:  "later",
+					Value: lastSeen.Add(time.Millisecond),
+				},
+			},
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:  "Time equality across locations",
+			Input: "lastSeen == '2014-01-02T11:50:00Z'",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen.In(time.FixedZone("UTC+2", 2*60*60)),
+				},
+			},
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:  "Time in array",
+			Input: "lastSeen in ('2014-01-01T00:00:00Z', '2014-01-02T11:50:00Z')",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen,
+				},
+			},
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:  "Time compared to unix seconds",
+			Input: "lastSeen >= 1388663400",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen,
+				},
+			},
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:  "Time equal to unix seconds",
+			Input: "lastSeen == 1388663400 && lastSeen != 1388663401 && 1388663400 IN (0, lastSeen)",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "lastSeen",
+					Value: lastSeen,
+				},
+			},
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:     "Duration addition",
+			Input:    "1h + 30m",
+			Expected: 90 * time.Minute,
+		},
+		EvaluationTest{
+
+			Name:     "Duration subtraction",
+			Input:    "1h - 90m",
+			Expected: -30 * time.Minute,
+		},
+		EvaluationTest{
+
+			Name:     "Duration negation",
+			Input:    "-15m",
+			Expected: -15 * time.Minute,
+		},
+		EvaluationTest{
+
+			Name:     "Duration multiplication",
+			Input:    "15m * 2",
+			Expected: 30 * time.Minute,
+		},
+		EvaluationTest{
+
+			Name:     "Number times duration",
+			Input:    "1.5 * 1h",
+			Expected: 90 * time.Minute,
+		},
+		EvaluationTest{
+
+			Name:     "Duration division",
+			Input:    "1h / 4",
+			Expected: 15 * time.Minute,
+		},
+		EvaluationTest{
+
+			Name:     "Duration ratio",
+			Input:    "1h / 15m",
+			Expected: 4.0,
+		},
+		EvaluationTest{
+
+			Name:     "Duration comparison",
+			Input:    "90s > 1m",
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:     "Duration equality",
+			Input:    "60m == 1h",
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:  "Duration parameter",
+			Input: "timeout <= 30s",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "timeout",
+					Value: 10 * time.Second,
+				},
+			},
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:     "Date literal difference",
+			Input:    "'2014-01-03' - '2014-01-02'",
+			Expected: 24 * time.Hour,
+		},
+	}
+
+	runEvaluationTests(evaluationTests, test)
+}
+
+func TestDurationInNumericModes(test *testing.T) {
+
+	modes := []NumericMode{INTEGER_NUMERICS, DECIMAL_NUMERICS}
+
+	for _, mode := range modes {
+
+		expression, err := NewEvaluableExpressionWithOptions("timeout * 2 + 1m", ParsingOptions{NumericMode: mode})
+		if err != nil {
+			test.Fatalf("Unable to parse expression in %v mode: %v", mode, err)
+		}
+
+		result, err := expression.Evaluate(map[string]interface{}{"timeout": 30 * time.Second})
+		if err != nil {
+			test.Fatalf("Unable to evaluate expression in %v mode: %v", mode, err)
+		}
+
+		if result != 2*time.Minute {
+			test.Errorf("Expected 2m in %v mode, got %v", mode, result)
+		}
+	}
+}
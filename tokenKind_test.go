@@ -28,6 +28,7 @@ func TestTokenKindStrings(test *testing.T) {
 		CLAUSE,
 		CLAUSE_CLOSE,
 		TERNARY,
+		DURATION,
 	}
 
 	for _, kind := range kinds {
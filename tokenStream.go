@@ -2,16 +2,18 @@ package govaluate
 
 type tokenStream struct {
 	tokens      []ExpressionToken
+	sources     []tokenSource
 	index       int
 	tokenLength int
 }
 
-func newTokenStream(tokens []ExpressionToken) *tokenStream {
+func newTokenStream(tokens []ExpressionToken, sources []tokenSource) *tokenStream {
 
 	var ret *tokenStream
 
 	ret = new(tokenStream)
 	ret.tokens = tokens
+	ret.sources = sources
 	ret.tokenLength = len(tokens)
 	return ret
 }
@@ -30,6 +32,20 @@ func (this *tokenStream) next() ExpressionToken {
 	return token
 }
 
+/*
+	Returns the source of the token most recently returned by `next()`.
+*/
+func (this tokenStream) source() tokenSource {
+	return this.sources[this.index-1]
+}
+
+/*
+	Returns the index of the token most recently returned by `next()`.
+*/
+func (this tokenStream) lastIndex() int {
+	return this.index - 1
+}
+
 func (this tokenStream) hasNext() bool {
 
 	return this.index < this.tokenLength
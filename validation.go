@@ -0,0 +1,64 @@
+package govaluate
+
+import (
+	"sort"
+)
+
+/*
+	Checks the given [expression] for every problem which would stop it from being parsed, using the given [options].
+	Unlike the `NewEvaluableExpression` functions, this doesn't stop at the first problem;
+	it skips past anything it can't read, and carries on checking the rest of the expression.
+
+	Returns every problem found as a ParseError or SyntaxError, in the order they appear in the expression.
+	Returns nil if the expression is valid.
+*/
+func ValidateExpression(expression string, options ParsingOptions) []error {
+
+	var tokens []ExpressionToken
+	var sources []tokenSource
+	var resumed map[int]bool
+	var errs []error
+	var err error
+
+	tokens, sources, resumed, errs = readTokens(expression, options, true)
+
+	openings, closings := findUnbalancedParens(tokens)
+	for _, index := range append(openings, closings...) {
+		errs = append(errs, SyntaxError{"Unbalanced parenthesis", sources[index].start, findTokenSymbol(tokens[index]), tokens[index]})
+	}
+
+	errs = append(errs, findSyntaxErrors(tokens, sources, resumed, true)...)
+	errs = append(errs, compilePatterns(tokens, sources, true)...)
+
+	// the planner assumes a valid expression, so it can only be checked once everything else is fine.
+	if len(errs) == 0 {
+
+		_, err = planStages(tokens, sources, options)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errorPosition(errs[i]).Offset < errorPosition(errs[j]).Offset
+	})
+	return errs
+}
+
+/*
+	Returns the position of an error found while parsing.
+*/
+func errorPosition(err error) Position {
+
+	switch err.(type) {
+	case ParseError:
+		return err.(ParseError).Position
+	case SyntaxError:
+		return err.(SyntaxError).Position
+	}
+	return Position{}
+}
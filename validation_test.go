@@ -0,0 +1,154 @@
+package govaluate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of validating an expression.
+	[Expected] holds part of the message of each error, and [Positions] the position of each.
+*/
+type ValidationTest struct {
+	Name      string
+	Input     string
+	Expected  []string
+	Positions []Position
+}
+
+func TestValidation(test *testing.T) {
+
+	validationTests := []ValidationTest{
+
+		ValidationTest{
+
+			Name:  "Valid expression",
+			Input: "foo > 1 && bar =~ '[a-z]+'",
+		},
+		ValidationTest{
+
+			Name:      "Single error",
+			Input:     "1 +",
+			Expected:  []string{UNEXPECTED_END},
+			Positions: []Position{Position{2, 1, 3}},
+		},
+		ValidationTest{
+
+			Name:     "Multiple invalid tokens",
+			Input:    "1 + @ 2 # 3",
+			Expected: []string{INVALID_TOKEN_KIND, INVALID_TOKEN_KIND},
+			Positions: []Position{
+				Position{4, 1, 5},
+				Position{8, 1, 9},
+			},
+		},
+		ValidationTest{
+
+			Name:     "Transitions and parenthesis",
+			Input:    "foo == 'bar' && > 3 || (1",
+			Expected: []string{INVALID_TOKEN_TRANSITION, UNBALANCED_PARENTHESIS},
+			Positions: []Position{
+				Position{16, 1, 17},
+				Position{23, 1, 24},
+			},
+		},
+		ValidationTest{
+
+			Name:     "Undefined functions",
+			Input:    "first(1) + second(2)",
+			Expected: []string{UNDEFINED_FUNCTION, UNDEFINED_FUNCTION},
+			Positions: []Position{
+				Position{0, 1, 1},
+				Position{11, 1, 12},
+			},
+		},
+		ValidationTest{
+
+			Name:     "Lexical and syntax errors across lines",
+			Input:    "a > 1 &&\nb @ 2 &&\nc =~ '[' &&\n> d",
+			Expected: []string{INVALID_TOKEN_KIND, "error parsing regexp", INVALID_TOKEN_TRANSITION},
+			Positions: []Position{
+				Position{11, 2, 3},
+				Position{23, 3, 6},
+				Position{30, 4, 1},
+			},
+		},
+		ValidationTest{
+
+			Name:      "Error at end of expression",
+			Input:     "1 + @",
+			Expected:  []string{INVALID_TOKEN_KIND},
+			Positions: []Position{Position{4, 1, 5}},
+		},
+		ValidationTest{
+
+			Name:      "Unclosed string",
+			Input:     "foo == 'bar",
+			Expected:  []string{UNCLOSED_QUOTES},
+			Positions: []Position{Position{7, 1, 8}},
+		},
+	}
+
+	runValidationTests(validationTests, test)
+}
+
+func runValidationTests(validationTests []ValidationTest, test *testing.T) {
+
+	for _, validationTest := range validationTests {
+
+		errs := ValidateExpression(validationTest.Input, ParsingOptions{})
+
+		if len(errs) != len(validationTest.Expected) {
+			test.Errorf("Test '%s' expected %d errors, got %d: %v", validationTest.Name, len(validationTest.Expected), len(errs), errs)
+			continue
+		}
+
+		for i, err := range errs {
+
+			if !strings.Contains(err.Error(), validationTest.Expected[i]) {
+				test.Errorf("Test '%s' expected error '%s', got '%v'", validationTest.Name, validationTest.Expected[i], err)
+			}
+
+			position := errorPosition(err)
+			if position != validationTest.Positions[i] {
+				test.Errorf("Test '%s' expected error '%v' at %+v, got %+v", validationTest.Name, err, validationTest.Positions[i], position)
+			}
+		}
+	}
+}
+
+/*
+	Whichever error parsing stops at should be one of the errors found by validation.
+*/
+func TestValidationMatchesParsing(test *testing.T) {
+
+	inputs := []string{
+		"1 + @ 2 # 3",
+		"1 +",
+		"first(1) + second(2)",
+		"foo =~ '[' && > 1",
+	}
+
+	for _, input := range inputs {
+
+		_, err := NewEvaluableExpression(input)
+		errs := ValidateExpression(input, ParsingOptions{})
+
+		if err == nil {
+			test.Errorf("Expected '%s' to be invalid", input)
+			continue
+		}
+
+		found := false
+		for _, validationError := range errs {
+			if reflect.DeepEqual(validationError, err) {
+				found = true
+			}
+		}
+
+		if !found {
+			test.Errorf("Parsing '%s' found '%v', which validation did not: %v", input, err, errs)
+		}
+	}
+}